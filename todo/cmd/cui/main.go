@@ -1,29 +1,183 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/tevino/the-clean-architecture-demo/todo/cui/io"
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
 
 	"github.com/tevino/the-clean-architecture-demo/todo/cui"
+	"github.com/tevino/the-clean-architecture-demo/todo/notify"
 	"github.com/tevino/the-clean-architecture-demo/todo/storage"
 	"github.com/tevino/the-clean-architecture-demo/todo/use"
 )
 
+// defaultDataFiles are the default files under the default data path of formats which store in a single file.
+var defaultDataFiles = map[string]string{
+	"todotxt": "todo.txt",
+	"outline": "todo.md",
+	"org":     "todo.org",
+}
+
+func defaultDataPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".todo"
+	}
+	return filepath.Join(home, ".todo")
+}
+
+// openStorage opens the storage of given format, the returned function closes it.
+func openStorage(format, path string) (use.Storage, func(), error) {
+	switch format {
+	case "fs":
+		return storage.NewFileSystem(path), func() {}, nil
+	case "journal":
+		j, err := storage.NewJournal(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return j, func() { j.Close() }, nil
+	case "todotxt":
+		return storage.NewTodoTxt(path), func() {}, nil
+	case "outline":
+		return storage.NewOutline(path), func() {}, nil
+	case "org":
+		return storage.NewOrg(path), func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage format: %s", format)
+	}
+}
+
+// migrateStorage migrates the data of given format to the current schema, nil is returned for formats without a
+// schema of their own.
+func migrateStorage(format, path string, dryRun bool) (*storage.MigrationReport, error) {
+	switch format {
+	case "fs":
+		return storage.MigrateFileSystem(path, dryRun)
+	case "journal":
+		return storage.MigrateJournal(path, dryRun)
+	default:
+		return nil, nil
+	}
+}
+
+// reminderStatePath returns the path of the file which keeps what reminders have been delivered, it is in the data
+// directory, or next to the data file of formats which store in a single file.
+func reminderStatePath(format, path string) string {
+	if _, ok := defaultDataFiles[format]; ok {
+		return path + ".reminders"
+	}
+	return filepath.Join(path, "reminders")
+}
+
+// openNotifier returns the notifiers of reminders, which are ui and those configured, the returned function closes
+// them.
+func openNotifier(ui *cui.Notifier, command, logPath string) (use.Notifier, func(), error) {
+	notifiers := notify.Notifiers{ui}
+	if args := strings.Fields(command); len(args) > 0 {
+		notifiers = append(notifiers, &notify.Command{Name: args[0], Args: args[1:]})
+	}
+	if logPath == "" {
+		return notifiers, func() {}, nil
+	}
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening reminder log: %w", err)
+	}
+	notifiers = append(notifiers, &notify.Log{Logger: log.New(f, "", log.LstdFlags)})
+	return notifiers, func() { f.Close() }, nil
+}
+
 func main() {
+	format := flag.String("format", "fs", "storage format, one of fs, journal, todotxt, outline and org")
+	dataPath := flag.String("data", "", "directory to store tasks in, or the file for todotxt, outline and org (default ~/.todo)")
+	migrateDryRun := flag.Bool("migrate-dry-run", false, "report what the migration of the data would change and exit")
+	remindCommand := flag.String("remind-command", "", "command to run on reminders, with the message as its last argument")
+	remindLog := flag.String("remind-log", "", "file to append reminders to")
+	autoComplete := flag.Bool("auto-complete-projects", true, "complete a project once all the tasks under it are completed")
+	flag.Parse()
+
+	if *dataPath == "" {
+		*dataPath = defaultDataPath()
+		if file, ok := defaultDataFiles[*format]; ok {
+			*dataPath = filepath.Join(*dataPath, file)
+		}
+	}
+	report, err := migrateStorage(*format, *dataPath, *migrateDryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *migrateDryRun {
+		if report == nil {
+			fmt.Printf("Format %s has no schema to migrate\n", *format)
+		} else {
+			fmt.Println(report)
+		}
+		return
+	}
+	if report != nil && report.From != report.To {
+		log.Println(report)
+	}
+
+	store, closeStorage, err := openStorage(*format, *dataPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeStorage()
+
 	ui := cui.New(&io.TermUI{})
-	mem := storage.NewMemory()
-	presenter := &cui.Presenter{ui}
+	presenter := &cui.Presenter{CUI: ui}
 	cases := &use.TaskInteractor{
-		Presenter: presenter,
-		Storage:   mem,
+		Presenter:            presenter,
+		Storage:              store,
+		AutoCompleteProjects: *autoComplete,
 	}
+	defer cases.Close()
 	ctl := &cui.Controller{
 		CUI:       ui,
 		IO:        &io.UnixLikeIO{},
 		CasesTask: cases,
 	}
-	cases.AddTemplate()
+	items, err := store.GetItemsByParentID(entity.RootID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(items) == 0 {
+		if err := cases.AddTemplate(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	uiNotifier := &cui.Notifier{CUI: ui}
+	if j, ok := store.(*storage.Journal); ok {
+		j.OnError = uiNotifier.Warn
+	}
+	notifier, closeNotifier, err := openNotifier(uiNotifier, *remindCommand, *remindLog)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeNotifier()
+	scheduler := &use.ReminderScheduler{
+		Storage:  store,
+		Notifier: notifier,
+		State:    storage.NewReminderFile(reminderStatePath(*format, *dataPath)),
+		OnError:  uiNotifier.Warn,
+	}
+	ctx, stopReminders := context.WithCancel(context.Background())
+	defer stopReminders()
+	go func() {
+		if err := scheduler.Run(ctx); err != nil {
+			uiNotifier.Warn(err)
+		}
+	}()
+
 	if err := ctl.Loop(); err != nil {
 		log.Fatal(err)
 	}
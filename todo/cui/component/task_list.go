@@ -2,6 +2,8 @@ package component
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/dustin/go-humanize"
 
@@ -22,13 +24,39 @@ type TaskList interface {
 	UpdateTasks(tasks []*model.Task)
 	GetSelectedTask() (*model.Task, bool)
 	SetEventHandler(func(TaskListEvent))
+	// Sort returns how tasks of the list are to be sorted, the nil TaskSort is the manual order.
+	Sort() model.TaskSort
+	// Path returns the parent and its ancestors from the top level down, the list could be left for the parent of
+	// the parent if it is not on the top level.
+	Path() []*model.Task
+	SetPath(path []*model.Task)
+}
+
+// taskSorts are the sorts a TaskList cycles through, the manual order goes first.
+var taskSorts = []struct {
+	name string
+	sort model.TaskSort
+}{
+	{"", nil},
+	{"priority", model.TaskSort{model.SortByPriority, model.SortByDue}},
+	{"due", model.TaskSort{model.SortByDue, model.SortByPriority}},
+	{"created", model.TaskSort{model.SortByCreated}},
+	{"title", model.TaskSort{model.SortByTitle}},
 }
 
 // TaskListComponent displays a list of tasks.
 type TaskListComponent struct {
 	*widgets.List
+	title    string
 	parentID int64
 	tasks    []*model.Task
+	// sort is the index of the sort in taskSorts
+	sort int
+	path []*model.Task
+	// cursors are the IDs of the tasks last selected in each parent, the selection is restored once the parent is
+	// listed again after restoreCursor is set.
+	cursors       map[int64]int64
+	restoreCursor bool
 	use.CasesTask
 	previousKey string
 	isActivated bool
@@ -44,6 +72,8 @@ func NewListComponent(title string) *TaskListComponent {
 	}
 	return &TaskListComponent{
 		List:        list,
+		title:       title,
+		cursors:     make(map[int64]int64),
 		isActivated: false,
 		handleEvent: func(TaskListEvent) {},
 	}
@@ -77,28 +107,111 @@ func (l *TaskListComponent) HandleEvent(e ui.Event) error {
 		// l.ScrollBottom()
 		l.selectTaskAt(len(l.Rows) - 1)
 	case "o", "O":
-		var order uint64
-		switch e.ID {
-		case "o":
-			order = 1
-		case "O":
-			order = 0
+		// after or before the selected one, or as the only one
+		position := l.SelectedRow - l.navRows()
+		if e.ID == "o" {
+			position++
 		}
-		t, ok := l.GetSelectedTask()
-		if ok {
-			order += t.Order
-		} else {
-			order = 0
+		if _, ok := l.GetSelectedTask(); !ok {
+			position = 0
 		}
-		l.handleEvent(TaskListEvent{Type: EventInsertTaskWithOrder, Order: order})
+		l.handleEvent(TaskListEvent{Type: EventInsertTask, Position: position})
 	case "<Space>":
 		l.handleEvent(TaskListEvent{Type: EventChangeTaskState})
+	case "x", "<Delete>":
+		l.handleEvent(TaskListEvent{Type: EventDeleteTask})
+	case "u":
+		l.handleEvent(TaskListEvent{Type: EventRestoreTask})
+	case "d":
+		if l.previousKey == "d" {
+			l.previousKey = ""
+			l.handleEvent(TaskListEvent{Type: EventCutTask})
+			return nil
+		}
+	case "p":
+		// after the selected one, or as the only one
+		position := l.SelectedRow - l.navRows() + 1
+		if _, ok := l.GetSelectedTask(); !ok {
+			position = 0
+		}
+		l.handleEvent(TaskListEvent{Type: EventPasteTask, Position: position})
+	case "J", "K":
+		position := l.SelectedRow - l.navRows() + 1
+		if e.ID == "K" {
+			position -= 2
+		}
+		if _, ok := l.GetSelectedTask(); !ok || position < 0 || position >= len(l.tasks) {
+			break
+		}
+		l.handleEvent(TaskListEvent{Type: EventMoveTask, Position: position})
+		// the selection follows the task once the list is updated
+		l.SelectedRow = position + l.navRows()
+	case "s":
+		l.sort = (l.sort + 1) % len(taskSorts)
+		l.updateTitle()
+		l.handleEvent(TaskListEvent{Type: EventSortTasks})
+	case "<Enter>", "l":
+		if l.navRows() > 0 && l.SelectedRow == navRowParent {
+			l.handleEvent(TaskListEvent{Type: EventLeaveTask})
+		} else if _, ok := l.GetSelectedTask(); ok {
+			l.handleEvent(TaskListEvent{Type: EventEnterTask})
+		}
+	case "<Backspace>", "h":
+		if l.navRows() > 0 {
+			l.handleEvent(TaskListEvent{Type: EventLeaveTask})
+		}
 	}
 
 	l.previousKey = e.ID
 	return nil
 }
 
+func (l *TaskListComponent) Sort() model.TaskSort {
+	return taskSorts[l.sort].sort
+}
+
+func (l *TaskListComponent) Path() []*model.Task {
+	return l.path
+}
+
+// SetPath sets the path to the parent, which is shown in the title as breadcrumbs.
+func (l *TaskListComponent) SetPath(path []*model.Task) {
+	l.path = path
+	l.updateTitle()
+}
+
+// updateTitle sets the title to the given one followed by the path and the sort, e.g. "Tasks: Work > Release (by
+// due)".
+func (l *TaskListComponent) updateTitle() {
+	l.Title = l.title
+	if len(l.path) > 0 {
+		titles := make([]string, len(l.path))
+		for i, t := range l.path {
+			titles[i] = t.Title
+		}
+		l.Title += ": " + strings.Join(titles, " > ")
+	}
+	if name := taskSorts[l.sort].name; name != "" {
+		l.Title += " (by " + name + ")"
+	}
+}
+
+// Rows of "." and "..", which go before tasks of a parent not on the top level.
+const (
+	navRowCurrent = iota
+	navRowParent
+	navRowCount
+)
+
+// navRows returns the count of rows before tasks, which is navRowCount if the list could be left for the parent of
+// the parent, see Path.
+func (l *TaskListComponent) navRows() int {
+	if len(l.path) > 1 {
+		return navRowCount
+	}
+	return 0
+}
+
 func (l *TaskListComponent) SetEventHandler(handle func(TaskListEvent)) {
 	l.handleEvent = handle
 }
@@ -110,8 +223,8 @@ func (l *TaskListComponent) selectTaskAt(idx int) {
 }
 
 func (l *TaskListComponent) GetSelectedTask() (*model.Task, bool) {
-	if l.SelectedRow < len(l.tasks) {
-		return l.tasks[l.SelectedRow], true
+	if i := l.SelectedRow - l.navRows(); i >= 0 && i < len(l.tasks) {
+		return l.tasks[i], true
 	}
 	return nil, false
 }
@@ -134,7 +247,19 @@ func formatTaskRow(t *model.Task, width int) string {
 	var row string
 	switch t.Type {
 	case model.TaskTypeCategory:
-		row = fmt.Sprintf("+ %s", t.Title)
+		row = fmt.Sprintf("+ %s", priorityMarks[t.Priority]+t.Title+formatTags(t.Tags))
+	case model.TaskTypeProject:
+		title := priorityMarks[t.Priority] + t.Title + formatTags(t.Tags)
+		if t.State == model.TaskStateCompleted {
+			title += " (done)"
+		}
+		progress := formatProgress(t.Progress)
+		// the 1s are the count of spaces in the formatting string
+		titleLength := width - len("*") - 1 - len(progress) - 1
+		if len(title) > titleLength && titleLength > 3 {
+			title = title[:titleLength-3] + "..."
+		}
+		row = fmt.Sprintf(fmt.Sprintf("* %%-%ds %%s", titleLength), title, progress)
 	case model.TaskTypeTask:
 		var x string
 		switch t.State {
@@ -147,7 +272,7 @@ func formatTaskRow(t *model.Task, width int) string {
 		due := humanize.Time(t.Due)
 		// the 1s are the count of spaces in the formatting string
 		titleLength := width - len(x) - 1 - len(due) - 1
-		title := t.Title
+		title := priorityMarks[t.Priority] + t.Title + formatTags(t.Tags)
 		if len(title) > titleLength {
 			title = title[:titleLength-3]
 			title = title + "..."
@@ -159,21 +284,80 @@ func formatTaskRow(t *model.Task, width int) string {
 	return row
 }
 
+// progressBarWidth is the count of cells in the progress bar of a project.
+const progressBarWidth = 10
+
+// formatProgress formats the progress of a project as a bar followed by the counts of tasks and the remaining
+// estimate, e.g. "[####------] 2/5 3h left".
+func formatProgress(p *model.Progress) string {
+	if p == nil {
+		p = &model.Progress{}
+	}
+	filled := 0
+	if p.Total > 0 {
+		filled = p.Completed * progressBarWidth / p.Total
+	}
+	s := fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled),
+		p.Completed, p.Total)
+	if p.Remaining > 0 {
+		s += " " + formatEstimate(p.Remaining) + " left"
+	}
+	return s
+}
+
+// formatEstimate formats an estimate in hours and minutes, e.g. 1h30m.
+func formatEstimate(d time.Duration) string {
+	hours, minutes := d/time.Hour, d%time.Hour/time.Minute
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+}
+
+// priorityMarks go before titles of tasks by their priorities.
+var priorityMarks = map[model.TaskPriority]string{
+	model.TaskPriorityLow:    "! ",
+	model.TaskPriorityMedium: "!! ",
+	model.TaskPriorityHigh:   "!!! ",
+}
+
+// formatTags formats tags to follow a title, e.g. " #work #home".
+func formatTags(tags []string) string {
+	var s string
+	for _, t := range tags {
+		s += " #" + t
+	}
+	return s
+}
+
 var emptyRows = []string{"<Empty>"}
 
 func (l *TaskListComponent) Update() error {
-	rowMatched := 0
-	rows := make([]string, len(l.tasks))
-
-	for i, t := range l.tasks {
-		rows[i] = formatTaskRow(t, l.Inner.Dx())
-		if selectedTask, ok := l.GetSelectedTask(); ok {
-			if t.ID == selectedTask.ID {
-				rowMatched = i
+	nav := l.navRows()
+	rows := make([]string, 0, nav+len(l.tasks))
+	if nav > 0 {
+		current, parent := l.path[len(l.path)-1], l.path[len(l.path)-2]
+		rows = append(rows, ".  "+current.Title, ".. "+parent.Title)
+	}
+	for _, t := range l.tasks {
+		rows = append(rows, formatTaskRow(t, l.Inner.Dx()))
+	}
+	if l.restoreCursor {
+		l.restoreCursor = false
+		l.SelectedRow = nav
+		for i, t := range l.tasks {
+			if t.ID == l.cursors[l.parentID] {
+				l.SelectedRow = nav + i
 			}
 		}
 	}
-	l.SelectedRow = rowMatched
+	if l.SelectedRow >= len(rows) {
+		l.SelectedRow = 0
+	}
 	if len(rows) == 0 {
 		rows = emptyRows
 	}
@@ -187,9 +371,16 @@ func (l *TaskListComponent) ParentID() int64 {
 	return l.parentID
 }
 
-// SetParentID sets the parentID of this list.
+// SetParentID sets the parentID of this list, the task selected in the previous parent is remembered and the one
+// last selected in the new parent is selected once its tasks are updated.
 func (l *TaskListComponent) SetParentID(parentID int64) {
-	l.parentID = parentID
+	if parentID == l.parentID {
+		return
+	}
+	if t, ok := l.GetSelectedTask(); ok {
+		l.cursors[l.parentID] = t.ID
+	}
+	l.parentID, l.restoreCursor = parentID, true
 }
 
 // UpdateTasks replaces tasks displayed with given slice.
@@ -5,10 +5,26 @@ type TaskListEventType int
 const (
 	TaskListEventAfterUpdate TaskListEventType = iota
 	EventChangeTaskState
-	EventInsertTaskWithOrder
+	// EventInsertTask inserts a new task at Position.
+	EventInsertTask
+	EventDeleteTask
+	// EventRestoreTask is for the last deleted task rather than the selected one.
+	EventRestoreTask
+	EventCutTask
+	// EventPasteTask pastes the cut task at Position.
+	EventPasteTask
+	// EventMoveTask moves the selected task to Position.
+	EventMoveTask
+	// EventSortTasks is sent once the sort of the list is changed, see TaskList.Sort.
+	EventSortTasks
+	// EventEnterTask lists the sub tasks of the selected task.
+	EventEnterTask
+	// EventLeaveTask lists the tasks of the parent of the listed parent, see TaskList.Path.
+	EventLeaveTask
 )
 
 type TaskListEvent struct {
-	Type  TaskListEventType
-	Order uint64
+	Type TaskListEventType
+	// Position is the position among the tasks of the list other than the moved one.
+	Position int
 }
@@ -1,7 +1,9 @@
 package component
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -12,25 +14,29 @@ import (
 
 func TestTaskListComponentHandleInsertTaskEvent(t *testing.T) {
 	tasks := []*model.Task{
-		{Order: 1},
-		{Order: 2},
+		{ID: 1},
+		{ID: 2},
 	}
 
 	for _, c := range []struct {
-		selectedRow int
-		key         string
-		expectOrder uint64
+		tasks          []*model.Task
+		selectedRow    int
+		key            string
+		expectPosition int
 	}{
-		{selectedRow: 0, key: "o", expectOrder: tasks[0].Order + 1},
-		{selectedRow: 0, key: "O", expectOrder: tasks[0].Order},
-		{selectedRow: 1, key: "o", expectOrder: tasks[1].Order + 1},
-		{selectedRow: 1, key: "O", expectOrder: tasks[1].Order},
+		{tasks: tasks, selectedRow: 0, key: "o", expectPosition: 1},
+		{tasks: tasks, selectedRow: 0, key: "O", expectPosition: 0},
+		{tasks: tasks, selectedRow: 1, key: "o", expectPosition: 2},
+		{tasks: tasks, selectedRow: 1, key: "O", expectPosition: 1},
+		{tasks: nil, selectedRow: 0, key: "o", expectPosition: 0},
+		{tasks: nil, selectedRow: 0, key: "O", expectPosition: 0},
 	} {
 		run := 0
 		l := NewListComponent("")
-		l.tasks = tasks
+		l.tasks = c.tasks
 		l.SetEventHandler(func(e TaskListEvent) {
-			assert.Equal(t, c.expectOrder, e.Order)
+			assert.Equal(t, EventInsertTask, e.Type)
+			assert.Equal(t, c.expectPosition, e.Position)
 			run += 1
 		})
 		l.SelectedRow = c.selectedRow
@@ -64,3 +70,179 @@ func TestSelectedTask(t *testing.T) {
 		}
 	}
 }
+
+func TestTaskListComponentHandleDeleteAndRestoreEvents(t *testing.T) {
+	for key, typ := range map[string]TaskListEventType{
+		"x":        EventDeleteTask,
+		"<Delete>": EventDeleteTask,
+		"u":        EventRestoreTask,
+	} {
+		var got []TaskListEventType
+		l := NewListComponent("")
+		l.SetEventHandler(func(e TaskListEvent) {
+			got = append(got, e.Type)
+		})
+		assert.NoError(t, l.HandleEvent(ui.Event{ID: key}))
+		assert.Equal(t, []TaskListEventType{typ}, got)
+	}
+}
+
+func TestTaskListComponentHandleMoveEvents(t *testing.T) {
+	tasks := []*model.Task{{ID: 1}, {ID: 2}, {ID: 3}}
+	for _, c := range []struct {
+		selectedRow  int
+		keys         []string
+		expect       []TaskListEvent
+		expectedRow  int
+		skipSetTasks bool
+	}{
+		{selectedRow: 1, keys: []string{"d"}, expectedRow: 1},
+		{selectedRow: 1, keys: []string{"d", "d"}, expect: []TaskListEvent{{Type: EventCutTask}}, expectedRow: 1},
+		{selectedRow: 1, keys: []string{"d", "d", "d"}, expect: []TaskListEvent{{Type: EventCutTask}}, expectedRow: 1},
+		{selectedRow: 1, keys: []string{"p"}, expect: []TaskListEvent{{Type: EventPasteTask, Position: 2}}, expectedRow: 1},
+		{selectedRow: 0, keys: []string{"p"}, expect: []TaskListEvent{{Type: EventPasteTask}}, skipSetTasks: true},
+		{selectedRow: 1, keys: []string{"J"}, expect: []TaskListEvent{{Type: EventMoveTask, Position: 2}}, expectedRow: 2},
+		{selectedRow: 1, keys: []string{"K"}, expect: []TaskListEvent{{Type: EventMoveTask, Position: 0}}, expectedRow: 0},
+		{selectedRow: 2, keys: []string{"J"}, expectedRow: 2},
+		{selectedRow: 0, keys: []string{"K"}, expectedRow: 0},
+	} {
+		var got []TaskListEvent
+		l := NewListComponent("")
+		if !c.skipSetTasks {
+			l.tasks = tasks
+		}
+		l.SelectedRow = c.selectedRow
+		l.SetEventHandler(func(e TaskListEvent) {
+			got = append(got, e)
+		})
+		for _, k := range c.keys {
+			assert.NoError(t, l.HandleEvent(ui.Event{ID: k}))
+		}
+		assert.Equal(t, c.expect, got, "keys: %v", c.keys)
+		assert.Equal(t, c.expectedRow, l.SelectedRow, "keys: %v", c.keys)
+	}
+}
+
+func TestFormatTaskRowWithTags(t *testing.T) {
+	assert.Equal(t, "+ Home #family", formatTaskRow(&model.Task{Type: model.TaskTypeCategory, Title: "Home", Tags: []string{"family"}}, 40))
+	row := formatTaskRow(&model.Task{Type: model.TaskTypeTask, Title: "Call mom", Tags: []string{"family", "phone"}}, 60)
+	assert.Contains(t, row, "[ ] Call mom #family #phone ")
+}
+
+func TestFormatTaskRowWithPriority(t *testing.T) {
+	assert.Equal(t, "+ !! Home", formatTaskRow(&model.Task{Type: model.TaskTypeCategory, Title: "Home", Priority: model.TaskPriorityMedium}, 40))
+	row := formatTaskRow(&model.Task{Type: model.TaskTypeTask, Title: "Call mom", Priority: model.TaskPriorityHigh}, 60)
+	assert.Contains(t, row, "[ ] !!! Call mom ")
+}
+
+func TestTaskListComponentCyclesSorts(t *testing.T) {
+	var got []TaskListEvent
+	l := NewListComponent("Tasks")
+	l.SetEventHandler(func(e TaskListEvent) {
+		got = append(got, e)
+	})
+	assert.Nil(t, l.Sort())
+
+	assert.NoError(t, l.HandleEvent(ui.Event{ID: "s"}))
+	assert.Equal(t, model.TaskSort{model.SortByPriority, model.SortByDue}, l.Sort())
+	assert.Equal(t, "Tasks (by priority)", l.Title)
+	assert.Equal(t, []TaskListEvent{{Type: EventSortTasks}}, got)
+
+	// back to the manual order
+	for range taskSorts[1:] {
+		assert.NoError(t, l.HandleEvent(ui.Event{ID: "s"}))
+	}
+	assert.Nil(t, l.Sort())
+	assert.Equal(t, "Tasks", l.Title)
+	assert.Len(t, got, len(taskSorts))
+}
+
+func TestFormatProjectRow(t *testing.T) {
+	row := formatTaskRow(&model.Task{
+		Type:     model.TaskTypeProject,
+		Title:    "Release",
+		Progress: &model.Progress{Completed: 2, Total: 5, Remaining: 90 * time.Minute},
+	}, 60)
+	assert.Len(t, row, 60)
+	assert.True(t, strings.HasPrefix(row, "* Release "))
+	assert.True(t, strings.HasSuffix(row, " [####------] 2/5 1h30m left"))
+
+	row = formatTaskRow(&model.Task{
+		Type:     model.TaskTypeProject,
+		Title:    "Release",
+		State:    model.TaskStateCompleted,
+		Progress: &model.Progress{Completed: 5, Total: 5},
+	}, 40)
+	assert.Equal(t, "* Release (done)        [##########] 5/5", row)
+	// without tasks
+	assert.True(t, strings.HasSuffix(formatTaskRow(&model.Task{Type: model.TaskTypeProject, Title: "Idea"}, 40), " [----------] 0/0"))
+}
+
+func TestTaskListComponentNavigatesPath(t *testing.T) {
+	work, release := &model.Task{ID: 1, Title: "Work"}, &model.Task{ID: 2, Title: "Release"}
+	var got []TaskListEvent
+	l := NewListComponent("Tasks")
+	l.SetEventHandler(func(e TaskListEvent) {
+		got = append(got, e)
+	})
+	l.SetParentID(work.ID)
+	l.SetPath([]*model.Task{work})
+	l.UpdateTasks([]*model.Task{release, {ID: 3}})
+	assert.NoError(t, l.Update())
+	got = nil
+	assert.Equal(t, "Tasks: Work", l.Title)
+	// nowhere to leave for on the top level
+	assert.Equal(t, 2, len(l.Rows))
+	assert.NoError(t, l.HandleEvent(ui.Event{ID: "h"}))
+	assert.NoError(t, l.HandleEvent(ui.Event{ID: "<Enter>"}))
+	assert.Equal(t, []TaskListEvent{{Type: EventEnterTask}}, got)
+
+	l.SetParentID(release.ID)
+	l.SetPath([]*model.Task{work, release})
+	l.UpdateTasks([]*model.Task{{ID: 4}, {ID: 5}})
+	assert.NoError(t, l.Update())
+	got = nil
+	assert.Equal(t, "Tasks: Work > Release", l.Title)
+	assert.Equal(t, []string{".  Release", ".. Work"}, l.Rows[:2])
+	// the first task is selected in a new parent
+	assert.Equal(t, 2, l.SelectedRow)
+	task, ok := l.GetSelectedTask()
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), task.ID)
+	for _, k := range []string{"o", "J", "k", "k", "<Enter>", "<Backspace>", "O"} {
+		assert.NoError(t, l.HandleEvent(ui.Event{ID: k}))
+	}
+	assert.Equal(t, []TaskListEvent{
+		{Type: EventInsertTask, Position: 1},
+		{Type: EventMoveTask, Position: 1},
+		// on ".."
+		{Type: EventLeaveTask},
+		{Type: EventLeaveTask},
+		{Type: EventInsertTask},
+	}, got)
+}
+
+func TestTaskListComponentRemembersCursors(t *testing.T) {
+	l := NewListComponent("Tasks")
+	l.SetParentID(1)
+	l.UpdateTasks([]*model.Task{{ID: 2}, {ID: 3}, {ID: 4}})
+	assert.NoError(t, l.Update())
+	assert.NoError(t, l.HandleEvent(ui.Event{ID: "G"}))
+
+	l.SetParentID(4)
+	l.UpdateTasks([]*model.Task{{ID: 5}, {ID: 6}})
+	assert.NoError(t, l.Update())
+	assert.NoError(t, l.HandleEvent(ui.Event{ID: "j"}))
+
+	// back where it was left in each parent
+	l.SetParentID(1)
+	l.UpdateTasks([]*model.Task{{ID: 2}, {ID: 3}, {ID: 4}})
+	assert.NoError(t, l.Update())
+	task, _ := l.GetSelectedTask()
+	assert.Equal(t, int64(4), task.ID)
+	l.SetParentID(4)
+	l.UpdateTasks([]*model.Task{{ID: 5}, {ID: 6}})
+	assert.NoError(t, l.Update())
+	task, _ = l.GetSelectedTask()
+	assert.Equal(t, int64(6), task.ID)
+}
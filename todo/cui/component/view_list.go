@@ -0,0 +1,135 @@
+package component
+
+import (
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+//go:generate mockgen -destination mock_component/view_list_mock.go github.com/tevino/the-clean-architecture-demo/todo/cui/component ViewList
+
+// ViewList represents a list of views.
+type ViewList interface {
+	InteractiveComponent
+	UpdateViews(views []*model.View)
+	GetSelectedView() (*model.View, bool)
+	SetEventHandler(func(ViewListEvent))
+}
+
+type ViewListEventType int
+
+const (
+	ViewListEventAfterUpdate ViewListEventType = iota
+	EventInsertView
+	EventDeleteView
+	// EventRestoreView is for the last deleted item rather than the selected view.
+	EventRestoreView
+)
+
+type ViewListEvent struct {
+	Type ViewListEventType
+}
+
+// ViewListComponent displays a list of views.
+type ViewListComponent struct {
+	*widgets.List
+	views []*model.View
+	// selected is the view selected before views are replaced.
+	selected    *model.View
+	isActivated bool
+	handleEvent func(ViewListEvent)
+}
+
+// NewViewListComponent creates a ViewListComponent with given title.
+func NewViewListComponent(title string) *ViewListComponent {
+	list := widgets.NewList()
+	if title != "" {
+		list.Title = title
+		list.TitleStyle.Modifier = ui.ModifierBold
+	}
+	return &ViewListComponent{
+		List:        list,
+		handleEvent: func(ViewListEvent) {},
+	}
+}
+
+// HandleEvent handles keyboard events.
+func (l *ViewListComponent) HandleEvent(e ui.Event) error {
+	switch e.ID {
+	case "j", "<Down>":
+		l.selectViewAt(l.SelectedRow + 1)
+	case "k", "<Up>":
+		l.selectViewAt(l.SelectedRow - 1)
+	case "<Home>":
+		l.selectViewAt(0)
+	case "G", "<End>":
+		l.selectViewAt(len(l.Rows) - 1)
+	case "o", "O":
+		l.handleEvent(ViewListEvent{Type: EventInsertView})
+	case "x", "<Delete>":
+		l.handleEvent(ViewListEvent{Type: EventDeleteView})
+	case "u":
+		l.handleEvent(ViewListEvent{Type: EventRestoreView})
+	}
+	return nil
+}
+
+func (l *ViewListComponent) SetEventHandler(handle func(ViewListEvent)) {
+	l.handleEvent = handle
+}
+
+func (l *ViewListComponent) selectViewAt(idx int) {
+	if idx >= 0 && idx < len(l.Rows) {
+		l.SelectedRow = idx
+	}
+}
+
+func (l *ViewListComponent) GetSelectedView() (*model.View, bool) {
+	if l.SelectedRow < len(l.views) {
+		return l.views[l.SelectedRow], true
+	}
+	return nil, false
+}
+
+// SetActivate highlights selected row.
+func (l *ViewListComponent) SetActivate(yes bool) {
+	l.isActivated = yes
+	modifier := ui.ModifierUnderline
+	if yes {
+		modifier = ui.ModifierReverse | ui.ModifierBold
+	}
+	l.SelectedRowStyle.Modifier = modifier
+}
+
+func (l *ViewListComponent) IsActivated() bool {
+	return l.isActivated
+}
+
+// Update shows the views, the selection stays on the same view if it is still listed.
+func (l *ViewListComponent) Update() error {
+	rows := make([]string, len(l.views))
+	for i, v := range l.views {
+		rows[i] = v.Name
+		if l.selected != nil && v.ID == l.selected.ID {
+			l.SelectedRow = i
+		}
+	}
+	l.selected = nil
+	if len(rows) == 0 {
+		rows = emptyRows
+	}
+	if l.SelectedRow >= len(rows) {
+		l.SelectedRow = len(rows) - 1
+	}
+	l.Rows = rows
+	l.handleEvent(ViewListEvent{Type: ViewListEventAfterUpdate})
+	return nil
+}
+
+// UpdateViews replaces views displayed with given slice.
+func (l *ViewListComponent) UpdateViews(views []*model.View) {
+	if v, ok := l.GetSelectedView(); ok {
+		l.selected = v
+	}
+	l.views = views
+}
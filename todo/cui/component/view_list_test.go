@@ -0,0 +1,39 @@
+package component
+
+import (
+	"testing"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+func TestViewListComponentKeepsSelection(t *testing.T) {
+	today, week, overdue := &model.View{ID: -1}, &model.View{ID: -2}, &model.View{ID: -3}
+	l := NewViewListComponent("")
+	l.UpdateViews([]*model.View{today, week, overdue})
+	assert.NoError(t, l.Update())
+	assert.NoError(t, l.HandleEvent(ui.Event{ID: "j"}))
+	v, _ := l.GetSelectedView()
+	assert.Equal(t, week, v)
+
+	// the selection follows the view
+	l.UpdateViews([]*model.View{week, overdue})
+	assert.NoError(t, l.Update())
+	v, _ = l.GetSelectedView()
+	assert.Equal(t, week, v)
+	// or stays in range once the view is gone
+	assert.NoError(t, l.HandleEvent(ui.Event{ID: "j"}))
+	l.UpdateViews([]*model.View{today})
+	assert.NoError(t, l.Update())
+	v, _ = l.GetSelectedView()
+	assert.Equal(t, today, v)
+
+	var events []ViewListEventType
+	l.SetEventHandler(func(e ViewListEvent) { events = append(events, e.Type) })
+	for _, key := range []string{"o", "x", "u", "k"} {
+		assert.NoError(t, l.HandleEvent(ui.Event{ID: key}))
+	}
+	assert.Equal(t, []ViewListEventType{EventInsertView, EventDeleteView, EventRestoreView}, events)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/tevino/the-clean-architecture-demo/todo/cui/io"
 
@@ -21,13 +23,56 @@ type Controller struct {
 	*CUI
 	io.IO
 	CasesTask use.CasesTask
+
+	// cut is the task to be pasted, it is nil if nothing is cut.
+	cut *model.Task
+	// deleted holds IDs of tasks deleted in this session, the last one is restored first.
+	deleted []int64
+	// view is the view whose tasks are shown in taskList instead of the tasks of its parent, it is selected in
+	// viewList and cleared once catList is activated.
+	view *model.View
+	// listed holds what is last listed in each TaskList, a list is listed again once that is changed or its tasks
+	// are changed.
+	listed map[component.TaskList]listing
+	// viewsListed is whether views are listed and not changed since then.
+	viewsListed bool
+}
+
+// listing is what is listed in a TaskList, either tasks of a parent or tasks in a view.
+type listing struct {
+	parentID int64
+	inView   bool
+	viewID   int64
+}
+
+func (c *Controller) handleViewListEvent(e component.ViewListEvent) {
+	switch e.Type {
+	case component.ViewListEventAfterUpdate:
+		if !c.viewList.IsActivated() {
+			return
+		}
+		if v, ok := c.viewList.GetSelectedView(); ok {
+			c.view = v
+			c.descBox.Plain(v.Query)
+		}
+	case component.EventInsertView:
+		c.addView()
+	case component.EventDeleteView:
+		c.deleteView()
+	case component.EventRestoreView:
+		c.restoreTask()
+	}
 }
 
 func (c *Controller) handleCatListEvent(e component.TaskListEvent) {
 	switch e.Type {
 	case component.TaskListEventAfterUpdate:
-		if t, ok := c.catList.GetSelectedTask(); ok {
-			c.taskList.SetParentID(t.ID)
+		// taskList follows the selected category until it is activated, tasks are entered and left there
+		if c.catList.IsActivated() {
+			if t, ok := c.catList.GetSelectedTask(); ok {
+				c.taskList.SetParentID(t.ID)
+			}
+			c.view = nil
 		}
 		fallthrough
 	default:
@@ -36,25 +81,127 @@ func (c *Controller) handleCatListEvent(e component.TaskListEvent) {
 }
 
 func (c *Controller) handleTaskListEvent(e component.TaskListEvent) {
+	switch e.Type {
+	case component.EventInsertTask, component.EventPasteTask, component.EventMoveTask:
+		if c.view != nil {
+			c.stateBar.Info(fmt.Sprintf("Tasks could not be added or moved in view %s", c.view.Name))
+			return
+		}
+	case component.EventEnterTask:
+		// a task in a view is entered among the tasks of its parent
+		if t, ok := c.taskList.GetSelectedTask(); ok {
+			c.view = nil
+			c.taskList.SetParentID(t.ID)
+		}
+		return
+	case component.EventLeaveTask:
+		if path := c.taskList.Path(); len(path) > 1 {
+			c.taskList.SetParentID(path[len(path)-2].ID)
+		}
+		return
+	}
 	c.handleGenericTaskListEvent(c.taskList, e)
 }
 
+// handleGenericTaskListEvent handles events of any TaskList. Positions only make sense in the manual order, tasks
+// are added to the end of a sorted list and could not be moved in it.
 func (c *Controller) handleGenericTaskListEvent(l component.TaskList, e component.TaskListEvent) {
+	switch e.Type {
+	case component.EventInsertTask, component.EventPasteTask:
+		if l.Sort() != nil {
+			e.Position = -1
+		}
+	case component.EventMoveTask:
+		if l.Sort() != nil {
+			c.stateBar.Info("Tasks could only be moved in the manual order, press s to change the sort")
+			return
+		}
+	}
 	switch e.Type {
 	case component.TaskListEventAfterUpdate:
 		c.setDescriptionByCurrentSelectedRow(l)
-	case component.EventInsertTaskWithOrder:
-		c.insertTaskWithOrder(l, e.Order)
+	case component.EventInsertTask:
+		c.insertTask(l, e.Position)
 	case component.EventChangeTaskState:
 		c.changeTaskState(l)
+	case component.EventDeleteTask:
+		c.deleteTask(l)
+	case component.EventRestoreTask:
+		c.restoreTask()
+	case component.EventCutTask:
+		c.cutTask(l)
+	case component.EventPasteTask:
+		c.pasteTask(l, e.Position)
+	case component.EventMoveTask:
+		c.moveTask(l, e.Position)
+	case component.EventSortTasks:
+		// listed again with the new sort
+		delete(c.listed, l)
+	}
+}
+
+func (c *Controller) cutTask(l component.TaskList) {
+	if t, ok := l.GetSelectedTask(); ok {
+		c.cut = t
+		c.stateBar.Info(fmt.Sprintf("Task Cut: %s, press p to paste", t.Title))
+	}
+}
+
+func (c *Controller) pasteTask(l component.TaskList, position int) {
+	if c.cut == nil {
+		c.stateBar.Info("Nothing to paste")
+		return
+	}
+	err := c.CasesTask.MoveTask(c.cut.ID, l.ParentID(), position)
+	if err != nil {
+		c.stateBar.Warn(fmt.Errorf("moving task[%d]: %w", c.cut.ID, err))
+		return
 	}
+	c.cut = nil
+}
+
+func (c *Controller) moveTask(l component.TaskList, position int) {
+	t, ok := l.GetSelectedTask()
+	if !ok {
+		return
+	}
+	if err := c.CasesTask.MoveTask(t.ID, l.ParentID(), position); err != nil {
+		c.stateBar.Warn(fmt.Errorf("moving task[%d]: %w", t.ID, err))
+	}
+}
+
+func (c *Controller) deleteTask(l component.TaskList) {
+	t, ok := l.GetSelectedTask()
+	if !ok {
+		return
+	}
+	if err := c.CasesTask.DeleteTask(t.ID); err != nil {
+		c.stateBar.Warn(fmt.Errorf("deleting task[%d]: %w", t.ID, err))
+		return
+	}
+	c.deleted = append(c.deleted, t.ID)
+}
+
+func (c *Controller) restoreTask() {
+	if len(c.deleted) == 0 {
+		c.stateBar.Info("Nothing to restore")
+		return
+	}
+	id := c.deleted[len(c.deleted)-1]
+	if err := c.CasesTask.RestoreTask(id); err != nil {
+		c.stateBar.Warn(fmt.Errorf("restoring task[%d]: %w", id, err))
+		return
+	}
+	c.deleted = c.deleted[:len(c.deleted)-1]
 }
 
 func (c *Controller) changeTaskState(l component.TaskList) {
 	t, ok := l.GetSelectedTask()
 	if ok {
-		err := c.CasesTask.ChangeTaskStateByID(t.ID, toggleCompletedState(t.State))
-		if err != nil {
+		err := c.CasesTask.ChangeTaskStateByID(t.ID, t.Revision, toggleCompletedState(t.State))
+		if errors.Is(err, use.ErrConflict) {
+			c.stateBar.Warn(fmt.Errorf("task[%d] was changed elsewhere, check it and try again: %w", t.ID, err))
+		} else if err != nil {
 			c.stateBar.Warn(fmt.Errorf("changing task[%d] state: %w", t.ID, err))
 		}
 	}
@@ -81,7 +228,7 @@ func (c *Controller) setDescriptionByCurrentSelectedRow(l component.TaskList) {
 	}
 }
 
-func (c *Controller) insertTaskWithOrder(l component.TaskList, order uint64) {
+func (c *Controller) insertTask(l component.TaskList, position int) {
 	defer func() {
 		err := c.CUILib.Init()
 		if err != nil {
@@ -95,16 +242,21 @@ func (c *Controller) insertTaskWithOrder(l component.TaskList, order uint64) {
 	}
 	form, err := createFormAddTaskFromString(buf)
 	if err != nil {
+		if !errors.Is(err, errEmptyInput) {
+			c.stateBar.Warn(fmt.Errorf("adding task: %w", err))
+		}
 		return
 	}
-	switch l {
-	case c.catList:
+	switch {
+	case form.Type == model.TaskTypeProject:
+		// projects could be added to either list
+	case l == c.catList:
 		form.Type = model.TaskTypeCategory
-	case c.taskList:
+	case l == c.taskList:
 		form.Type = model.TaskTypeTask
 	}
 	form.ParentID = l.ParentID()
-	form.Order = order
+	form.Position = position
 	err = c.CasesTask.AddTask(form)
 	if err != nil {
 		// TODO: launch editor again to let use re-edit the file.
@@ -113,7 +265,68 @@ func (c *Controller) insertTaskWithOrder(l component.TaskList, order uint64) {
 	}
 }
 
+// addView adds a view named by the first line of the input, the rest is the query.
+func (c *Controller) addView() {
+	defer func() {
+		err := c.CUILib.Init()
+		if err != nil {
+			panic(fmt.Sprintf("failed to initialize CUI: %s", err))
+		}
+	}()
+	buf, err := c.GetInputByLaunchingEditor()
+	if err != nil {
+		c.stateBar.Warn(err)
+		return
+	}
+	lines := strings.SplitN(strings.TrimSpace(buf), "\n", 2)
+	if lines[0] == "" {
+		return
+	}
+	var query string
+	if len(lines) > 1 {
+		query = strings.Join(strings.Fields(lines[1]), " ")
+	}
+	if err := c.CasesTask.AddView(lines[0], query); err != nil {
+		c.stateBar.Warn(fmt.Errorf("adding view: %w", err))
+	}
+}
+
+func (c *Controller) deleteView() {
+	v, ok := c.viewList.GetSelectedView()
+	if !ok {
+		return
+	}
+	if err := c.CasesTask.DeleteView(v.ID); err != nil {
+		c.stateBar.Warn(fmt.Errorf("deleting view %s: %w", v.Name, err))
+		return
+	}
+	c.deleted = append(c.deleted, v.ID)
+}
+
+// searchTasks searches tasks by the keyword on the first line of the input.
+func (c *Controller) searchTasks() {
+	defer func() {
+		err := c.CUILib.Init()
+		if err != nil {
+			panic(fmt.Sprintf("failed to initialize CUI: %s", err))
+		}
+	}()
+	buf, err := c.GetInputByLaunchingEditor()
+	if err != nil {
+		c.stateBar.Warn(err)
+		return
+	}
+	keyword := strings.TrimSpace(strings.SplitN(buf, "\n", 2)[0])
+	if keyword == "" {
+		return
+	}
+	if err := c.CasesTask.SearchInTasks(keyword); err != nil {
+		c.stateBar.Warn(fmt.Errorf("searching tasks: %w", err))
+	}
+}
+
 func (c *Controller) init() error {
+	c.viewList.SetEventHandler(c.handleViewListEvent)
 	c.catList.SetEventHandler(c.handleCatListEvent)
 	c.taskList.SetEventHandler(c.handleTaskListEvent)
 
@@ -131,7 +344,7 @@ func (c *Controller) close() {
 	c.CUILib.Close()
 }
 
-// Loop starts rendering the CUI.
+// Loop starts rendering the CUI, it renders again on user input or changes of tasks.
 func (c *Controller) Loop() error {
 	err := c.init()
 	if err != nil {
@@ -139,26 +352,57 @@ func (c *Controller) Loop() error {
 	}
 	defer c.close()
 	uiEvents := c.CUILib.PollEvents()
+	taskEvents, cancel := c.CasesTask.SubscribeTaskEvents()
+	defer cancel()
 	for {
+		err := c.Update()
+		if err != nil {
+			c.stateBar.Warn(err)
+		}
 		select {
 		case e := <-uiEvents:
 			quit := c.handleEvent(e)
 			if quit {
 				return nil
 			}
-		default:
-			err := c.Update()
-			if err != nil {
-				c.stateBar.Warn(err)
+		case e, ok := <-taskEvents:
+			if !ok {
+				taskEvents = nil
+				break
 			}
+			c.handleTaskEvent(e)
+		case r := <-c.reminders:
+			c.stateBar.Info(formatReminder(r))
+		case err := <-c.warnings:
+			c.stateBar.Warn(err)
+		}
+	}
+}
+
+// handleTaskEvent marks lists showing the changed task to be listed again, including the one it is moved out of,
+// lists of views are listed again on any change since the task may enter or leave them. Lists showing the projects
+// above the task are marked by the updates of those projects which follow, see CasesTask.SubscribeTaskEvents.
+func (c *Controller) handleTaskEvent(e model.TaskEvent) {
+	for l, listed := range c.listed {
+		if listed.inView || listed.parentID == e.Task.ParentID || listed.parentID == e.PreviousParentID {
+			delete(c.listed, l)
 		}
 	}
+	if e.Task.Type == model.TaskTypeView {
+		c.viewsListed = false
+	}
 }
 
 func (c *Controller) handleEvent(e ui.Event) bool {
 	switch e.ID {
 	case "q", "<C-c>":
 		return true
+	case "/":
+		c.searchTasks()
+	case "#":
+		if err := c.CasesTask.ListTags(); err != nil {
+			c.stateBar.Warn(fmt.Errorf("listing tags: %w", err))
+		}
 	case "<Resize>":
 		payload := e.Payload.(ui.Resize)
 		c.grid.SetRect(0, 0, payload.Width, payload.Height)
@@ -172,26 +416,69 @@ func (c *Controller) handleEvent(e ui.Event) bool {
 	return false
 }
 
+// Update renders all components, tasks of a TaskList and views of a ViewList are listed before rendering if they
+// are not up to date.
 func (c *Controller) Update() error {
+	if c.listed == nil {
+		c.listed = make(map[component.TaskList]listing)
+	}
 	c.CUILib.Render(c.grid)
 	for _, r := range c.components {
+		// Update tasks
+		switch l := r.(type) {
+		case component.TaskList:
+			if err := c.listTasks(l); err != nil {
+				return err
+			}
+		case component.ViewList:
+			if err := c.listViews(); err != nil {
+				return err
+			}
+		}
 		// render
 		err := r.Update()
 		if err != nil {
 			return err
 		}
-		// Update tasks
-		if l, ok := r.(component.TaskList); ok {
-			err := c.CasesTask.ListTasksByParentID(l.ParentID())
-			if err != nil {
-				return fmt.Errorf("get tasks of parent[%d]: %w", l.ParentID(), err)
-			}
-		}
 		c.CUILib.Render(r.(ui.Drawable))
 	}
 	return nil
 }
 
+func (c *Controller) listTasks(l component.TaskList) error {
+	want := listing{parentID: l.ParentID()}
+	if l == c.taskList && c.view != nil {
+		want = listing{inView: true, viewID: c.view.ID}
+	}
+	if listed, ok := c.listed[l]; ok && listed == want {
+		return nil
+	}
+	if want.inView {
+		if err := c.CasesTask.ListTasksInView(want.viewID, l.Sort()); err != nil {
+			return fmt.Errorf("get tasks in view %s: %w", c.view.Name, err)
+		}
+	} else if err := c.CasesTask.ListTasksByParentID(want.parentID, l.Sort()); err != nil {
+		return fmt.Errorf("get tasks of parent[%d]: %w", want.parentID, err)
+	} else if l == c.taskList {
+		if err := c.CasesTask.ListPathToParentID(want.parentID); err != nil {
+			return fmt.Errorf("get path to parent[%d]: %w", want.parentID, err)
+		}
+	}
+	c.listed[l] = want
+	return nil
+}
+
+func (c *Controller) listViews() error {
+	if c.viewsListed {
+		return nil
+	}
+	if err := c.CasesTask.ListViews(); err != nil {
+		return fmt.Errorf("get views: %w", err)
+	}
+	c.viewsListed = true
+	return nil
+}
+
 var ErrInvalidDue = errors.New("invalid due")
 
 func parseDue(s string) (time.Time, error) {
@@ -211,12 +498,56 @@ func parseDue(s string) (time.Time, error) {
 
 var errEmptyInput = errors.New("empty input")
 
+// recurrencePrefix starts a line of the input of a task which is the RRULE it repeats by.
+const recurrencePrefix = "RRULE:"
+
+// priorityPrefix starts a line of the input of a task which is its priority, see taskPriorities.
+const priorityPrefix = "PRIORITY:"
+
+// taskPriorities are priorities by their names in the input of a task.
+var taskPriorities = map[string]model.TaskPriority{
+	"none":   model.TaskPriorityNone,
+	"low":    model.TaskPriorityLow,
+	"medium": model.TaskPriorityMedium,
+	"high":   model.TaskPriorityHigh,
+}
+
+// ErrInvalidPriority is returned for a priority which is not in taskPriorities.
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// remindPrefix starts a line of the input of a task which is a comma separated list of reminders, e.g. 15m, 1d.
+const remindPrefix = "REMIND:"
+
+// estimatePrefix starts a line of the input of a task which is its estimate, e.g. 2h.
+const estimatePrefix = "ESTIMATE:"
+
+// typePrefix starts a line of the input of a task which is its type, see taskTypes.
+const typePrefix = "TYPE:"
+
+// taskTypes are types by their names in the input of a task, tasks and categories are told by the list they are
+// added to.
+var taskTypes = map[string]model.TaskType{
+	"project": model.TaskTypeProject,
+}
+
+// ErrInvalidType is returned for a type which is not in taskTypes.
+var ErrInvalidType = errors.New("invalid type")
+
+// createFormAddTaskFromString creates a form from the title on the first non-empty line, followed by an optional due,
+// the rest is the description. Words of the title starting with "#" and a letter are tags, e.g. #work but not #1.
+// A line starting with "RRULE:" anywhere after the title is the recurrence instead, as is a line starting with
+// "REMIND:" the reminders, a line starting with "PRIORITY:" the priority, e.g. PRIORITY: high, a line starting with
+// "ESTIMATE:" the estimate and a line of "TYPE: project" makes a project.
 func createFormAddTaskFromString(s string) (*model.FormAddTask, error) {
 	if s == "" {
 		return nil, errEmptyInput
 	}
 
-	var title, desc string
+	var title, desc, recurrence, estimate string
+	var reminders, tags []string
+	var priority model.TaskPriority
+	var hasPriority, hasType bool
+	var taskType model.TaskType
 	var due time.Time
 	var skipDue = false
 	scanner := bufio.NewScanner(strings.NewReader(s))
@@ -225,7 +556,25 @@ func createFormAddTaskFromString(s string) (*model.FormAddTask, error) {
 		trimmedLine := strings.TrimSpace(line)
 		isLineEmpty := line == "" || trimmedLine == ""
 		if title == "" && !isLineEmpty {
-			title = trimmedLine
+			title, tags = splitTitleTags(trimmedLine)
+		} else if recurrence == "" && strings.HasPrefix(strings.ToUpper(trimmedLine), recurrencePrefix) {
+			recurrence = trimmedLine[len(recurrencePrefix):]
+		} else if !hasPriority && strings.HasPrefix(strings.ToUpper(trimmedLine), priorityPrefix) {
+			name := strings.ToLower(strings.TrimSpace(trimmedLine[len(priorityPrefix):]))
+			if priority, hasPriority = taskPriorities[name]; !hasPriority {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidPriority, name)
+			}
+		} else if !hasType && strings.HasPrefix(strings.ToUpper(trimmedLine), typePrefix) {
+			name := strings.ToLower(strings.TrimSpace(trimmedLine[len(typePrefix):]))
+			if taskType, hasType = taskTypes[name]; !hasType {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidType, name)
+			}
+		} else if estimate == "" && strings.HasPrefix(strings.ToUpper(trimmedLine), estimatePrefix) {
+			estimate = strings.TrimSpace(trimmedLine[len(estimatePrefix):])
+		} else if reminders == nil && strings.HasPrefix(strings.ToUpper(trimmedLine), remindPrefix) {
+			for _, r := range strings.Split(trimmedLine[len(remindPrefix):], ",") {
+				reminders = append(reminders, strings.TrimSpace(r))
+			}
 		} else if !skipDue && due.IsZero() && !isLineEmpty {
 			parsedDue, err := parseDue(line)
 			if err != nil {
@@ -243,5 +592,27 @@ func createFormAddTaskFromString(s string) (*model.FormAddTask, error) {
 		Title:       title,
 		Due:         due,
 		Description: desc,
+		Type:        taskType,
+		Priority:    priority,
+		Recurrence:  recurrence,
+		Reminders:   reminders,
+		Tags:        tags,
+		Estimate:    estimate,
 	}, nil
 }
+
+// splitTitleTags splits tags out of the line of a title, the title is the line if it has nothing but tags.
+func splitTitleTags(line string) (title string, tags []string) {
+	var words []string
+	for _, w := range strings.Fields(line) {
+		if r, _ := utf8.DecodeRuneInString(strings.TrimPrefix(w, "#")); w[0] == '#' && unicode.IsLetter(r) {
+			tags = append(tags, w[1:])
+			continue
+		}
+		words = append(words, w)
+	}
+	if len(words) == 0 {
+		return line, nil
+	}
+	return strings.Join(words, " "), tags
+}
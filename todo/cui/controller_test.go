@@ -1,8 +1,11 @@
 package cui
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	ui "github.com/gizak/termui/v3"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/tevino/the-clean-architecture-demo/todo/cui/component"
 
 	"github.com/tevino/the-clean-architecture-demo/todo/model"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
 
 	"github.com/golang/mock/gomock"
 )
@@ -42,32 +46,125 @@ func TestCatListAfterUpdateSetsTaskListParent(t *testing.T) {
 	c.catList = mockCatList
 	c.taskList = mockTaskList
 	gomock.InOrder(
+		mockCatList.EXPECT().IsActivated().Return(true),
 		mockCatList.EXPECT().GetSelectedTask().Return(task, true),
 		mockTaskList.EXPECT().SetParentID(task.ID),
 		mockCatList.EXPECT().IsActivated(),
+		// not followed once categories are left
+		mockCatList.EXPECT().IsActivated(),
+		mockCatList.EXPECT().IsActivated(),
 	)
 	c.handleCatListEvent(component.TaskListEvent{Type: component.TaskListEventAfterUpdate})
+	c.handleCatListEvent(component.TaskListEvent{Type: component.TaskListEventAfterUpdate})
+}
+
+func TestViewListSelectsViewOfTaskList(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockViewList := mock_component.NewMockViewList(ctl)
+	mockCatList := mock_component.NewMockTaskList(ctl)
+	mockTaskList := mock_component.NewMockTaskList(ctl)
+	cases := mock_use.NewMockCasesTask(ctl)
+	today := &model.View{ID: -1, Name: "Today"}
+
+	c := newController(ctl)
+	c.viewList, c.catList, c.taskList = mockViewList, mockCatList, mockTaskList
+	c.CasesTask = cases
+	c.listed = make(map[component.TaskList]listing)
+	mockTaskList.EXPECT().Sort().AnyTimes()
+	gomock.InOrder(
+		// the view is kept when the views pane is left for the task list
+		mockViewList.EXPECT().IsActivated().Return(true),
+		mockViewList.EXPECT().GetSelectedView().Return(today, true),
+		mockViewList.EXPECT().IsActivated().Return(false),
+		cases.EXPECT().ListTasksInView(today.ID, nil),
+		// until categories are activated
+		mockCatList.EXPECT().IsActivated().Return(true),
+		mockCatList.EXPECT().GetSelectedTask().Return(&model.Task{ID: 42}, true),
+		mockTaskList.EXPECT().SetParentID(int64(42)),
+		mockCatList.EXPECT().IsActivated().Return(false),
+		mockTaskList.EXPECT().ParentID().Return(int64(42)),
+		cases.EXPECT().ListTasksByParentID(int64(42), nil),
+		cases.EXPECT().ListPathToParentID(int64(42)),
+	)
+	c.handleViewListEvent(component.ViewListEvent{Type: component.ViewListEventAfterUpdate})
+	c.handleViewListEvent(component.ViewListEvent{Type: component.ViewListEventAfterUpdate})
+	mockTaskList.EXPECT().ParentID().Return(int64(7))
+	assert.NoError(t, c.listTasks(c.taskList))
+	// tasks in a view are listed again on any change
+	c.handleTaskEvent(model.TaskEvent{Task: &model.Task{ID: 3, ParentID: 5}})
+	mockTaskList.EXPECT().ParentID().Return(int64(7))
+	cases.EXPECT().ListTasksInView(today.ID, nil)
+	assert.NoError(t, c.listTasks(c.taskList))
+
+	c.handleCatListEvent(component.TaskListEvent{Type: component.TaskListEventAfterUpdate})
+	assert.NoError(t, c.listTasks(c.taskList))
+}
+
+func TestTaskEventInvalidatesLists(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockCatList := mock_component.NewMockTaskList(ctl)
+	mockTaskList := mock_component.NewMockTaskList(ctl)
+	c := newController(ctl)
+	c.listed = map[component.TaskList]listing{mockCatList: {parentID: 0}, mockTaskList: {parentID: 42}}
+	// a category moved under a task leaves the categories
+	c.handleTaskEvent(model.TaskEvent{Type: model.TaskEventUpdated, Task: &model.Task{ID: 3, ParentID: 7}, PreviousParentID: 0})
+	assert.Equal(t, map[component.TaskList]listing{mockTaskList: {parentID: 42}}, c.listed)
+	c.handleTaskEvent(model.TaskEvent{Type: model.TaskEventUpdated, Task: &model.Task{ID: 3, ParentID: 42}, PreviousParentID: 7})
+	assert.Empty(t, c.listed)
 }
 
-func TestInsertTaskWithOrder(t *testing.T) {
+func TestAddAndDeleteView(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockViewList := mock_component.NewMockViewList(ctl)
+	c := newController(ctl)
+	c.viewList = mockViewList
+	in := c.IO.(*mock_cui.MockIO)
+	lib := c.CUILib.(*mock_cui.MockCUILib)
+	cases := c.CasesTask.(*mock_use.MockCasesTask)
+	urgent := &model.View{ID: 5, Name: "Urgent"}
+	gomock.InOrder(
+		in.EXPECT().GetInputByLaunchingEditor().Return("Urgent\ndue < today+1d\n  and state:todo\n", nil),
+		cases.EXPECT().AddView("Urgent", "due < today+1d and state:todo"),
+		lib.EXPECT().Init(),
+		// nothing to add
+		in.EXPECT().GetInputByLaunchingEditor().Return("\n", nil),
+		lib.EXPECT().Init(),
+		mockViewList.EXPECT().GetSelectedView().Return(urgent, true),
+		cases.EXPECT().DeleteView(urgent.ID),
+		cases.EXPECT().RestoreTask(urgent.ID),
+	)
+	c.handleViewListEvent(component.ViewListEvent{Type: component.EventInsertView})
+	c.handleViewListEvent(component.ViewListEvent{Type: component.EventInsertView})
+	c.handleViewListEvent(component.ViewListEvent{Type: component.EventDeleteView})
+	c.handleViewListEvent(component.ViewListEvent{Type: component.EventRestoreView})
+}
+
+func TestInsertTask(t *testing.T) {
 	ctl := gomock.NewController(t)
 	defer ctl.Finish()
 
 	// expectations
 	var (
-		parentID  int64  = 42
-		order     uint64 = 4200
-		taskInput        = "test title"
+		parentID  int64 = 42
+		position        = 3
+		taskInput       = "test title"
 	)
 
 	c := newController(ctl)
 
 	mockList := mock_component.NewMockTaskList(ctl)
+	mockList.EXPECT().Sort().AnyTimes()
 	c.taskList = mockList
 	form, err := createFormAddTaskFromString(taskInput)
 	assert.NoError(t, err)
 	form.ParentID = parentID
-	form.Order = order
+	form.Position = position
 	form.Type = model.TaskTypeTask
 	gomock.InOrder(
 		c.IO.(*mock_cui.MockIO).EXPECT().GetInputByLaunchingEditor().Return(taskInput, nil),
@@ -76,7 +173,27 @@ func TestInsertTaskWithOrder(t *testing.T) {
 		c.CUILib.(*mock_cui.MockCUILib).EXPECT().Init(),
 	)
 
-	c.handleTaskListEvent(component.TaskListEvent{Type: component.EventInsertTaskWithOrder, Order: order})
+	c.handleTaskListEvent(component.TaskListEvent{Type: component.EventInsertTask, Position: position})
+}
+
+func TestInsertProject(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	c := newController(ctl)
+	mockList := mock_component.NewMockTaskList(ctl)
+	mockList.EXPECT().Sort().AnyTimes()
+	c.catList = mockList
+	gomock.InOrder(
+		c.IO.(*mock_cui.MockIO).EXPECT().GetInputByLaunchingEditor().Return("Release\nTYPE: project\nESTIMATE: 2d\n", nil),
+		mockList.EXPECT().ParentID().Return(int64(7)),
+		// a project rather than a category
+		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().AddTask(&model.FormAddTask{
+			Title: "Release", Type: model.TaskTypeProject, Estimate: "2d", ParentID: 7, Position: 1,
+		}),
+		c.CUILib.(*mock_cui.MockCUILib).EXPECT().Init(),
+	)
+	c.handleCatListEvent(component.TaskListEvent{Type: component.EventInsertTask, Position: 1})
 }
 
 func TestChangeStateEventHandled(t *testing.T) {
@@ -88,20 +205,167 @@ func TestChangeStateEventHandled(t *testing.T) {
 	mockList := mock_component.NewMockTaskList(ctl)
 	c := newController(ctl)
 	c.stateBar = mockText
-	task := &model.Task{}
+	task := &model.Task{Revision: 42}
 	// OK
 	gomock.InOrder(
 		mockList.EXPECT().GetSelectedTask().Return(task, true),
-		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().ChangeTaskStateByID(task.ID, gomock.Any()).Return(nil),
+		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().ChangeTaskStateByID(task.ID, task.Revision, gomock.Any()).Return(nil),
 	)
 	c.changeTaskState(mockList)
 	// Error
 	gomock.InOrder(
 		mockList.EXPECT().GetSelectedTask().Return(task, true),
-		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().ChangeTaskStateByID(task.ID, gomock.Any()).Return(io.EOF),
+		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().ChangeTaskStateByID(task.ID, task.Revision, gomock.Any()).Return(io.EOF),
 		mockText.EXPECT().Warn(gomock.Any()),
 	)
 	c.changeTaskState(mockList)
+	// Conflict
+	conflict := fmt.Errorf("saving item: %w", use.ErrConflict)
+	gomock.InOrder(
+		mockList.EXPECT().GetSelectedTask().Return(task, true),
+		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().ChangeTaskStateByID(task.ID, task.Revision, gomock.Any()).Return(conflict),
+		mockText.EXPECT().Warn(gomock.Any()).Do(func(err error) {
+			assert.True(t, errors.Is(err, use.ErrConflict))
+			assert.Contains(t, err.Error(), "changed elsewhere")
+		}),
+	)
+	c.changeTaskState(mockList)
+}
+
+func TestDeleteAndRestoreTask(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockText := mock_component.NewMockText(ctl)
+	mockList := mock_component.NewMockTaskList(ctl)
+	c := newController(ctl)
+	c.stateBar = mockText
+	cases := c.CasesTask.(*mock_use.MockCasesTask)
+	first, second := &model.Task{ID: 1}, &model.Task{ID: 2}
+	gomock.InOrder(
+		mockList.EXPECT().GetSelectedTask().Return(first, true),
+		cases.EXPECT().DeleteTask(first.ID),
+		mockList.EXPECT().GetSelectedTask().Return(second, true),
+		cases.EXPECT().DeleteTask(second.ID),
+		// the last deleted is restored first, and kept if failed
+		cases.EXPECT().RestoreTask(second.ID).Return(io.EOF),
+		mockText.EXPECT().Warn(gomock.Any()),
+		cases.EXPECT().RestoreTask(second.ID),
+		cases.EXPECT().RestoreTask(first.ID),
+		mockText.EXPECT().Info(gomock.Any()),
+	)
+	c.handleGenericTaskListEvent(mockList, component.TaskListEvent{Type: component.EventDeleteTask})
+	c.handleGenericTaskListEvent(mockList, component.TaskListEvent{Type: component.EventDeleteTask})
+	for i := 0; i < 4; i++ {
+		c.handleGenericTaskListEvent(mockList, component.TaskListEvent{Type: component.EventRestoreTask})
+	}
+}
+
+func TestCutAndPasteTask(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockText := mock_component.NewMockText(ctl)
+	from, to := mock_component.NewMockTaskList(ctl), mock_component.NewMockTaskList(ctl)
+	to.EXPECT().Sort().AnyTimes()
+	c := newController(ctl)
+	c.stateBar = mockText
+	cases := c.CasesTask.(*mock_use.MockCasesTask)
+	task := &model.Task{ID: 42}
+	var parentID int64 = 7
+	gomock.InOrder(
+		mockText.EXPECT().Info("Nothing to paste"),
+		from.EXPECT().GetSelectedTask().Return(task, true),
+		mockText.EXPECT().Info(gomock.Any()),
+		to.EXPECT().ParentID().Return(parentID),
+		cases.EXPECT().MoveTask(task.ID, parentID, 3).Return(use.ErrMoveUnderItself),
+		mockText.EXPECT().Warn(gomock.Any()),
+		// still cut after a failure
+		to.EXPECT().ParentID().Return(parentID),
+		cases.EXPECT().MoveTask(task.ID, parentID, 2),
+		mockText.EXPECT().Info("Nothing to paste"),
+	)
+	c.handleGenericTaskListEvent(to, component.TaskListEvent{Type: component.EventPasteTask})
+	c.handleGenericTaskListEvent(from, component.TaskListEvent{Type: component.EventCutTask})
+	c.handleGenericTaskListEvent(to, component.TaskListEvent{Type: component.EventPasteTask, Position: 3})
+	c.handleGenericTaskListEvent(to, component.TaskListEvent{Type: component.EventPasteTask, Position: 2})
+	c.handleGenericTaskListEvent(to, component.TaskListEvent{Type: component.EventPasteTask, Position: 2})
+}
+
+func TestMoveTask(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockList := mock_component.NewMockTaskList(ctl)
+	c := newController(ctl)
+	task := &model.Task{ID: 42}
+	gomock.InOrder(
+		mockList.EXPECT().Sort(),
+		mockList.EXPECT().GetSelectedTask().Return(task, true),
+		mockList.EXPECT().ParentID().Return(int64(7)),
+		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().MoveTask(task.ID, int64(7), 1),
+	)
+	c.handleGenericTaskListEvent(mockList, component.TaskListEvent{Type: component.EventMoveTask, Position: 1})
+}
+
+func TestEnterAndLeaveTasks(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockList := mock_component.NewMockTaskList(ctl)
+	c := newController(ctl)
+	c.taskList = mockList
+	c.view = &model.View{ID: -1, Name: "Today"}
+	work, release := &model.Task{ID: 1}, &model.Task{ID: 2, ParentID: 1}
+	gomock.InOrder(
+		// from a view to the sub tasks of a task in it
+		mockList.EXPECT().GetSelectedTask().Return(release, true),
+		mockList.EXPECT().SetParentID(release.ID),
+		mockList.EXPECT().Path().Return([]*model.Task{work, release}),
+		mockList.EXPECT().SetParentID(work.ID),
+		// not above the top level
+		mockList.EXPECT().Path().Return([]*model.Task{work}),
+	)
+	c.handleTaskListEvent(component.TaskListEvent{Type: component.EventEnterTask})
+	assert.Nil(t, c.view)
+	c.handleTaskListEvent(component.TaskListEvent{Type: component.EventLeaveTask})
+	c.handleTaskListEvent(component.TaskListEvent{Type: component.EventLeaveTask})
+}
+
+func TestSortedTaskList(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockList := mock_component.NewMockTaskList(ctl)
+	mockText := mock_component.NewMockText(ctl)
+	c := newController(ctl)
+	c.stateBar = mockText
+	c.listed = map[component.TaskList]listing{mockList: {parentID: 7}}
+	cases := c.CasesTask.(*mock_use.MockCasesTask)
+	byDue := model.TaskSort{model.SortByDue}
+	mockList.EXPECT().Sort().Return(byDue).AnyTimes()
+	gomock.InOrder(
+		// tasks could not be moved
+		mockText.EXPECT().Info(gomock.Any()),
+		// but pasted to the end
+		mockList.EXPECT().GetSelectedTask().Return(&model.Task{ID: 42}, true),
+		mockText.EXPECT().Info(gomock.Any()),
+		mockList.EXPECT().ParentID().Return(int64(7)),
+		cases.EXPECT().MoveTask(int64(42), int64(7), -1),
+		// listed again by the new sort
+		mockList.EXPECT().ParentID().Return(int64(7)),
+		cases.EXPECT().ListTasksByParentID(int64(7), byDue),
+	)
+	c.handleGenericTaskListEvent(mockList, component.TaskListEvent{Type: component.EventMoveTask, Position: 1})
+	c.handleGenericTaskListEvent(mockList, component.TaskListEvent{Type: component.EventCutTask})
+	c.handleGenericTaskListEvent(mockList, component.TaskListEvent{Type: component.EventPasteTask, Position: 1})
+	c.handleGenericTaskListEvent(mockList, component.TaskListEvent{Type: component.EventSortTasks})
+	assert.NoError(t, c.listTasks(mockList))
 }
 
 func TestToggleCompletedState(t *testing.T) {
@@ -136,24 +400,137 @@ func TestControllerLoop(t *testing.T) {
 	defer ctl.Finish()
 
 	c := newController(ctl)
+	lib := c.CUILib.(*mock_cui.MockCUILib)
+	cases := c.CasesTask.(*mock_use.MockCasesTask)
+	uiEvents := make(chan ui.Event)
+	taskEvents := make(chan model.TaskEvent)
+	canceled := false
+	lib.EXPECT().Render(gomock.Any()).AnyTimes()
 	gomock.InOrder(
-		c.CUILib.(*mock_cui.MockCUILib).EXPECT().Init(),
-		c.CUILib.(*mock_cui.MockCUILib).EXPECT().TerminalDimensions(),
-		c.CUILib.(*mock_cui.MockCUILib).EXPECT().PollEvents().Return(uiEvents("q")),
-		c.CUILib.(*mock_cui.MockCUILib).EXPECT().Close(),
+		lib.EXPECT().Init(),
+		lib.EXPECT().TerminalDimensions(),
+		lib.EXPECT().PollEvents().Return(uiEvents),
+		cases.EXPECT().SubscribeTaskEvents().Return(taskEvents, func() { canceled = true }),
+		// views are listed once, since the change is not of a view
+		cases.EXPECT().ListViews(),
+		// both lists are listed at first, then once again on the change of a task, not on user input
+		cases.EXPECT().ListTasksByParentID(int64(0), nil).Times(4),
+		lib.EXPECT().Close(),
 	)
+	// of the task list only
+	cases.EXPECT().ListPathToParentID(int64(0)).Times(2)
 	ended := make(chan bool, 1)
 	go func() {
 		assert.NoError(t, c.Loop())
 		ended <- true
 	}()
+	uiEvents <- ui.Event{ID: "j"}
+	taskEvents <- model.TaskEvent{Type: model.TaskEventCreated, Task: &model.Task{ID: 1}}
+	uiEvents <- ui.Event{ID: "q"}
 	<-ended
+	assert.True(t, canceled)
 }
 
-func uiEvents(keys ...string) <-chan ui.Event {
-	ch := make(chan ui.Event, len(keys)+1)
-	for _, k := range keys {
-		ch <- ui.Event{ID: k}
+func TestSearchTasks(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	c := newController(ctl)
+	in := c.IO.(*mock_cui.MockIO)
+	lib := c.CUILib.(*mock_cui.MockCUILib)
+	gomock.InOrder(
+		in.EXPECT().GetInputByLaunchingEditor().Return("  release notes \nignored\n", nil),
+		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().SearchInTasks("release notes"),
+		lib.EXPECT().Init(),
+		// nothing to search
+		in.EXPECT().GetInputByLaunchingEditor().Return("\n", nil),
+		lib.EXPECT().Init(),
+	)
+
+	assert.False(t, c.handleEvent(ui.Event{ID: "/"}))
+	assert.False(t, c.handleEvent(ui.Event{ID: "/"}))
+}
+
+func TestCreateFormAddTaskFromString(t *testing.T) {
+	t.Parallel()
+	form, err := createFormAddTaskFromString("Weekly report\nrrule:FREQ=WEEKLY;BYDAY=MO\nSend it to the team\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "Weekly report", form.Title)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO", form.Recurrence)
+	assert.Equal(t, "Send it to the team\n", form.Description)
+	assert.True(t, form.Due.IsZero())
+
+	form, err = createFormAddTaskFromString("Water plants\ntomorrow\nRRULE:FREQ=DAILY;INTERVAL=2;X-FROM=COMPLETION\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=DAILY;INTERVAL=2;X-FROM=COMPLETION", form.Recurrence)
+	assert.False(t, form.Due.IsZero())
+	assert.Empty(t, form.Description)
+
+	form, err = createFormAddTaskFromString("Call mom\ntoday\nremind: 15m, 1h before\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"15m", "1h before"}, form.Reminders)
+	assert.Empty(t, form.Description)
+
+	form, err = createFormAddTaskFromString("Fix #1 #work  for #Team-A\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "Fix #1 for", form.Title)
+	assert.Equal(t, []string{"work", "Team-A"}, form.Tags)
+	// a title of tags only is kept as it is
+	form, err = createFormAddTaskFromString("#work\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "#work", form.Title)
+	assert.Empty(t, form.Tags)
+
+	form, err = createFormAddTaskFromString("Pay bills\nPriority: High\n")
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskPriorityHigh, form.Priority)
+	assert.Empty(t, form.Description)
+	_, err = createFormAddTaskFromString("Pay bills\npriority: urgent\n")
+	assert.True(t, errors.Is(err, ErrInvalidPriority))
+
+	form, err = createFormAddTaskFromString("Release\ntype: Project\nEstimate: 1w\n")
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskTypeProject, form.Type)
+	assert.Equal(t, "1w", form.Estimate)
+	assert.Empty(t, form.Description)
+	_, err = createFormAddTaskFromString("Release\nTYPE: category\n")
+	assert.True(t, errors.Is(err, ErrInvalidType))
+}
+
+func TestNotifierDropsRemindersWhenFull(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	c := newController(ctl)
+	n := &Notifier{CUI: c.CUI}
+	r := &model.Reminder{Task: &model.Task{ID: 1, Title: "Call mom", Due: time.Now()}, At: time.Now()}
+	for i := 0; i < reminderBufferSize; i++ {
+		assert.NoError(t, n.Notify(r))
 	}
-	return ch
+	assert.True(t, errors.Is(n.Notify(r), ErrReminderDropped))
+	assert.Equal(t, r, <-c.reminders)
+	assert.Contains(t, formatReminder(r), "Reminder: Call mom is due")
+
+	err := errors.New("no reminder state")
+	n.Warn(err)
+	assert.Equal(t, err, <-c.warnings)
+}
+
+func TestListTagsByKey(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	c := newController(ctl)
+	mockText := mock_component.NewMockText(ctl)
+	c.stateBar = mockText
+	err := errors.New("no storage")
+	gomock.InOrder(
+		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().ListTags(),
+		c.CasesTask.(*mock_use.MockCasesTask).EXPECT().ListTags().Return(err),
+		mockText.EXPECT().Warn(gomock.Any()).Do(func(e error) { assert.True(t, errors.Is(e, err)) }),
+	)
+	assert.False(t, c.handleEvent(ui.Event{ID: "#"}))
+	assert.False(t, c.handleEvent(ui.Event{ID: "#"}))
 }
@@ -1,6 +1,8 @@
 package cui
 
 import (
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+
 	ui "github.com/gizak/termui/v3"
 	"github.com/tevino/the-clean-architecture-demo/todo/cui/component"
 	"github.com/tevino/the-clean-architecture-demo/todo/cui/io"
@@ -12,13 +14,18 @@ type CUI struct {
 	grid       *component.GridComponent
 	taskList   component.TaskList
 	catList    component.TaskList
+	viewList   component.ViewList
 	stateBar   component.Text
 	descBox    component.Text
 	components []component.Component
+	// reminders and warnings are sent by Notifier and shown in stateBar by Controller.
+	reminders chan *model.Reminder
+	warnings  chan error
 }
 
 // New creates a new CUI.
 func New(lib io.CUILib) *CUI {
+	viewList := component.NewViewListComponent("Views")
 	catList := component.NewListComponent("Categories")
 	taskList := component.NewListComponent("Tasks")
 	stateBar := component.NewTextComponent("State")
@@ -30,11 +37,18 @@ func New(lib io.CUILib) *CUI {
 				component.DefaultActivated: catList,
 				"h":                        catList,
 				"<Left>":                   catList,
+				"j":                        catList,
+				"<Down>":                   catList,
+				"k":                        viewList,
+				"<Up>":                     viewList,
 				"l":                        taskList,
 				"<Right>":                  taskList,
 			},
 			ui.NewRow(9.0/10,
-				ui.NewCol(2.0/10, catList),
+				ui.NewCol(2.0/10,
+					ui.NewRow(3.0/10, viewList),
+					ui.NewRow(7.0/10, catList),
+				),
 				ui.NewCol(8.0/10,
 					ui.NewRow(5.0/10, taskList),
 					ui.NewRow(5.0/10, descBox),
@@ -46,12 +60,17 @@ func New(lib io.CUILib) *CUI {
 		),
 		taskList: taskList,
 		catList:  catList,
+		viewList: viewList,
 		stateBar: stateBar,
 		descBox:  descBox,
+		// viewList and catList go first, which set what taskList lists once updated
 		components: []component.Component{
-			taskList,
+			viewList,
 			catList,
+			taskList,
 		},
+		reminders: make(chan *model.Reminder, reminderBufferSize),
+		warnings:  make(chan error, reminderBufferSize),
 	}
 	return c
 }
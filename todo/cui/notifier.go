@@ -0,0 +1,41 @@
+package cui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+// reminderBufferSize is how many reminders, and warnings, are kept until shown, more are dropped.
+const reminderBufferSize = 16
+
+// ErrReminderDropped is returned by Notifier if reminders are sent faster than they are shown.
+var ErrReminderDropped = errors.New("Reminder dropped")
+
+// Notifier implements use.Notifier by showing reminders in the state bar, it is safe to use from other goroutines.
+type Notifier struct {
+	*CUI
+}
+
+func (n *Notifier) Notify(r *model.Reminder) error {
+	select {
+	case n.reminders <- r:
+		return nil
+	default:
+		return fmt.Errorf("%w: task[%d]", ErrReminderDropped, r.Task.ID)
+	}
+}
+
+// Warn shows err in the state bar, e.g. an error of the use.ReminderScheduler, it is dropped if too many are waiting.
+func (n *Notifier) Warn(err error) {
+	select {
+	case n.warnings <- err:
+	default:
+	}
+}
+
+func formatReminder(r *model.Reminder) string {
+	return fmt.Sprintf("Reminder: %s is due %s", r.Task.Title, humanize.Time(r.Task.Due))
+}
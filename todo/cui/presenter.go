@@ -2,6 +2,7 @@ package cui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/tevino/the-clean-architecture-demo/todo/model"
 )
@@ -21,7 +22,88 @@ func (p *Presenter) ShowTasksOfParentID(parentID int64, tasks []*model.Task) err
 	return err
 }
 
+// ShowPathToParentID shows the path in the title of the list of the parent.
+func (p *Presenter) ShowPathToParentID(parentID int64, path []*model.Task) error {
+	if parentID == p.taskList.ParentID() {
+		p.taskList.SetPath(path)
+	}
+	return nil
+}
+
 func (p *Presenter) ShowTaskAdded(task *model.Task) error {
 	p.stateBar.Info(fmt.Sprintf("Task Added: %s", task.Title))
 	return nil
 }
+
+func (p *Presenter) ShowTaskDeleted(task *model.Task) error {
+	p.stateBar.Info(fmt.Sprintf("Task Deleted: %s, press u to restore", task.Title))
+	return nil
+}
+
+func (p *Presenter) ShowTaskRestored(task *model.Task) error {
+	p.stateBar.Info(fmt.Sprintf("Task Restored: %s", task.Title))
+	return nil
+}
+
+func (p *Presenter) ShowTrashEmptied(removed int) error {
+	p.stateBar.Info(fmt.Sprintf("Trash Emptied: %d tasks removed", removed))
+	return nil
+}
+
+// ShowSearchResults lists the results in the description box, each with the path to it.
+func (p *Presenter) ShowSearchResults(keyword string, results []*model.SearchResult) error {
+	p.stateBar.Info(fmt.Sprintf("Tasks Found: %d for %q", len(results), keyword))
+	lines := make([]string, len(results))
+	for i, r := range results {
+		titles := make([]string, 0, len(r.Path)+1)
+		for _, t := range r.Path {
+			titles = append(titles, t.Title)
+		}
+		lines[i] = strings.Join(append(titles, r.Task.Title), " > ")
+	}
+	p.descBox.Plain(strings.Join(lines, "\n"))
+	return nil
+}
+
+// ShowTasksInView lists the tasks in the task list, which shows the selected view.
+func (p *Presenter) ShowTasksInView(name string, tasks []*model.Task) error {
+	// tasks in a view are of different parents
+	p.taskList.SetPath(nil)
+	p.taskList.UpdateTasks(tasks)
+	return nil
+}
+
+func (p *Presenter) ShowViews(views []*model.View) error {
+	p.viewList.UpdateViews(views)
+	return nil
+}
+
+func (p *Presenter) ShowViewSaved(view *model.View) error {
+	p.stateBar.Info(fmt.Sprintf("View Saved: %s", view.Name))
+	return nil
+}
+
+func (p *Presenter) ShowViewDeleted(view *model.View) error {
+	p.stateBar.Info(fmt.Sprintf("View Deleted: %s, press u to restore", view.Name))
+	return nil
+}
+
+// ShowTags lists tags in the description box, each with the number of tasks labeled.
+func (p *Presenter) ShowTags(tags []*model.Tag) error {
+	p.stateBar.Info(fmt.Sprintf("Tags: %d", len(tags)))
+	lines := make([]string, len(tags))
+	for i, t := range tags {
+		lines[i] = fmt.Sprintf("#%s (%d)", t.Name, t.Count)
+	}
+	p.descBox.Plain(strings.Join(lines, "\n"))
+	return nil
+}
+
+func (p *Presenter) ShowTagsReplaced(from []string, to string, tasks int) error {
+	if to == "" {
+		p.stateBar.Info(fmt.Sprintf("Tags Removed: #%s from %d tasks", strings.Join(from, " #"), tasks))
+	} else {
+		p.stateBar.Info(fmt.Sprintf("Tags Replaced: #%s with #%s on %d tasks", strings.Join(from, " #"), to, tasks))
+	}
+	return nil
+}
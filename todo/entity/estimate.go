@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrInvalidEstimate is wrapped by errors of estimates which could not be parsed.
+var ErrInvalidEstimate = errors.New("Invalid estimate")
+
+// estimatePattern matches estimates, which are a number of minutes, hours, days or weeks.
+var estimatePattern = regexp.MustCompile(`^([0-9]+)\s*([mhdw])$`)
+
+// ParseEstimate parses the expected effort of an item, e.g. "30m", "2h" or "1d".
+func ParseEstimate(s string) (time.Duration, error) {
+	m := estimatePattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if m == nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidEstimate, s)
+	}
+	d, err := unitDuration(m[1], m[2])
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q: %v", ErrInvalidEstimate, s, err)
+	}
+	return d, nil
+}
+
+// FormatEstimate formats an estimate in the largest unit it is a multiple of, e.g. 90m or 2h.
+func FormatEstimate(d time.Duration) string {
+	return formatUnitDuration(d)
+}
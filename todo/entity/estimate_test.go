@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEstimate(t *testing.T) {
+	for s, d := range map[string]time.Duration{
+		"30m":   30 * time.Minute,
+		" 2 H ": 2 * time.Hour,
+		"1d":    24 * time.Hour,
+		"1w":    7 * 24 * time.Hour,
+	} {
+		got, err := ParseEstimate(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, d, got, s)
+	}
+	for _, s := range []string{"", "30", "h", "-1h", "1.5h", "1h30m", "15m before"} {
+		_, err := ParseEstimate(s)
+		assert.True(t, errors.Is(err, ErrInvalidEstimate), s)
+	}
+}
+
+func TestFormatEstimate(t *testing.T) {
+	assert.Equal(t, "90m", FormatEstimate(90*time.Minute))
+	assert.Equal(t, "2h", FormatEstimate(2*time.Hour))
+	assert.Equal(t, "1d", FormatEstimate(24*time.Hour))
+}
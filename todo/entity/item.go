@@ -9,12 +9,29 @@ type Item struct {
 	Description  string
 	Type         ItemType
 	State        ItemState
+	Priority     ItemPriority
 	Due          time.Time
 	CompletedAt  time.Time
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	ParentItemID int64
-	Order        uint64
+	// Rank orders the item among its siblings, see RankBetween.
+	Rank string
+	// Recurrence is the RRULE the item repeats by, see ParseRecurrence, it is empty if the item does not repeat.
+	Recurrence string
+	// Reminders are offsets before Due to remind the user of the item at, see ParseReminder.
+	Reminders []time.Duration
+	// Tags label the item across parents, see ParseTag.
+	Tags []string
+	// Estimate is the expected effort of the item, see ParseEstimate, it is zero if the item is not estimated.
+	Estimate time.Duration
+	// Revision is increased by storage on every change, a change based on a stale revision is rejected.
+	Revision uint64
+	// DeletedAt is when the item was moved to the trash, it is zero if the item is not in the trash.
+	DeletedAt time.Time
+	// DeletedWithItemID is the ID of the item whose deletion moved this item to the trash, which is the item itself
+	// or one of its ancestors.
+	DeletedWithItemID int64
 }
 
 // RootID is the ID of RootItem.
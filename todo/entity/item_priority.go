@@ -0,0 +1,12 @@
+package entity
+
+// ItemPriority indicates how important an item is.
+type ItemPriority int
+
+const (
+	// ItemPriorityNone indicates the item has no priority, it is the default.
+	ItemPriorityNone ItemPriority = iota
+	ItemPriorityLow
+	ItemPriorityMedium
+	ItemPriorityHigh
+)
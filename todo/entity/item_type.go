@@ -10,4 +10,7 @@ const (
 	ItemTypeProject
 	// ItemTypeCategory indicates a category to sort tasks or projects.
 	ItemTypeCategory
+	// ItemTypeView indicates a saved TaskView, the title of the item is the name of the view and the description is
+	// the query of its filter. Views are on the top level and have no children.
+	ItemTypeView
 )
@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// Progress is the progress of a project rolled up from the tasks among its descendants, sub projects included.
+type Progress struct {
+	Completed int
+	Total     int
+	// Remaining is the sum of estimates of the tasks not completed yet.
+	Remaining time.Duration
+}
+
+// Add counts a descendant of the project, only tasks are counted.
+func (p *Progress) Add(it *Item) {
+	if it.Type != ItemTypeTask {
+		return
+	}
+	p.Total++
+	if it.State == ItemStateCompleted {
+		p.Completed++
+	} else {
+		p.Remaining += it.Estimate
+	}
+}
+
+// Done returns whether all tasks of the project are completed, a project without tasks is never done.
+func (p Progress) Done() bool {
+	return p.Total > 0 && p.Completed == p.Total
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	var p Progress
+	assert.False(t, p.Done())
+
+	for _, it := range []*Item{
+		{Type: ItemTypeTask, State: ItemStateCompleted, Estimate: time.Hour},
+		{Type: ItemTypeTask, Estimate: 2 * time.Hour},
+		{Type: ItemTypeTask},
+		// sub projects are not tasks themselves
+		{Type: ItemTypeProject, Estimate: time.Hour},
+	} {
+		p.Add(it)
+	}
+	assert.Equal(t, Progress{Completed: 1, Total: 3, Remaining: 2 * time.Hour}, p)
+	assert.False(t, p.Done())
+
+	p = Progress{}
+	p.Add(&Item{Type: ItemTypeTask, State: ItemStateCompleted})
+	assert.True(t, p.Done())
+}
@@ -0,0 +1,437 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Queries are the text form of compositions, e.g.
+//
+//	due < +7d and not state:done and (title ~ "release" or parent:42)
+//
+// A condition is a field, an operator and a value, values with spaces or operators are quoted as in Go. Conditions
+// are combined by not, and, or in the order of precedence, and grouped by parentheses, an and could be omitted.
+// () is an empty group matching all tasks, so an empty query matches all tasks as well.
+
+// queryFields are fields in queries by target.
+var queryFields = map[ConditionTarget]string{
+	Title:        "title",
+	Description:  "description",
+	CreatedAt:    "created",
+	UpdatedAt:    "updated",
+	ParentTaskID: "parent",
+	State:        "state",
+	Type:         "type",
+	Due:          "due",
+	Tag:          "tag",
+}
+
+// queryFieldAliases are other names of fields accepted in queries.
+var queryFieldAliases = map[string]ConditionTarget{
+	"desc": Description,
+}
+
+// queryOperators are operators in queries by condition type.
+var queryOperators = map[ConditionType]string{
+	Equal:      ":",
+	NotEqual:   "!=",
+	Contains:   "~",
+	NotContain: "!~",
+	Before:     "<",
+	After:      ">",
+}
+
+// queryOperatorAliases are other operators accepted in queries.
+var queryOperatorAliases = map[string]ConditionType{
+	"=": Equal,
+}
+
+// queryStateAliases are other names of states accepted in queries.
+var queryStateAliases = map[string]string{
+	"done": ItemStateNames[ItemStateCompleted],
+	"todo": ItemStateNames[ItemStateNormal],
+}
+
+func (t ConditionTarget) String() string {
+	if name, ok := queryFields[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ConditionTarget(%d)", int(t))
+}
+
+func (t ConditionType) String() string {
+	if op, ok := queryOperators[t]; ok {
+		return op
+	}
+	return fmt.Sprintf("ConditionType(%d)", int(t))
+}
+
+// QueryError is an error in a query.
+type QueryError struct {
+	// Pos is the offset in runes of where the error is found in the query.
+	Pos int
+	Msg string
+	// Err is the cause, e.g. an error wrapping ErrInvalidCondition, it is nil for syntax errors.
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("column %d: %s: %v", e.Pos+1, e.Msg, e.Err)
+	}
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+type queryTokenKind int
+
+const (
+	queryEnd queryTokenKind = iota
+	queryWord
+	queryString
+	queryOperator
+	queryOpen
+	queryClose
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	// text is the unquoted value of a string and the text of the others
+	text string
+	pos  int
+}
+
+// queryPunctuations end words, they are either operators or parentheses.
+const queryPunctuations = `()":=!~<>`
+
+// lexQuery splits a query into tokens, the last one is always a queryEnd.
+func lexQuery(query string) ([]queryToken, error) {
+	rs := []rune(query)
+	var tokens []queryToken
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{queryOpen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{queryClose, ")", i})
+			i++
+		case r == '"':
+			j := i + 1
+			for ; j < len(rs) && rs[j] != '"'; j++ {
+				if rs[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(rs) {
+				return nil, &QueryError{Pos: i, Msg: "unterminated string"}
+			}
+			s, err := strconv.Unquote(string(rs[i : j+1]))
+			if err != nil {
+				return nil, &QueryError{Pos: i, Msg: "invalid string", Err: err}
+			}
+			tokens = append(tokens, queryToken{queryString, s, i})
+			i = j + 1
+		case r == '!':
+			if i+1 >= len(rs) || (rs[i+1] != '=' && rs[i+1] != '~') {
+				return nil, &QueryError{Pos: i, Msg: "expected != or !~"}
+			}
+			tokens = append(tokens, queryToken{queryOperator, string(rs[i : i+2]), i})
+			i += 2
+		case strings.ContainsRune(queryPunctuations, r):
+			tokens = append(tokens, queryToken{queryOperator, string(r), i})
+			i++
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune(queryPunctuations, rs[j]) {
+				j++
+			}
+			tokens = append(tokens, queryToken{queryWord, string(rs[i:j]), i})
+			i = j
+		}
+	}
+	return append(tokens, queryToken{queryEnd, "", len(rs)}), nil
+}
+
+// ParseQuery compiles a query into a composition, errors are *QueryError(s).
+func ParseQuery(query string) (*Composition, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	if p.peek().kind == queryEnd {
+		return &Composition{Type: And}, nil
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != queryEnd {
+		return nil, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	if e.comp != nil {
+		return e.comp, nil
+	}
+	return &Composition{Type: And, Conditions: []*Condition{e.cond}}, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	i      int
+}
+
+// queryExpr is either a condition or a composition.
+type queryExpr struct {
+	cond *Condition
+	comp *Composition
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.i]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.i]
+	if t.kind != queryEnd {
+		p.i++
+	}
+	return t
+}
+
+// isKeyword reports whether t is the keyword, keywords are case insensitive.
+func isKeyword(t queryToken, keyword string) bool {
+	return t.kind == queryWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *queryParser) parseOr() (queryExpr, error) {
+	e, err := p.parseAnd()
+	if err != nil {
+		return e, err
+	}
+	exprs := []queryExpr{e}
+	for isKeyword(p.peek(), "or") {
+		p.next()
+		e, err := p.parseAnd()
+		if err != nil {
+			return e, err
+		}
+		exprs = append(exprs, e)
+	}
+	return composeQuery(Or, exprs), nil
+}
+
+func (p *queryParser) parseAnd() (queryExpr, error) {
+	e, err := p.parseUnary()
+	if err != nil {
+		return e, err
+	}
+	exprs := []queryExpr{e}
+	for {
+		t := p.peek()
+		switch {
+		case isKeyword(t, "and"):
+			p.next()
+		case isKeyword(t, "or"):
+			return composeQuery(And, exprs), nil
+		case t.kind == queryWord || t.kind == queryOpen:
+			// an omitted and
+		default:
+			return composeQuery(And, exprs), nil
+		}
+		e, err := p.parseUnary()
+		if err != nil {
+			return e, err
+		}
+		exprs = append(exprs, e)
+	}
+}
+
+func (p *queryParser) parseUnary() (queryExpr, error) {
+	if !isKeyword(p.peek(), "not") {
+		return p.parsePrimary()
+	}
+	p.next()
+	e, err := p.parseUnary()
+	if err != nil {
+		return e, err
+	}
+	return composeQuery(Not, []queryExpr{e}), nil
+}
+
+func (p *queryParser) parsePrimary() (queryExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case queryOpen:
+		if p.peek().kind == queryClose {
+			p.next()
+			return queryExpr{comp: &Composition{Type: And}}, nil
+		}
+		e, err := p.parseOr()
+		if err != nil {
+			return e, err
+		}
+		if c := p.next(); c.kind != queryClose {
+			return e, &QueryError{Pos: c.pos, Msg: fmt.Sprintf("expected ) to close ( at column %d", t.pos+1)}
+		}
+		return e, nil
+	case queryWord:
+		if isKeyword(t, "and") || isKeyword(t, "or") {
+			return queryExpr{}, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("expected condition before %q", t.text)}
+		}
+		cond, err := p.parseCondition(t)
+		return queryExpr{cond: cond}, err
+	case queryEnd:
+		return queryExpr{}, &QueryError{Pos: t.pos, Msg: "unexpected end of query"}
+	default:
+		return queryExpr{}, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("expected condition, found %q", t.text)}
+	}
+}
+
+func (p *queryParser) parseCondition(field queryToken) (*Condition, error) {
+	cond := &Condition{}
+	target, ok := queryFieldAliases[strings.ToLower(field.text)]
+	for t, name := range queryFields {
+		if name == strings.ToLower(field.text) {
+			target, ok = t, true
+		}
+	}
+	if !ok {
+		return nil, &QueryError{Pos: field.pos, Msg: fmt.Sprintf("unknown field %q", field.text)}
+	}
+	cond.Target = target
+
+	op := p.next()
+	typ, ok := queryOperatorAliases[op.text]
+	for t, o := range queryOperators {
+		if o == op.text {
+			typ, ok = t, true
+		}
+	}
+	if op.kind != queryOperator || !ok {
+		return nil, &QueryError{Pos: op.pos, Msg: fmt.Sprintf("expected operator after %s", field.text)}
+	}
+	cond.Type = typ
+
+	value := p.next()
+	if value.kind != queryWord && value.kind != queryString {
+		return nil, &QueryError{Pos: value.pos, Msg: fmt.Sprintf("expected value after %s", op.text)}
+	}
+	cond.Value = value.text
+	if state, ok := queryStateAliases[strings.ToLower(value.text)]; ok && target == State {
+		cond.Value = state
+	}
+
+	if err := cond.Validate(); err != nil {
+		var unsupported *unsupportedConditionError
+		if errors.As(err, &unsupported) {
+			return nil, &QueryError{Pos: op.pos, Msg: "invalid operator", Err: err}
+		}
+		return nil, &QueryError{Pos: value.pos, Msg: "invalid value", Err: err}
+	}
+	return cond, nil
+}
+
+// composeQuery composes exprs, a single expression is kept as is except by Not, and compositions of the same type
+// are merged.
+func composeQuery(typ ComposeType, exprs []queryExpr) queryExpr {
+	if len(exprs) == 1 && typ != Not {
+		return exprs[0]
+	}
+	c := &Composition{Type: typ}
+	for _, e := range exprs {
+		switch {
+		case e.cond != nil:
+			c.Conditions = append(c.Conditions, e.cond)
+		case e.comp.Type == typ && typ != Not:
+			c.Conditions = append(c.Conditions, e.comp.Conditions...)
+			c.Compositions = append(c.Compositions, e.comp.Compositions...)
+		default:
+			c.Compositions = append(c.Compositions, e.comp)
+		}
+	}
+	return queryExpr{comp: c}
+}
+
+// String returns the composition as a canonical query, conditions go before nested compositions.
+func (c *Composition) String() string {
+	if c.Type == And && len(c.Conditions)+len(c.Compositions) == 0 {
+		return ""
+	}
+	return c.format()
+}
+
+// format returns the composition as a query which could be an operand of any operator.
+func (c *Composition) format() string {
+	n := len(c.Conditions) + len(c.Compositions)
+	switch {
+	case c.Type == Not && n == 0:
+		return "()"
+	case c.Type == Not:
+		inner := &Composition{Type: Or, Conditions: c.Conditions, Compositions: c.Compositions}
+		if n == 1 && len(c.Compositions) == 1 {
+			inner = c.Compositions[0]
+		}
+		return "not " + inner.operand()
+	case c.Type == Or && n == 0:
+		return "not ()"
+	case n == 0:
+		return "()"
+	}
+
+	parts := make([]string, 0, n)
+	for _, cond := range c.Conditions {
+		parts = append(parts, cond.String())
+	}
+	for _, comp := range c.Compositions {
+		if c.Type == And && comp.Type == Or && len(comp.Conditions)+len(comp.Compositions) > 1 {
+			parts = append(parts, "("+comp.format()+")")
+		} else {
+			parts = append(parts, comp.format())
+		}
+	}
+	if c.Type == Or {
+		return strings.Join(parts, " or ")
+	}
+	return strings.Join(parts, " and ")
+}
+
+// operand returns the composition as an operand of not.
+func (c *Composition) operand() string {
+	if c.Type == Not || len(c.Conditions)+len(c.Compositions) <= 1 {
+		return c.format()
+	}
+	return "(" + c.format() + ")"
+}
+
+// String returns the condition in a query.
+func (c *Condition) String() string {
+	if c.Type == Equal {
+		return c.Target.String() + c.Type.String() + quoteQueryValue(c.Value)
+	}
+	return c.Target.String() + " " + c.Type.String() + " " + quoteQueryValue(c.Value)
+}
+
+// quoteQueryValue quotes a value unless it could be a word.
+func quoteQueryValue(s string) string {
+	for _, keyword := range []string{"and", "or", "not"} {
+		if strings.EqualFold(s, keyword) {
+			return strconv.Quote(s)
+		}
+	}
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || !unicode.IsPrint(r) || strings.ContainsRune(queryPunctuations, r)
+	}) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	c, err := ParseQuery(`due < +7d and not state:done and (title ~ "release" or parent:42)`)
+	assert.NoError(t, err)
+	assert.Equal(t, &Composition{
+		Type:       And,
+		Conditions: []*Condition{{Before, Due, "+7d"}},
+		Compositions: []*Composition{
+			{Type: Not, Conditions: []*Condition{{Equal, State, "completed"}}},
+			{Type: Or, Conditions: []*Condition{{Contains, Title, "release"}, {Equal, ParentTaskID, "42"}}},
+		},
+	}, c)
+	assert.Equal(t, `due < +7d and not state:completed and (title ~ release or parent:42)`, c.String())
+}
+
+func TestParseQueryPrecedence(t *testing.T) {
+	for query, canonical := range map[string]string{
+		"":                                       "",
+		"()":                                     "",
+		"title:a":                                "title:a",
+		"TITLE = a":                              "title:a",
+		"desc ~ a desc ~ b":                      "description ~ a and description ~ b",
+		"title:a or title:b and title:c":         "title:a or title:b and title:c",
+		"(title:a or title:b) and title:c":       "title:c and (title:a or title:b)",
+		"not not title:a":                        "not not title:a",
+		"not (title:a and title:b)":              "not (title:a and title:b)",
+		"not (title:a or title:b)":               "not (title:a or title:b)",
+		"title:a and (title:b and (title:c))":    "title:a and title:b and title:c",
+		"not ()":                                 "not ()",
+		`title:"and" or title:"a b" or title:""`: `title:"and" or title:"a b" or title:""`,
+		`title:"say \"hi\""`:                     `title:"say \"hi\""`,
+		"created > 2020-01-02 updated < today-1w": "created > 2020-01-02 and updated < today-1w",
+		`due:"" or due != ""`:                     `due:"" or due != ""`,
+		"tag:#work and tag != work/meetings":      "tag:#work and tag != work/meetings",
+	} {
+		c, err := ParseQuery(query)
+		if !assert.NoError(t, err, query) {
+			continue
+		}
+		assert.Equal(t, canonical, c.String(), query)
+		// canonical queries stay the same
+		again, err := ParseQuery(c.String())
+		assert.NoError(t, err, query)
+		assert.Equal(t, c.String(), again.String(), query)
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for query, pos := range map[string]int{
+		`title ~ "release`:         8,
+		`title !`:                  6,
+		`title`:                    5,
+		`title release`:            6,
+		`title :`:                  7,
+		`title : (`:                8,
+		`size > 1`:                 0,
+		`(title:a or title:b`:      19,
+		`title:a)`:                 7,
+		`and title:a`:              0,
+		`title:a or or title:b`:    11,
+		`state:doing`:              6,
+		`title < a`:                6,
+		`due < "2020-01-02 15:04"`: 6,
+		`not`:                      3,
+	} {
+		_, err := ParseQuery(query)
+		var qe *QueryError
+		if assert.True(t, errors.As(err, &qe), "%s: %v", query, err) {
+			assert.Equal(t, pos, qe.Pos, "%s: %v", query, err)
+		}
+	}
+
+	_, err := ParseQuery("parent:x")
+	assert.True(t, errors.Is(err, ErrInvalidCondition))
+	assert.EqualError(t, err, `column 8: invalid value: Invalid condition: parent ID "x": strconv.ParseInt: parsing "x": invalid syntax`)
+	_, err = ParseQuery("due ~ today")
+	assert.EqualError(t, err, "column 5: invalid operator: Invalid condition: ~ is not supported by due")
+}
+
+func TestCompositionString(t *testing.T) {
+	a, b := &Condition{Equal, Title, "a"}, &Condition{Equal, Title, "b"}
+	for c, s := range map[*Composition]string{
+		{Type: Or}:  "not ()",
+		{Type: Not}: "()",
+		{Type: Not, Conditions: []*Condition{a, b}}:           "not (title:a or title:b)",
+		{Type: And, Compositions: []*Composition{{Type: Or}}}: "not ()",
+		{Type: Or, Compositions: []*Composition{{Type: And, Conditions: []*Condition{a, b}}, {Type: Or, Conditions: []*Condition{b}}}}: "title:a and title:b or title:b",
+	} {
+		assert.Equal(t, s, c.String())
+		parsed, err := ParseQuery(s)
+		assert.NoError(t, err, s)
+		match, err := parsed.match(time.Time{})
+		assert.NoError(t, err)
+		expected, err := c.match(time.Time{})
+		assert.NoError(t, err)
+		for _, it := range []*Item{{Title: "a"}, {Title: "b"}, {Title: "c"}} {
+			assert.Equal(t, expected(it), match(it), "%s %s", s, it.Title)
+		}
+	}
+}
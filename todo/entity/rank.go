@@ -0,0 +1,113 @@
+package entity
+
+import "strings"
+
+// Ranks order items among their siblings, they are strings of rankDigits compared lexicographically, so a rank could
+// always be found between any two ranks without changing others. A rank never ends with the smallest digit, which
+// keeps room before it.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// MaxRankLength is the length beyond which ranks are too dense, siblings should be given new ranks by SpreadRanks
+// rather than taking a longer rank.
+const MaxRankLength = 16
+
+// ValidRank reports whether s is a valid rank.
+func ValidRank(s string) bool {
+	if s == "" || s[len(s)-1] == rankDigits[0] {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(rankDigits, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// RankBetween returns a rank between prev and next, an empty prev means before all ranks and an empty next means
+// after all ranks. It returns false if there is no rank between them, i.e. either of them is invalid or prev is not
+// less than next.
+//
+// Ranks are kept short by stepping one digit from a single bound, so appending or prepending repeatedly grows a
+// rank by one digit every eighteen items or so.
+func RankBetween(prev, next string) (string, bool) {
+	if (prev != "" && !ValidRank(prev)) || (next != "" && !ValidRank(next)) {
+		return "", false
+	}
+	if prev != "" && next != "" && prev >= next {
+		return "", false
+	}
+	return midRank(prev, next), true
+}
+
+// midRank returns a rank between valid ranks prev and next, where prev is taken as padded with the smallest digit.
+func midRank(prev, next string) string {
+	if next != "" {
+		n := 0
+		for n < len(next) && rankDigitAt(prev, n) == next[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(prev) {
+				rest = prev[n:]
+			}
+			return next[:n] + midRank(rest, next[n:])
+		}
+	}
+
+	// the first digits differ from here on
+	lo, hi := 0, len(rankDigits)
+	if prev != "" {
+		lo = strings.IndexByte(rankDigits, prev[0])
+	}
+	if next != "" {
+		hi = strings.IndexByte(rankDigits, next[0])
+	}
+	switch {
+	case prev == "" && next == "":
+		return rankDigits[len(rankDigits)/2 : len(rankDigits)/2+1]
+	case next == "" && lo+1 < hi:
+		return rankDigits[lo+1 : lo+2]
+	case prev == "" && hi > 1:
+		return rankDigits[hi-1 : hi]
+	case hi-lo > 1:
+		return rankDigits[(lo+hi)/2 : (lo+hi)/2+1]
+	case len(next) > 1:
+		return next[:1]
+	}
+	rest := ""
+	if len(prev) > 1 {
+		rest = prev[1:]
+	}
+	return rankDigits[lo:lo+1] + midRank(rest, "")
+}
+
+func rankDigitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return rankDigits[0]
+}
+
+// SpreadRanks returns n ranks in ascending order spread evenly, with room before, between and after them.
+func SpreadRanks(n int) []string {
+	base := uint64(len(rankDigits))
+	width, space := 1, base
+	// leave a digit of room between ranks
+	for space < uint64(n+1)*base {
+		width++
+		space *= base
+	}
+	ranks := make([]string, n)
+	buf := make([]byte, width)
+	for i := range ranks {
+		v := uint64(i+1) * (space / uint64(n+1))
+		for j := width - 1; j >= 0; j-- {
+			buf[j] = rankDigits[v%base]
+			v /= base
+		}
+		ranks[i] = strings.TrimRight(string(buf), rankDigits[:1])
+	}
+	return ranks
+}
@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankBetween(t *testing.T) {
+	for _, c := range []struct{ prev, next string }{
+		{"", ""},
+		{"", "1"},
+		{"", "01"},
+		{"", "i"},
+		{"1", ""},
+		{"z", ""},
+		{"zz", ""},
+		{"a", "b"},
+		{"a", "a1"},
+		{"a", "a01"},
+		{"a1", "b"},
+		{"a1", "a2"},
+		{"ay", "az"},
+		{"1", "z"},
+	} {
+		r, ok := RankBetween(c.prev, c.next)
+		assert.True(t, ok, "%q %q", c.prev, c.next)
+		assert.True(t, ValidRank(r), "%q", r)
+		assert.True(t, c.prev < r, "%q < %q", c.prev, r)
+		if c.next != "" {
+			assert.True(t, r < c.next, "%q < %q", r, c.next)
+		}
+	}
+}
+
+func TestRankBetweenRejectsInvalidRanks(t *testing.T) {
+	for _, c := range []struct{ prev, next string }{
+		{"b", "a"},
+		{"a", "a"},
+		{"a0", ""},
+		{"", "A"},
+	} {
+		_, ok := RankBetween(c.prev, c.next)
+		assert.False(t, ok, "%q %q", c.prev, c.next)
+	}
+}
+
+func TestRankBetweenGrowsSlowly(t *testing.T) {
+	var appended, prepended string
+	for i := 0; i < 100; i++ {
+		appended, _ = RankBetween(appended, "")
+		prepended, _ = RankBetween("", prepended)
+	}
+	assert.True(t, len(appended) <= 7, appended)
+	assert.True(t, len(prepended) <= 7, prepended)
+}
+
+func TestRankBetweenRandomInserts(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ranks := []string{}
+	for i := 0; i < 1000; i++ {
+		pos := r.Intn(len(ranks) + 1)
+		var prev, next string
+		if pos > 0 {
+			prev = ranks[pos-1]
+		}
+		if pos < len(ranks) {
+			next = ranks[pos]
+		}
+		rank, ok := RankBetween(prev, next)
+		assert.True(t, ok)
+		ranks = append(ranks[:pos], append([]string{rank}, ranks[pos:]...)...)
+	}
+	assert.True(t, sort.StringsAreSorted(ranks))
+}
+
+func TestSpreadRanks(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 35, 36, 1000} {
+		ranks := SpreadRanks(n)
+		assert.Len(t, ranks, n)
+		assert.True(t, sort.StringsAreSorted(ranks))
+		for i, r := range ranks {
+			assert.True(t, ValidRank(r), "%q", r)
+			if i > 0 {
+				_, ok := RankBetween(ranks[i-1], r)
+				assert.True(t, ok)
+			}
+		}
+	}
+	assert.Equal(t, []string{"c", "o"}, SpreadRanks(2))
+}
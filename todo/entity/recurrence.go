@@ -0,0 +1,389 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecurrence is wrapped by errors of recurrence rules which could not be parsed.
+var ErrInvalidRecurrence = errors.New("Invalid recurrence")
+
+// Frequency is the period a Recurrence repeats in.
+type Frequency int
+
+// All Frequency(s).
+const (
+	Daily Frequency = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencyNames = map[Frequency]string{
+	Daily:   "DAILY",
+	Weekly:  "WEEKLY",
+	Monthly: "MONTHLY",
+	Yearly:  "YEARLY",
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+	time.Sunday:    "SU",
+}
+
+// WeekdayNum is a weekday in BYDAY. N is the Nth of the weekday within the month of a monthly recurrence, or the
+// year of a yearly one, counted from the end if negative. A zero N is every such weekday.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Recurrence is how an item repeats, it is written as an RRULE of RFC 5545 with the parts FREQ, INTERVAL, BYDAY,
+// BYMONTHDAY, COUNT and UNTIL, see ParseRecurrence.
+//
+// An occurrence is followed by the first time after it in the periods of FREQ, starting with the period of the
+// occurrence and every INTERVAL periods, which matches BYDAY and BYMONTHDAY, or falls on the same weekday, day of
+// month or day of year as the occurrence if neither is given. The time of day is kept.
+type Recurrence struct {
+	Freq Frequency
+	// Interval is the number of periods between occurrences, zero means 1.
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	// Count is the number of occurrences left including the current one, zero means no limit.
+	Count int
+	// Until is the last time an occurrence could be at, zero means no limit. A date, which is midnight in local time,
+	// includes the whole day.
+	Until time.Time
+	// FromCompletion repeats from the time an occurrence is completed rather than its due, it is written as the
+	// extension part X-FROM=COMPLETION.
+	FromCompletion bool
+}
+
+// Layouts of UNTIL, a date is in local time and so is a time without the Z suffix.
+const (
+	recurrenceDateLayout     = "20060102"
+	recurrenceLocalLayout    = "20060102T150405"
+	recurrenceUTCLayout      = "20060102T150405Z"
+	recurrenceFromCompletion = "COMPLETION"
+	recurrenceFromDue        = "DUE"
+)
+
+// maxRecurrencePeriods is the number of periods searched for the next occurrence before giving up, e.g. for the
+// 31st of every other month which could never come.
+const maxRecurrencePeriods = 1000
+
+// ParseRecurrence parses an RRULE, e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", the "RRULE:" prefix is optional.
+// Parts and values are case-insensitive, other parts of RFC 5545, e.g. BYMONTH, are not supported.
+func ParseRecurrence(s string) (*Recurrence, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "RRULE:")
+	r := &Recurrence{}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("%w: invalid part %q", ErrInvalidRecurrence, part)
+		}
+		key, value := kv[0], kv[1]
+		if seen[key] {
+			return nil, fmt.Errorf("%w: duplicate %s", ErrInvalidRecurrence, key)
+		}
+		seen[key] = true
+		if err := r.parsePart(key, value); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRecurrence, key, err)
+		}
+	}
+	if err := r.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	return r, nil
+}
+
+func (r *Recurrence) parsePart(key, value string) error {
+	var err error
+	switch key {
+	case "FREQ":
+		for f, name := range frequencyNames {
+			if name == value {
+				r.Freq = f
+			}
+		}
+		if r.Freq == 0 {
+			return fmt.Errorf("unsupported frequency %s", value)
+		}
+	case "INTERVAL":
+		if r.Interval, err = strconv.Atoi(value); err == nil && r.Interval < 1 {
+			err = fmt.Errorf("not positive: %d", r.Interval)
+		}
+	case "COUNT":
+		if r.Count, err = strconv.Atoi(value); err == nil && r.Count < 1 {
+			err = fmt.Errorf("not positive: %d", r.Count)
+		}
+	case "UNTIL":
+		r.Until, err = parseRecurrenceUntil(value)
+	case "BYDAY":
+		for _, v := range strings.Split(value, ",") {
+			day, err := parseWeekdayNum(v)
+			if err != nil {
+				return err
+			}
+			r.ByDay = append(r.ByDay, day)
+		}
+	case "BYMONTHDAY":
+		for _, v := range strings.Split(value, ",") {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			if n == 0 || n < -31 || n > 31 {
+				return fmt.Errorf("out of range: %d", n)
+			}
+			r.ByMonthDay = append(r.ByMonthDay, n)
+		}
+	case "X-FROM":
+		switch value {
+		case recurrenceFromCompletion:
+			r.FromCompletion = true
+		case recurrenceFromDue:
+		default:
+			return fmt.Errorf("unknown value %s", value)
+		}
+	default:
+		return errors.New("unsupported part")
+	}
+	return err
+}
+
+func parseRecurrenceUntil(s string) (time.Time, error) {
+	if t, err := time.Parse(recurrenceUTCLayout, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(recurrenceLocalLayout, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(recurrenceDateLayout, s, time.Local)
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+	}
+	var day WeekdayNum
+	name := s[len(s)-2:]
+	found := false
+	for wd, n := range weekdayNames {
+		if n == name {
+			day.Weekday, found = wd, true
+		}
+	}
+	if !found {
+		return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+	}
+	if n := s[:len(s)-2]; n != "" {
+		var err error
+		if day.N, err = strconv.Atoi(n); err != nil || day.N == 0 || day.N < -53 || day.N > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+		}
+	}
+	return day, nil
+}
+
+func (r *Recurrence) validate() error {
+	if r.Freq == 0 {
+		return errors.New("FREQ is required")
+	}
+	if r.Count != 0 && !r.Until.IsZero() {
+		return errors.New("COUNT and UNTIL could not be both given")
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return fmt.Errorf("BYDAY with a number is only supported by MONTHLY and YEARLY")
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return errors.New("BYMONTHDAY is not supported by WEEKLY")
+	}
+	return nil
+}
+
+// String returns the RRULE without the "RRULE:" prefix, parts are in a fixed order.
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + frequencyNames[r.Freq]}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = weekdayNames[day.Weekday]
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+formatRecurrenceUntil(r.Until))
+	}
+	if r.FromCompletion {
+		parts = append(parts, "X-FROM="+recurrenceFromCompletion)
+	}
+	return strings.Join(parts, ";")
+}
+
+func formatRecurrenceUntil(t time.Time) string {
+	switch {
+	case t.Location() == time.UTC:
+		return t.Format(recurrenceUTCLayout)
+	case isRecurrenceDate(t):
+		return t.Local().Format(recurrenceDateLayout)
+	}
+	return t.Local().Format(recurrenceLocalLayout)
+}
+
+// isRecurrenceDate returns true if an UNTIL is a date rather than a date-time.
+func isRecurrenceDate(t time.Time) bool {
+	if t.Location() == time.UTC {
+		return false
+	}
+	t = t.Local()
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// endsBefore returns true if t is after Until, an UNTIL of a date ends at the end of the day as it is inclusive.
+func (r *Recurrence) endsBefore(t time.Time) bool {
+	switch {
+	case r.Until.IsZero():
+		return false
+	case isRecurrenceDate(r.Until):
+		return !t.Before(r.Until.AddDate(0, 0, 1))
+	}
+	return t.After(r.Until)
+}
+
+// Next returns the occurrence following the one at given time, along with the recurrence of the occurrences after
+// it, i.e. with Count decreased. ok is false if the recurrence ends at given time.
+func (r *Recurrence) Next(at time.Time) (next time.Time, rest *Recurrence, ok bool) {
+	if r.Count == 1 {
+		return time.Time{}, nil, false
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	start := r.periodStart(at)
+	for i := 0; i < maxRecurrencePeriods; i++ {
+		period := r.addPeriods(start, i*interval)
+		end := r.addPeriods(period, 1)
+		for day := period; day.Before(end); day = day.AddDate(0, 0, 1) {
+			if !day.After(at) || !r.matches(day, at) {
+				continue
+			}
+			if r.endsBefore(day) {
+				return time.Time{}, nil, false
+			}
+			rest := *r
+			if rest.Count > 0 {
+				rest.Count--
+			}
+			return day, &rest, true
+		}
+	}
+	return time.Time{}, nil, false
+}
+
+// periodStart returns the start of the period of t at the time of day of t, weeks start on Monday.
+func (r *Recurrence) periodStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch r.Freq {
+	case Weekly:
+		d -= (int(t.Weekday()) + 6) % 7
+	case Monthly:
+		d = 1
+	case Yearly:
+		m, d = time.January, 1
+	}
+	return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+func (r *Recurrence) addPeriods(t time.Time, n int) time.Time {
+	switch r.Freq {
+	case Weekly:
+		return t.AddDate(0, 0, 7*n)
+	case Monthly:
+		return t.AddDate(0, n, 0)
+	case Yearly:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// matches reports whether day is an occurrence of the recurrence of an occurrence at given time.
+func (r *Recurrence) matches(day, at time.Time) bool {
+	if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+		switch r.Freq {
+		case Weekly:
+			return day.Weekday() == at.Weekday()
+		case Monthly:
+			return day.Day() == at.Day()
+		case Yearly:
+			return day.Month() == at.Month() && day.Day() == at.Day()
+		default:
+			return true
+		}
+	}
+	if len(r.ByMonthDay) > 0 && !r.matchesMonthDay(day) {
+		return false
+	}
+	return len(r.ByDay) == 0 || r.matchesWeekday(day)
+}
+
+func (r *Recurrence) matchesMonthDay(day time.Time) bool {
+	days := daysIn(day.Year(), day.Month())
+	for _, n := range r.ByMonthDay {
+		if n == day.Day() || n < 0 && days+n+1 == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recurrence) matchesWeekday(day time.Time) bool {
+	// the position of day among the same weekdays of the month or year, from the start and from the end
+	index, total := day.Day()-1, daysIn(day.Year(), day.Month())
+	if r.Freq == Yearly {
+		index, total = day.YearDay()-1, time.Date(day.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	}
+	n, fromEnd := index/7+1, -((total-1-index)/7 + 1)
+	for _, wd := range r.ByDay {
+		if wd.Weekday == day.Weekday() && (wd.N == 0 || wd.N == n || wd.N == fromEnd) {
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn returns the number of days in the month.
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
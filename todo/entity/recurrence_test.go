@@ -0,0 +1,119 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := ParseRecurrence("RRULE:freq=monthly;interval=2;byday=MO,-1FR;bymonthday=1,-1;count=3;x-from=completion")
+	assert.NoError(t, err)
+	assert.Equal(t, &Recurrence{
+		Freq:           Monthly,
+		Interval:       2,
+		ByDay:          []WeekdayNum{{0, time.Monday}, {-1, time.Friday}},
+		ByMonthDay:     []int{1, -1},
+		Count:          3,
+		FromCompletion: true,
+	}, r)
+	assert.Equal(t, "FREQ=MONTHLY;INTERVAL=2;BYDAY=MO,-1FR;BYMONTHDAY=1,-1;COUNT=3;X-FROM=COMPLETION", r.String())
+
+	for _, s := range []string{
+		"FREQ=DAILY",
+		"FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		"FREQ=YEARLY;BYDAY=20MO",
+		"FREQ=DAILY;UNTIL=20200102T150405Z",
+		"FREQ=DAILY;UNTIL=20200102",
+		"FREQ=DAILY;UNTIL=20200102T150405",
+	} {
+		r, err := ParseRecurrence(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, s, r.String())
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20200102",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYDAY=0MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTH=1",
+		"FREQ=MONTHLY;X-FROM=NOW",
+		"FREQ=MONTHLY;COUNT",
+	} {
+		_, err := ParseRecurrence(s)
+		assert.True(t, errors.Is(err, ErrInvalidRecurrence), "%s: %v", s, err)
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	// Thursday
+	at := time.Date(2020, 1, 2, 15, 4, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time { return time.Date(2020, month, d, 15, 4, 0, 0, time.UTC) }
+	for rule, expected := range map[string][]time.Time{
+		"FREQ=DAILY":                            {day(1, 3), day(1, 4), day(1, 5)},
+		"FREQ=DAILY;INTERVAL=3":                 {day(1, 5), day(1, 8), day(1, 11)},
+		"FREQ=DAILY;BYDAY=MO,FR":                {day(1, 3), day(1, 6), day(1, 10)},
+		"FREQ=WEEKLY":                           {day(1, 9), day(1, 16), day(1, 23)},
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH":    {day(1, 13), day(1, 16), day(1, 27)},
+		"FREQ=MONTHLY":                          {day(2, 2), day(3, 2), day(4, 2)},
+		"FREQ=MONTHLY;BYMONTHDAY=-1":            {day(1, 31), day(2, 29), day(3, 31)},
+		"FREQ=MONTHLY;BYDAY=-1FR":               {day(1, 31), day(2, 28), day(3, 27)},
+		"FREQ=MONTHLY;BYDAY=1MO,3MO":            {day(1, 6), day(1, 20), day(2, 3)},
+		"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13":   {day(3, 13), day(11, 13), day(8, 13).AddDate(1, 0, 0)},
+		"FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=31": {day(1, 31), day(3, 31), day(5, 31)},
+		"FREQ=YEARLY":                           {at.AddDate(1, 0, 0), at.AddDate(2, 0, 0), at.AddDate(3, 0, 0)},
+		"FREQ=YEARLY;BYDAY=-1SU":                {day(12, 27), day(12, 26).AddDate(1, 0, 0), day(12, 25).AddDate(2, 0, 0)},
+		"FREQ=DAILY;COUNT=3":                    {day(1, 3), day(1, 4)},
+		"FREQ=DAILY;UNTIL=20200104T150400Z":     {day(1, 3), day(1, 4)},
+	} {
+		r, err := ParseRecurrence(rule)
+		if !assert.NoError(t, err, rule) {
+			continue
+		}
+		// up to 3 occurrences, fewer if the recurrence ends
+		var got []time.Time
+		prev := at
+		for len(got) < 3 {
+			next, rest, ok := r.Next(prev)
+			if !ok {
+				break
+			}
+			got = append(got, next)
+			prev, r = next, rest
+		}
+		assert.Equal(t, expected, got, rule)
+	}
+
+	// a count of 1 is the last occurrence
+	r := &Recurrence{Freq: Daily, Count: 1}
+	_, _, ok := r.Next(at)
+	assert.False(t, ok)
+	// an UNTIL of a date includes the day
+	r, err := ParseRecurrence("FREQ=WEEKLY;UNTIL=20250214")
+	assert.NoError(t, err)
+	friday := time.Date(2025, 1, 31, 9, 0, 0, 0, time.Local)
+	next, r, ok := r.Next(friday)
+	assert.True(t, ok)
+	assert.Equal(t, friday.AddDate(0, 0, 7), next)
+	next, r, ok = r.Next(next)
+	assert.True(t, ok)
+	assert.Equal(t, friday.AddDate(0, 0, 14), next)
+	_, _, ok = r.Next(next)
+	assert.False(t, ok)
+	// an occurrence which could never come
+	r = &Recurrence{Freq: Yearly, Interval: 4, ByMonthDay: []int{30}, ByDay: []WeekdayNum{{1, time.Monday}}}
+	_, _, ok = r.Next(at)
+	assert.False(t, ok)
+}
@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidReminder is wrapped by errors of reminders which could not be parsed.
+var ErrInvalidReminder = errors.New("Invalid reminder")
+
+// reminderPattern matches reminders, which are a number of minutes, hours, days or weeks before the due.
+var reminderPattern = regexp.MustCompile(`^([0-9]+)\s*([mhdw])(?:\s+before)?$`)
+
+// MaxReminder is the longest offset of a reminder, an item is reminded of at most MaxReminder before its due, so that
+// reminders of a period could be found among items due around it.
+const MaxReminder = 4 * 7 * 24 * time.Hour
+
+// durationUnits are units of reminders and estimates from the largest.
+var durationUnits = []struct {
+	name string
+	unit time.Duration
+}{
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+}
+
+// ParseReminder parses the offset of a reminder before the due, e.g. "15m", "2h", "1d before" or "1w".
+func ParseReminder(s string) (time.Duration, error) {
+	m := reminderPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if m == nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidReminder, s)
+	}
+	d, err := unitDuration(m[1], m[2])
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q: %v", ErrInvalidReminder, s, err)
+	}
+	return d, nil
+}
+
+// unitDuration returns n of the unit named in durationUnits.
+func unitDuration(n, unit string) (time.Duration, error) {
+	count, err := strconv.Atoi(n)
+	if err != nil {
+		return 0, err
+	}
+	for _, u := range durationUnits {
+		if u.name == unit {
+			return time.Duration(count) * u.unit, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown unit: %s", unit)
+}
+
+// FormatReminder formats the offset of a reminder in the largest unit it is a multiple of, offsets shorter than a
+// minute are rounded down.
+func FormatReminder(d time.Duration) string {
+	return formatUnitDuration(d)
+}
+
+// formatUnitDuration formats d in the largest unit of durationUnits it is a multiple of, d is rounded down to
+// minutes.
+func formatUnitDuration(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	for _, u := range durationUnits {
+		if d%u.unit == 0 && d >= u.unit {
+			return strconv.FormatInt(int64(d/u.unit), 10) + u.name
+		}
+	}
+	return "0m"
+}
+
+// RemindAt returns when the item should be reminded of, which is each of its Reminders before its due, in the
+// order of Reminders, offsets longer than MaxReminder are taken as MaxReminder. Items without a due, or completed, are
+// not reminded of.
+func (it *Item) RemindAt() []time.Time {
+	if it.Due.IsZero() || it.State == ItemStateCompleted {
+		return nil
+	}
+	times := make([]time.Time, len(it.Reminders))
+	for i, d := range it.Reminders {
+		if d > MaxReminder {
+			d = MaxReminder
+		}
+		times[i] = it.Due.Add(-d)
+	}
+	return times
+}
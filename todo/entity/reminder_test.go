@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReminder(t *testing.T) {
+	for s, d := range map[string]time.Duration{
+		"0m":         0,
+		"15m":        15 * time.Minute,
+		"15m before": 15 * time.Minute,
+		" 2 H ":      2 * time.Hour,
+		"1d":         24 * time.Hour,
+		"2w before":  14 * 24 * time.Hour,
+	} {
+		got, err := ParseReminder(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, d, got, s)
+	}
+	for _, s := range []string{"", "15", "m", "-15m", "1.5h", "15s", "15m after"} {
+		_, err := ParseReminder(s)
+		assert.True(t, errors.Is(err, ErrInvalidReminder), s)
+	}
+}
+
+func TestFormatReminder(t *testing.T) {
+	for d, s := range map[time.Duration]string{
+		0:                            "0m",
+		30 * time.Second:             "0m",
+		15 * time.Minute:             "15m",
+		90 * time.Minute:             "90m",
+		2 * time.Hour:                "2h",
+		36 * time.Hour:               "36h",
+		24 * time.Hour:               "1d",
+		14 * 24 * time.Hour:          "2w",
+		15*time.Minute + time.Second: "15m",
+		10 * 24 * time.Hour:          "10d",
+		7*24*time.Hour + time.Minute: "10081m",
+	} {
+		assert.Equal(t, s, FormatReminder(d), d.String())
+		parsed, err := ParseReminder(s)
+		assert.NoError(t, err)
+		assert.Equal(t, d.Truncate(time.Minute), parsed)
+	}
+}
+
+func TestItemRemindAt(t *testing.T) {
+	due := time.Date(2020, 1, 2, 15, 4, 0, 0, time.UTC)
+	it := &Item{Due: due, Reminders: []time.Duration{time.Hour, 0, 8 * 7 * 24 * time.Hour}}
+	assert.Equal(t, []time.Time{due.Add(-time.Hour), due, due.Add(-MaxReminder)}, it.RemindAt())
+
+	it.State = ItemStateCompleted
+	assert.Empty(t, it.RemindAt())
+	assert.Empty(t, (&Item{Reminders: []time.Duration{time.Hour}}).RemindAt())
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"sort"
+	"strings"
+)
+
+// SortKey is a key to sort items by.
+type SortKey int
+
+const (
+	// SortByPriority sorts items of higher priorities first.
+	SortByPriority SortKey = iota
+	// SortByDue sorts items due earlier first, items without a due go last.
+	SortByDue
+	// SortByCreated sorts items created earlier first.
+	SortByCreated
+	// SortByTitle sorts items by their titles regardless of case.
+	SortByTitle
+)
+
+// SortItems sorts items by each key in turn, items equal by all keys stay in the order they are given, which is
+// usually the manual order.
+func SortItems(items []*Item, keys []SortKey) {
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, k := range keys {
+			if c := compareItems(items[i], items[j], k); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// compareItems returns a negative number if a goes before b by key, a positive number if after and 0 if equal.
+func compareItems(a, b *Item, key SortKey) int {
+	switch key {
+	case SortByPriority:
+		return int(b.Priority) - int(a.Priority)
+	case SortByDue:
+		switch {
+		case a.Due.Equal(b.Due):
+			return 0
+		case a.Due.IsZero():
+			return 1
+		case b.Due.IsZero():
+			return -1
+		case a.Due.Before(b.Due):
+			return -1
+		default:
+			return 1
+		}
+	case SortByCreated:
+		switch {
+		case a.CreatedAt.Before(b.CreatedAt):
+			return -1
+		case a.CreatedAt.After(b.CreatedAt):
+			return 1
+		default:
+			return 0
+		}
+	case SortByTitle:
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortItems(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	report := &Item{ID: 1, Title: "report", Priority: ItemPriorityHigh, Due: day(5), CreatedAt: day(2)}
+	call := &Item{ID: 2, Title: "Call mom", Due: day(3), CreatedAt: day(1)}
+	bills := &Item{ID: 3, Title: "Pay bills", Priority: ItemPriorityHigh, Due: day(4), CreatedAt: day(3)}
+	plants := &Item{ID: 4, Title: "water plants", Priority: ItemPriorityLow, CreatedAt: day(4)}
+	manual := []*Item{report, call, bills, plants}
+
+	for _, c := range []struct {
+		keys   []SortKey
+		expect []*Item
+	}{
+		{nil, manual},
+		// items of the same priority stay in the manual order
+		{[]SortKey{SortByPriority}, []*Item{report, bills, plants, call}},
+		{[]SortKey{SortByPriority, SortByDue}, []*Item{bills, report, plants, call}},
+		// items without a due go last
+		{[]SortKey{SortByDue}, []*Item{call, bills, report, plants}},
+		{[]SortKey{SortByCreated}, []*Item{call, report, bills, plants}},
+		{[]SortKey{SortByTitle}, []*Item{call, bills, report, plants}},
+	} {
+		items := append([]*Item(nil), manual...)
+		SortItems(items, c.keys)
+		assert.Equal(t, c.expect, items, "keys: %v", c.keys)
+	}
+}
@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidTag is wrapped by errors of tags which could not be parsed.
+var ErrInvalidTag = errors.New("Invalid tag")
+
+// tagPattern matches tags, which start with a letter followed by letters, digits, "_", "-" or "/".
+var tagPattern = regexp.MustCompile(`^\p{L}[\p{L}\p{N}_/-]*$`)
+
+// ParseTag parses a tag with an optional leading "#", e.g. "#work" is "work". Tags are case sensitive.
+func ParseTag(s string) (string, error) {
+	tag := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTag, s)
+	}
+	return tag, nil
+}
+
+// HasTag reports whether the item is tagged with tag.
+func (it *Item) HasTag(tag string) bool {
+	return containsTag(it.Tags, tag)
+}
+
+// ReplaceTags replaces tags of the item in from with to, which is only kept once, tags are removed if to is empty.
+// It returns false if the item has none of from.
+func (it *Item) ReplaceTags(from []string, to string) bool {
+	replaced := false
+	var tags []string
+	for _, t := range it.Tags {
+		for _, f := range from {
+			if t == f {
+				t, replaced = to, true
+				break
+			}
+		}
+		if t != "" && !containsTag(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	if replaced {
+		it.Tags = tags
+	}
+	return replaced
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceTags replaces tags of conditions on Tag in from with to, nested compositions included, returns true if any
+// is replaced.
+func (c *Composition) ReplaceTags(from []string, to string) bool {
+	replaced := false
+	for _, cond := range c.Conditions {
+		if cond.Target != Tag {
+			continue
+		}
+		if tag, err := ParseTag(cond.Value); err == nil && containsTag(from, tag) && tag != to {
+			cond.Value, replaced = to, true
+		}
+	}
+	for _, comp := range c.Compositions {
+		replaced = comp.ReplaceTags(from, to) || replaced
+	}
+	return replaced
+}
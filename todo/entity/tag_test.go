@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTag(t *testing.T) {
+	for s, expected := range map[string]string{
+		"work":          "work",
+		"#work":         "work",
+		" #Work ":       "Work",
+		"work/meetings": "work/meetings",
+		"to-do_2":       "to-do_2",
+		"日本":            "日本",
+	} {
+		tag, err := ParseTag(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, tag, s)
+		}
+	}
+	for _, s := range []string{"", "#", "##work", "1st", "-work", "work home", "work:home", "work,home"} {
+		_, err := ParseTag(s)
+		assert.True(t, errors.Is(err, ErrInvalidTag), s)
+	}
+}
+
+func TestItemReplaceTags(t *testing.T) {
+	it := &Item{Tags: []string{"work", "home", "errand"}}
+	assert.True(t, it.HasTag("home"))
+	assert.False(t, it.ReplaceTags([]string{"garden"}, "yard"))
+	assert.Equal(t, []string{"work", "home", "errand"}, it.Tags)
+
+	// merged into an existing tag, which is kept once
+	assert.True(t, it.ReplaceTags([]string{"home", "errand"}, "work"))
+	assert.Equal(t, []string{"work"}, it.Tags)
+	assert.True(t, it.ReplaceTags([]string{"work"}, "office"))
+	assert.Equal(t, []string{"office"}, it.Tags)
+	// removed
+	assert.True(t, it.ReplaceTags([]string{"office"}, ""))
+	assert.Nil(t, it.Tags)
+	assert.False(t, it.HasTag("office"))
+}
+
+func TestCompositionReplaceTags(t *testing.T) {
+	c, err := ParseQuery(`tag:#home and (tag:errand or title ~ home) and not tag:work`)
+	assert.NoError(t, err)
+	assert.False(t, c.ReplaceTags([]string{"garden"}, "yard"))
+	assert.True(t, c.ReplaceTags([]string{"home", "errand"}, "house"))
+	assert.Equal(t, `tag:house and (tag:house or title ~ home) and not tag:work`, c.String())
+}
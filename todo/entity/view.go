@@ -1,8 +1,31 @@
 package entity
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCondition is wrapped by errors of conditions which could not be evaluated.
+var ErrInvalidCondition = errors.New("Invalid condition")
+
 // TaskView represents a set of conditions to filter tasks.
 type TaskView struct {
-	// TODO:
+	Name string
+	// Filter is what tasks in the view match, a nil Filter matches all tasks.
+	Filter *Composition
+}
+
+// Match compiles the view into a function reporting whether an item is in the view, relative times are relative to
+// now.
+func (v *TaskView) Match(now time.Time) (func(*Item) bool, error) {
+	if v.Filter == nil {
+		return func(*Item) bool { return true }, nil
+	}
+	return v.Filter.match(now)
 }
 
 type ConditionTarget int
@@ -13,23 +36,37 @@ const (
 	CreatedAt
 	UpdatedAt
 	ParentTaskID
+	// State is matched by the name of an ItemState, see ItemStateNames.
+	State
+	// Type is matched by the name of an ItemType, see ItemTypeNames.
+	Type
+	// Due is matched by a time, an empty value matches items without a due.
+	Due
+	// Tag is matched by a tag, Equal matches items with the tag, see ParseTag.
+	Tag
 )
 
+// Condition matches the target of an item against Value. Times are either absolute in RFC 3339 or as dates, or
+// relative to now, see ParseTimeValue.
 type Condition struct {
 	Type   ConditionType
 	Target ConditionTarget
 	Value  string
 }
 
+// Composition matches items by its conditions and nested compositions, an empty And matches all items and an empty
+// Or matches none, while Not matches items matching none of them.
 type Composition struct {
-	Type       ComposeType
-	Conditions []*Condition
+	Type         ComposeType
+	Conditions   []*Condition
+	Compositions []*Composition
 }
 type ComposeType int
 
 const (
 	And ComposeType = iota
 	Or
+	Not
 )
 
 type ConditionType int
@@ -37,6 +74,247 @@ type ConditionType int
 const (
 	Equal ConditionType = iota
 	NotEqual
+	// Contains matches text targets containing the value regardless of case.
 	Contains
 	NotContain
+	// Before matches time targets before the value, a zero time, e.g. of an item without a due, is never before.
+	Before
+	// After matches time targets after the value, a zero time is never after.
+	After
 )
+
+// ItemStateNames are names of ItemState(s) in conditions.
+var ItemStateNames = map[ItemState]string{
+	ItemStateNormal:    "normal",
+	ItemStateCompleted: "completed",
+}
+
+// ItemTypeNames are names of ItemType(s) in conditions.
+var ItemTypeNames = map[ItemType]string{
+	ItemTypeTask:     "task",
+	ItemTypeProject:  "project",
+	ItemTypeCategory: "category",
+	ItemTypeView:     "view",
+}
+
+// Validate reports whether the condition could be evaluated.
+func (c *Condition) Validate() error {
+	_, err := c.match(time.Time{})
+	return err
+}
+
+func (c *Composition) match(now time.Time) (func(*Item) bool, error) {
+	var matches []func(*Item) bool
+	for _, cond := range c.Conditions {
+		m, err := cond.match(now)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	for _, comp := range c.Compositions {
+		m, err := comp.match(now)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	switch c.Type {
+	case And:
+		return func(it *Item) bool {
+			for _, m := range matches {
+				if !m(it) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case Or:
+		return func(it *Item) bool {
+			for _, m := range matches {
+				if m(it) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case Not:
+		return func(it *Item) bool {
+			for _, m := range matches {
+				if m(it) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown compose type %d", ErrInvalidCondition, c.Type)
+	}
+}
+
+func (c *Condition) match(now time.Time) (func(*Item) bool, error) {
+	switch c.Target {
+	case Title:
+		return c.matchText(func(it *Item) string { return it.Title })
+	case Description:
+		return c.matchText(func(it *Item) string { return it.Description })
+	case CreatedAt:
+		return c.matchTime(now, func(it *Item) time.Time { return it.CreatedAt })
+	case UpdatedAt:
+		return c.matchTime(now, func(it *Item) time.Time { return it.UpdatedAt })
+	case Due:
+		return c.matchTime(now, func(it *Item) time.Time { return it.Due })
+	case ParentTaskID:
+		id, err := strconv.ParseInt(c.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parent ID %q: %v", ErrInvalidCondition, c.Value, err)
+		}
+		return c.matchEqual(func(it *Item) bool { return it.ParentItemID == id })
+	case State:
+		for state, name := range ItemStateNames {
+			if name == c.Value {
+				state := state
+				return c.matchEqual(func(it *Item) bool { return it.State == state })
+			}
+		}
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalidCondition, c.Value)
+	case Type:
+		for typ, name := range ItemTypeNames {
+			if name == c.Value {
+				typ := typ
+				return c.matchEqual(func(it *Item) bool { return it.Type == typ })
+			}
+		}
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidCondition, c.Value)
+	case Tag:
+		tag, err := ParseTag(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCondition, err)
+		}
+		return c.matchEqual(func(it *Item) bool { return it.HasTag(tag) })
+	default:
+		return nil, fmt.Errorf("%w: unknown target %s", ErrInvalidCondition, c.Target)
+	}
+}
+
+func (c *Condition) matchText(text func(*Item) string) (func(*Item) bool, error) {
+	value := strings.ToLower(c.Value)
+	switch c.Type {
+	case Equal, NotEqual:
+		return c.matchEqual(func(it *Item) bool { return text(it) == c.Value })
+	case Contains:
+		return func(it *Item) bool { return strings.Contains(strings.ToLower(text(it)), value) }, nil
+	case NotContain:
+		return func(it *Item) bool { return !strings.Contains(strings.ToLower(text(it)), value) }, nil
+	default:
+		return nil, c.unsupported()
+	}
+}
+
+func (c *Condition) matchTime(now time.Time, at func(*Item) time.Time) (func(*Item) bool, error) {
+	var value time.Time
+	if c.Value != "" || (c.Type != Equal && c.Type != NotEqual) {
+		var err error
+		value, err = ParseTimeValue(c.Value, now)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCondition, err)
+		}
+	}
+	switch c.Type {
+	case Equal, NotEqual:
+		return c.matchEqual(func(it *Item) bool { return at(it).Equal(value) })
+	case Before:
+		return func(it *Item) bool { t := at(it); return !t.IsZero() && t.Before(value) }, nil
+	case After:
+		return func(it *Item) bool { t := at(it); return !t.IsZero() && t.After(value) }, nil
+	default:
+		return nil, c.unsupported()
+	}
+}
+
+// matchEqual returns equal for Equal and its negation for NotEqual.
+func (c *Condition) matchEqual(equal func(*Item) bool) (func(*Item) bool, error) {
+	switch c.Type {
+	case Equal:
+		return equal, nil
+	case NotEqual:
+		return func(it *Item) bool { return !equal(it) }, nil
+	default:
+		return nil, c.unsupported()
+	}
+}
+
+func (c *Condition) unsupported() error {
+	return &unsupportedConditionError{c.Type, c.Target}
+}
+
+// unsupportedConditionError is returned for a condition type not supported by the target, it wraps
+// ErrInvalidCondition.
+type unsupportedConditionError struct {
+	Type   ConditionType
+	Target ConditionTarget
+}
+
+func (e *unsupportedConditionError) Error() string {
+	return fmt.Sprintf("%s: %s is not supported by %s", ErrInvalidCondition, e.Type, e.Target)
+}
+
+func (e *unsupportedConditionError) Unwrap() error {
+	return ErrInvalidCondition
+}
+
+// timeOffsetPattern matches offsets of relative times.
+var timeOffsetPattern = regexp.MustCompile(`^([+-])([0-9]+)([hdw])$`)
+
+// ParseTimeValue parses a time in conditions, which is one of:
+//   - a time in RFC 3339, e.g. 2020-01-02T15:04:05Z
+//   - a date in the location of now, e.g. 2020-01-02
+//   - an anchor of now, today (the start of the day) or week (the start of the week, Monday), optionally followed by
+//     an offset in hours, days or weeks, e.g. today+1d and week-2w
+//   - an offset alone, which is relative to now, e.g. +7d
+func ParseTimeValue(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	anchor, offset := now, s
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, a := range []struct {
+		name string
+		at   time.Time
+	}{
+		{"now", now},
+		{"today", today},
+		{"week", today.AddDate(0, 0, -(int(today.Weekday())+6)%7)},
+	} {
+		if strings.HasPrefix(s, a.name) {
+			if s == a.name {
+				return a.at, nil
+			}
+			anchor, offset = a.at, s[len(a.name):]
+			break
+		}
+	}
+	m := timeOffsetPattern.FindStringSubmatch(offset)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: %w", s, err)
+	}
+	if m[1] == "-" {
+		n = -n
+	}
+	switch m[3] {
+	case "h":
+		return anchor.Add(time.Duration(n) * time.Hour), nil
+	case "d":
+		return anchor.AddDate(0, 0, n), nil
+	default:
+		return anchor.AddDate(0, 0, 7*n), nil
+	}
+}
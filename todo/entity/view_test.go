@@ -0,0 +1,147 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskViewMatch(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	item := &Item{
+		ID:           1,
+		Title:        "Write Changelog",
+		Description:  "mention the storages",
+		Type:         ItemTypeTask,
+		State:        ItemStateCompleted,
+		Due:          day.Add(12 * time.Hour),
+		CreatedAt:    day,
+		UpdatedAt:    day.Add(time.Hour),
+		ParentItemID: 42,
+		Tags:         []string{"docs", "release"},
+	}
+	for _, c := range []struct {
+		cond    Condition
+		matched bool
+	}{
+		{Condition{Equal, Title, "Write Changelog"}, true},
+		{Condition{Equal, Title, "write changelog"}, false},
+		{Condition{NotEqual, Title, "Write"}, true},
+		{Condition{Contains, Title, "changelog"}, true},
+		{Condition{NotContain, Description, "STORAGE"}, false},
+		{Condition{Before, CreatedAt, "2020-01-02T00:00:01Z"}, true},
+		{Condition{After, CreatedAt, "2020-01-02T00:00:00Z"}, false},
+		{Condition{After, UpdatedAt, "2020-01-02T08:00:00+08:00"}, true},
+		{Condition{Equal, UpdatedAt, "2020-01-02T01:00:00Z"}, true},
+		{Condition{Before, Due, "2020-01-03T00:00:00Z"}, true},
+		{Condition{Equal, Due, ""}, false},
+		{Condition{NotEqual, Due, ""}, true},
+		{Condition{Equal, ParentTaskID, "42"}, true},
+		{Condition{NotEqual, ParentTaskID, "42"}, false},
+		{Condition{Equal, State, "completed"}, true},
+		{Condition{Equal, Type, "project"}, false},
+		{Condition{NotEqual, Type, "project"}, true},
+		{Condition{Equal, Tag, "#docs"}, true},
+		{Condition{Equal, Tag, "Docs"}, false},
+		{Condition{NotEqual, Tag, "release"}, false},
+	} {
+		c := c
+		match, err := (&TaskView{Filter: &Composition{Conditions: []*Condition{&c.cond}}}).Match(day)
+		if assert.NoError(t, err, "%+v", c.cond) {
+			assert.Equal(t, c.matched, match(item), "%+v", c.cond)
+		}
+	}
+
+	// a zero time is neither before nor after anything
+	match, err := (&TaskView{Filter: &Composition{Type: Or, Conditions: []*Condition{
+		{Before, Due, "2030-01-01T00:00:00Z"},
+		{After, Due, "2000-01-01T00:00:00Z"},
+	}}}).Match(day)
+	assert.NoError(t, err)
+	assert.False(t, match(&Item{}))
+}
+
+func TestTaskViewMatchNestedCompositions(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	// tasks not completed, either due before 2020 or titled urgent
+	view := &TaskView{Filter: &Composition{
+		Type:       And,
+		Conditions: []*Condition{{Equal, State, "normal"}},
+		Compositions: []*Composition{{
+			Type: Or,
+			Conditions: []*Condition{
+				{Before, Due, "2020-01-01T00:00:00Z"},
+				{Contains, Title, "urgent"},
+			},
+		}},
+	}}
+	match, err := view.Match(day)
+	assert.NoError(t, err)
+	old := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.True(t, match(&Item{Due: old}))
+	assert.True(t, match(&Item{Title: "URGENT: call"}))
+	assert.False(t, match(&Item{Title: "call"}))
+	assert.False(t, match(&Item{Due: old, State: ItemStateCompleted}))
+
+	for _, empty := range []struct {
+		comp    ComposeType
+		matched bool
+	}{{And, true}, {Or, false}} {
+		match, err := (&TaskView{Filter: &Composition{Type: empty.comp}}).Match(day)
+		assert.NoError(t, err)
+		assert.Equal(t, empty.matched, match(&Item{}))
+	}
+	match, err = (&TaskView{}).Match(day)
+	assert.NoError(t, err)
+	assert.True(t, match(&Item{}))
+}
+
+func TestTaskViewMatchInvalidConditions(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	for _, c := range []*Condition{
+		{Before, Title, "a"},
+		{Contains, Due, "2020-01-01T00:00:00Z"},
+		{Before, Due, ""},
+		{Equal, CreatedAt, "yesterday"},
+		{Equal, ParentTaskID, "root"},
+		{Contains, ParentTaskID, "1"},
+		{Equal, State, "done"},
+		{Equal, Type, "Task"},
+		{Equal, Tag, "1st"},
+		{Contains, Tag, "docs"},
+		{Equal, ConditionTarget(-1), ""},
+	} {
+		_, err := (&TaskView{Filter: &Composition{Compositions: []*Composition{{Conditions: []*Condition{c}}}}}).Match(day)
+		assert.True(t, errors.Is(err, ErrInvalidCondition), "%+v", c)
+	}
+	_, err := (&TaskView{Filter: &Composition{Type: ComposeType(-1)}}).Match(day)
+	assert.True(t, errors.Is(err, ErrInvalidCondition))
+}
+
+func TestParseTimeValue(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	// a Thursday
+	now := time.Date(2020, 1, 2, 15, 4, 5, 0, loc)
+	for s, expected := range map[string]time.Time{
+		"2020-01-02T00:00:00Z": time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		"2020-02-01":           time.Date(2020, 2, 1, 0, 0, 0, 0, loc),
+		"now":                  now,
+		"now-2h":               now.Add(-2 * time.Hour),
+		"+7d":                  time.Date(2020, 1, 9, 15, 4, 5, 0, loc),
+		"today":                time.Date(2020, 1, 2, 0, 0, 0, 0, loc),
+		"today+1d":             time.Date(2020, 1, 3, 0, 0, 0, 0, loc),
+		"week":                 time.Date(2019, 12, 30, 0, 0, 0, 0, loc),
+		"week+1w":              time.Date(2020, 1, 6, 0, 0, 0, 0, loc),
+	} {
+		got, err := ParseTimeValue(s, now)
+		if assert.NoError(t, err, s) {
+			assert.True(t, expected.Equal(got), "%s: %s", s, got)
+		}
+	}
+	for _, s := range []string{"", "7d", "today+", "today+1y", "nowhere", "+1.5d"} {
+		_, err := ParseTimeValue(s, now)
+		assert.Error(t, err, s)
+	}
+}
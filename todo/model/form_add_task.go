@@ -8,6 +8,16 @@ type FormAddTask struct {
 	Due         time.Time
 	Description string
 	Type        TaskType
+	Priority    TaskPriority
 	ParentID    int64
-	Order       uint64
+	// Position is where the task goes among the tasks of the parent, a position out of range puts it at the end.
+	Position int
+	// Recurrence is an RRULE, e.g. FREQ=WEEKLY;BYDAY=MO, the task is not repeated if it is empty.
+	Recurrence string
+	// Reminders are offsets before Due to be reminded at, e.g. 15m or 1d before.
+	Reminders []string
+	// Tags label the task, e.g. work or #work.
+	Tags []string
+	// Estimate is the expected effort of the task, e.g. 30m or 2h, the task is not estimated if it is empty.
+	Estimate string
 }
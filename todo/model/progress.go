@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// Progress is the progress of a project.
+type Progress struct {
+	// Completed and Total are the counts of completed tasks and all tasks under the project.
+	Completed int
+	Total     int
+	// Remaining is the sum of estimates of the tasks not completed yet.
+	Remaining time.Duration
+}
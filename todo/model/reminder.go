@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// Reminder reminds the user of a task before its due.
+type Reminder struct {
+	Task *Task
+	// At is the time the reminder is for, which is an offset of the task before its due.
+	At time.Time
+}
@@ -0,0 +1,8 @@
+package model
+
+// SearchResult is a task found by a search along with where it is.
+type SearchResult struct {
+	Task *Task
+	// Path are the ancestors of the task from the top level down to its parent.
+	Path []*Task
+}
@@ -0,0 +1,7 @@
+package model
+
+// Tag is a label of tasks along with the number of tasks labeled.
+type Tag struct {
+	Name  string
+	Count int
+}
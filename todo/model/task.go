@@ -8,7 +8,18 @@ type Task struct {
 	Title       string
 	Type        TaskType
 	State       TaskState
+	Priority    TaskPriority
 	Due         time.Time
 	Description string
-	Order       uint64
+	Revision    uint64
+	ParentID    int64
+	// Recurrence is the RRULE the task repeats by, it is empty if the task does not repeat.
+	Recurrence string
+	// Reminders are the offsets before Due at which the user is reminded of the task.
+	Reminders []time.Duration
+	Tags      []string
+	// Estimate is the expected effort of the task, it is zero if the task is not estimated.
+	Estimate time.Duration
+	// Progress is rolled up from the sub tasks of a project, it is nil for other types.
+	Progress *Progress
 }
@@ -0,0 +1,27 @@
+package model
+
+// TaskEventType indicates how a task is changed.
+type TaskEventType int
+
+// All TaskEventType(s).
+const (
+	TaskEventCreated TaskEventType = iota
+	TaskEventUpdated
+	// TaskEventReordered indicates only the position of the task among its siblings is changed.
+	TaskEventReordered
+	// TaskEventDeleted indicates the task is moved to the trash.
+	TaskEventDeleted
+	// TaskEventRestored indicates the task is moved back from the trash.
+	TaskEventRestored
+	// TaskEventRemoved indicates the task is removed permanently.
+	TaskEventRemoved
+)
+
+// TaskEvent is a change of a task.
+type TaskEvent struct {
+	Type TaskEventType
+	Task *Task
+	// PreviousParentID is the parent of the task before the change, which differs from that of Task only if the task
+	// is moved.
+	PreviousParentID int64
+}
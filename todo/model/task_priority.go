@@ -0,0 +1,12 @@
+package model
+
+// TaskPriority indicates how important a task is.
+type TaskPriority int
+
+// All TaskPriority(s), from the lowest.
+const (
+	TaskPriorityNone TaskPriority = iota
+	TaskPriorityLow
+	TaskPriorityMedium
+	TaskPriorityHigh
+)
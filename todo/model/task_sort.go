@@ -0,0 +1,22 @@
+package model
+
+// SortKey is a key to sort tasks by.
+type SortKey int
+
+// All SortKey(s).
+const (
+	// SortByOrder sorts tasks in their manual order.
+	SortByOrder SortKey = iota
+	// SortByPriority sorts tasks of higher priorities first.
+	SortByPriority
+	// SortByDue sorts tasks due earlier first, tasks without a due go last.
+	SortByDue
+	// SortByCreated sorts tasks created earlier first.
+	SortByCreated
+	// SortByTitle sorts tasks by their titles regardless of case.
+	SortByTitle
+)
+
+// TaskSort specifies how tasks are sorted: by each key in turn, then in their manual order. The nil TaskSort is the
+// manual order.
+type TaskSort []SortKey
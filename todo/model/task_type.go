@@ -7,4 +7,8 @@ type TaskType int
 const (
 	TaskTypeCategory TaskType = iota
 	TaskTypeTask
+	// TaskTypeView is the type of views in events of tasks, views are not listed as tasks.
+	TaskTypeView
+	// TaskTypeProject is a task made of sub tasks, see Task.Progress.
+	TaskTypeProject
 )
@@ -0,0 +1,13 @@
+package model
+
+// View is a named filter of tasks.
+type View struct {
+	ID   int64
+	Name string
+	// Query is the filter in the query language, see entity.ParseQuery.
+	Query string
+	// BuiltIn views are given by the application rather than saved by the user, they have negative IDs and could
+	// not be changed.
+	BuiltIn  bool
+	Revision uint64
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+// Command delivers reminders by running a command with the message of the reminder as its last argument. The task is
+// also given in environment variables: TODO_TASK_ID, TODO_TASK_TITLE, TODO_TASK_DUE and TODO_REMIND_AT, times are in
+// RFC 3339.
+type Command struct {
+	Name string
+	Args []string
+}
+
+// Notify runs the command and waits for it to exit, its output is returned in the error if it fails.
+func (c *Command) Notify(r *model.Reminder) error {
+	args := append(append([]string(nil), c.Args...), message(r))
+	cmd := exec.Command(c.Name, args...)
+	cmd.Env = append(os.Environ(),
+		"TODO_TASK_ID="+strconv.FormatInt(r.Task.ID, 10),
+		"TODO_TASK_TITLE="+r.Task.Title,
+		"TODO_TASK_DUE="+r.Task.Due.Format(time.RFC3339),
+		"TODO_REMIND_AT="+r.At.Format(time.RFC3339),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("running %s: %w: %s", c.Name, err, out)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "todo-notify-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	due := time.Date(2020, 1, 2, 15, 4, 0, 0, time.UTC)
+	r := &model.Reminder{Task: &model.Task{ID: 42, Title: "Call mom", Due: due}, At: due.Add(-time.Hour)}
+	c := &Command{Name: "sh", Args: []string{"-c", `echo "$TODO_TASK_ID $TODO_TASK_DUE $TODO_REMIND_AT $1" > ` + out, "sh"}}
+	assert.NoError(t, c.Notify(r))
+	buf, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "42 2020-01-02T15:04:00Z 2020-01-02T14:04:00Z "+message(r)+"\n", string(buf))
+
+	c = &Command{Name: "sh", Args: []string{"-c", "echo failed; exit 1"}}
+	err = c.Notify(r)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed")
+	}
+}
@@ -0,0 +1,17 @@
+package notify
+
+import (
+	"log"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+// Log delivers reminders by writing them to a log.
+type Log struct {
+	*log.Logger
+}
+
+func (l *Log) Notify(r *model.Reminder) error {
+	l.Printf("Reminder: task[%d] %s", r.Task.ID, message(r))
+	return nil
+}
@@ -0,0 +1,21 @@
+package notify
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	l := &Log{log.New(&buf, "", 0)}
+	r := &model.Reminder{Task: &model.Task{ID: 42, Title: "Call mom", Due: time.Now()}, At: time.Now()}
+	assert.NoError(t, l.Notify(r))
+	assert.Equal(t, "Reminder: task[42] "+message(r)+"\n", buf.String())
+}
@@ -0,0 +1,32 @@
+// Package notify implements use.Notifier, to deliver reminders of tasks outside of the user interface.
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+// Notifiers delivers reminders through each of its notifiers, errors of all of them are returned together.
+type Notifiers []use.Notifier
+
+func (ns Notifiers) Notify(r *model.Reminder) error {
+	var msgs []string
+	for _, n := range ns {
+		if err := n.Notify(r); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("notifying: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// message is the text of a reminder.
+func message(r *model.Reminder) string {
+	return fmt.Sprintf("%s is due at %s", r.Task.Title, r.Task.Due.Format(time.RFC1123))
+}
@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+type failing struct{}
+
+func (failing) Notify(*model.Reminder) error { return errors.New("no display") }
+
+func TestNotifiers(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	r := &model.Reminder{Task: &model.Task{ID: 42, Title: "Call mom", Due: time.Now()}, At: time.Now()}
+
+	// every notifier is tried even if one fails
+	err := Notifiers{failing{}, &Log{log.New(&buf, "", 0)}, failing{}}.Notify(r)
+	assert.EqualError(t, err, "notifying: no display; no display")
+	assert.Contains(t, buf.String(), "Call mom")
+
+	assert.NoError(t, Notifiers{&Log{log.New(&buf, "", 0)}}.Notify(r))
+	assert.NoError(t, Notifiers{}.Notify(r))
+}
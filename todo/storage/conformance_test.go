@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/storage/storagetest"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) (use.Storage, func()) {
+		return NewMemory(), nil
+	})
+}
+
+func TestFileSystemConformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) (use.Storage, func()) {
+		dir := tempDir(t)
+		return NewFileSystem(dir), func() { os.RemoveAll(dir) }
+	})
+}
+
+func TestJournalConformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) (use.Storage, func()) {
+		dir := tempDir(t)
+		j, err := NewJournal(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return j, func() {
+			j.Close()
+			os.RemoveAll(dir)
+		}
+	})
+}
+
+func TestTodoTxtConformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) (use.Storage, func()) {
+		dir := tempDir(t)
+		return NewTodoTxt(filepath.Join(dir, "todo.txt")), func() { os.RemoveAll(dir) }
+	})
+}
+
+func TestOrgConformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) (use.Storage, func()) {
+		dir := tempDir(t)
+		return NewOrg(filepath.Join(dir, "todo.org")), func() { os.RemoveAll(dir) }
+	})
+}
+
+func TestOutlineConformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) (use.Storage, func()) {
+		dir := tempDir(t)
+		return NewOutline(filepath.Join(dir, "todo.outline")), func() { os.RemoveAll(dir) }
+	})
+}
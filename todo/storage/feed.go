@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+// feed delivers item events to its subscribers, the zero value is ready to use.
+// Events are queued for each subscriber, so publishing never blocks on a slow subscriber.
+type feed struct {
+	mu   sync.Mutex
+	subs map[*subscriber]bool
+}
+
+type subscriber struct {
+	events chan use.ItemEvent
+	// wake is signaled when the queue is not empty
+	wake chan struct{}
+	done chan struct{}
+
+	mu    sync.Mutex
+	queue []use.ItemEvent
+}
+
+// subscribe returns a channel of events published from now on, the channel is closed once cancel is called.
+func (f *feed) subscribe() (<-chan use.ItemEvent, func()) {
+	s := &subscriber{
+		events: make(chan use.ItemEvent),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	f.mu.Lock()
+	if f.subs == nil {
+		f.subs = make(map[*subscriber]bool)
+	}
+	f.subs[s] = true
+	f.mu.Unlock()
+	go s.deliver()
+
+	var once sync.Once
+	return s.events, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs, s)
+			f.mu.Unlock()
+			close(s.done)
+		})
+	}
+}
+
+// publish queues events for all subscribers, each subscriber gets its own copies of items.
+func (f *feed) publish(events ...use.ItemEvent) {
+	if len(events) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for s := range f.subs {
+		s.mu.Lock()
+		for _, e := range events {
+			e.Item = copyItem(e.Item)
+			s.queue = append(s.queue, e)
+		}
+		s.mu.Unlock()
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// len returns the number of subscribers.
+func (f *feed) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
+
+func (s *subscriber) deliver() {
+	defer close(s.events)
+	for {
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+
+		for _, e := range queue {
+			select {
+			case s.events <- e:
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// itemEvent returns the event of old being changed to item, old is nil if item is new. It returns false if nothing
+// has been changed.
+func itemEvent(old, item *entity.Item) (use.ItemEvent, bool) {
+	if old == nil {
+		return use.ItemEvent{Type: use.ItemCreated, Item: item, PreviousParentItemID: item.ParentItemID}, true
+	}
+	if reflect.DeepEqual(old, item) {
+		return use.ItemEvent{}, false
+	}
+	e := use.ItemEvent{Type: use.ItemUpdated, Item: item, PreviousParentItemID: old.ParentItemID}
+	switch {
+	case !isDeleted(old) && isDeleted(item):
+		e.Type = use.ItemDeleted
+		return e, true
+	case isDeleted(old) && !isDeleted(item):
+		e.Type = use.ItemRestored
+		return e, true
+	}
+	reordered := *old
+	reordered.Rank, reordered.Revision, reordered.UpdatedAt = item.Rank, item.Revision, item.UpdatedAt
+	if reflect.DeepEqual(&reordered, item) {
+		e.Type = use.ItemReordered
+	}
+	return e, true
+}
+
+// removedItemEvent returns the event of it being removed.
+func removedItemEvent(it *entity.Item) use.ItemEvent {
+	return use.ItemEvent{Type: use.ItemRemoved, Item: it, PreviousParentItemID: it.ParentItemID}
+}
+
+// diffItemTables returns events of items of after which are new or different from those of before, followed by
+// those of items of before which are removed.
+func diffItemTables(before, after *itemTable) []use.ItemEvent {
+	old := make(map[int64]*entity.Item, len(before.Items))
+	for _, it := range before.Items {
+		old[it.ID] = it
+	}
+	var events []use.ItemEvent
+	for _, it := range after.Items {
+		if e, ok := itemEvent(old[it.ID], it); ok {
+			events = append(events, e)
+		}
+		delete(old, it.ID)
+	}
+	for _, it := range before.Items {
+		if _, ok := old[it.ID]; ok {
+			events = append(events, removedItemEvent(it))
+		}
+	}
+	return events
+}
@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+// fileFormat converts between an itemTable and the content of a data file.
+type fileFormat interface {
+	decode(buf []byte) (*itemTable, error)
+	// encode returns the new content of the data file, prev is the current content which could be used to keep
+	// the parts that are not understood by the format.
+	encode(t *itemTable, prev []byte) ([]byte, error)
+}
+
+// fileStore implements the storage on top of a single data file in any fileFormat.
+//
+// Every change is written to a temporary file which then replaces the data file atomically, so the data file is
+// always complete even if the process crashes. Changes are made while holding an exclusive lock on the lock file,
+// which makes it safe for multiple processes to share one data file.
+//
+// Changes made by others are found by comparing the data file to the last version read, which happens on every
+// read, and every fileWatchInterval while there are subscribers.
+type fileStore struct {
+	file     string
+	lockFile string
+	format   fileFormat
+
+	mu        sync.Mutex
+	cache     *itemTable
+	cacheInfo os.FileInfo
+	feed      feed
+	// stopWatch stops checking the data file periodically, it is nil if not checking
+	stopWatch chan struct{}
+}
+
+// fileWatchInterval is how often a fileStore with subscribers checks the data file for changes made by others.
+var fileWatchInterval = time.Second
+
+// SaveItem saves an item into the data file, return its id.
+func (f *fileStore) SaveItem(item *entity.Item) (int64, error) {
+	if item == nil {
+		return -1, ErrNilItem
+	}
+	err := f.update(func(d *itemTable) error {
+		touchItem(item)
+		return d.saveItem(item)
+	})
+	if err != nil {
+		return -1, err
+	}
+	return item.ID, nil
+}
+
+// DeleteItem moves the item of given ID and its descendants to the trash.
+func (f *fileStore) DeleteItem(id int64) error {
+	return f.RunInTx(func(tx use.Storage) error {
+		return tx.DeleteItem(id)
+	})
+}
+
+// RestoreItem moves the item of given ID back from the trash.
+func (f *fileStore) RestoreItem(id int64) error {
+	return f.RunInTx(func(tx use.Storage) error {
+		return tx.RestoreItem(id)
+	})
+}
+
+// EmptyTrash removes items in the trash, returns the number of removed items.
+func (f *fileStore) EmptyTrash() (n int, err error) {
+	err = f.RunInTx(func(tx use.Storage) error {
+		n, err = tx.EmptyTrash()
+		return err
+	})
+	return n, err
+}
+
+// RunInTx runs fn within a transaction, changes made through the Storage given to fn are written at once if fn
+// succeeds or discarded otherwise. The data file stays locked until the transaction ends, so fn must not use the
+// storage directly.
+func (f *fileStore) RunInTx(fn func(use.Storage) error) error {
+	return f.update(func(d *itemTable) error {
+		// d is dropped if fn fails
+		return fn(&tableTx{d, &f.feed})
+	})
+}
+
+// Subscribe returns a channel of changes made to items from now on, including those made by other processes, the
+// channel is closed once cancel is called.
+func (f *fileStore) Subscribe() (<-chan use.ItemEvent, func()) {
+	events, cancel := f.feed.subscribe()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopWatch == nil {
+		// the version to compare to, an error is left to the next check
+		f.loadLocked()
+		f.stopWatch = make(chan struct{})
+		go f.watch(f.stopWatch)
+	}
+	return events, func() {
+		cancel()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.feed.len() == 0 && f.stopWatch != nil {
+			close(f.stopWatch)
+			f.stopWatch = nil
+		}
+	}
+}
+
+// watch checks the data file until stop is closed.
+func (f *fileStore) watch(stop chan struct{}) {
+	ticker := time.NewTicker(fileWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// an error is left to readers, who get it on their own reads
+			f.load()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// GetItemsByParentID returns items of given parent.
+func (f *fileStore) GetItemsByParentID(parentID int64) ([]*entity.Item, error) {
+	d, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return d.itemsByParentID(parentID), nil
+}
+
+// GetItemByID returns items of given ID.
+func (f *fileStore) GetItemByID(id int64) (*entity.Item, error) {
+	if id == entity.RootID {
+		return copyItem(entity.RootItem), nil
+	}
+	d, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return d.itemByID(id)
+}
+
+// GetItemsDueBetween returns items due in [from, to) sorted by due, items of the same due are sorted by ID.
+func (f *fileStore) GetItemsDueBetween(from, to time.Time) ([]*entity.Item, error) {
+	d, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return d.itemsDueBetween(from, to), nil
+}
+
+// load returns the content of the data file, the file is read only if it has been replaced since the last read.
+func (f *fileStore) load() (*itemTable, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.loadLocked()
+}
+
+// loadLocked is load with f.mu held, changes since the last read are published.
+func (f *fileStore) loadLocked() (*itemTable, error) {
+	info, err := os.Stat(f.file)
+	if os.IsNotExist(err) {
+		if f.cache == nil {
+			// the version to compare to once the file is created
+			f.cache = newItemTable()
+		}
+		return newItemTable(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("checking data file: %w", err)
+	}
+	if f.cache != nil && f.cacheInfo != nil && sameFileVersion(f.cacheInfo, info) {
+		return f.cache, nil
+	}
+	d, _, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	if f.cache != nil {
+		f.feed.publish(diffItemTables(f.cache, d)...)
+	}
+	f.cache, f.cacheInfo = d, info
+	return d, nil
+}
+
+// read reads the data file without looking at the cache, returns the decoded table and the raw content.
+func (f *fileStore) read() (*itemTable, []byte, error) {
+	buf, err := ioutil.ReadFile(f.file)
+	if os.IsNotExist(err) {
+		return newItemTable(), nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading data file: %w", err)
+	}
+	d, err := f.format.decode(buf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding data file: %w", err)
+	}
+	return d, buf, nil
+}
+
+// update applies fn to the latest content of the data file and writes the result back, all within the lock.
+func (f *fileStore) update(fn func(*itemTable) error) error {
+	if err := os.MkdirAll(filepath.Dir(f.file), 0700); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	unlock, err := lockFile(f.lockFile)
+	if err != nil {
+		return fmt.Errorf("locking data file: %w", err)
+	}
+	defer unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// always read from disk, another process may have changed the file since the last read
+	d, prev, err := f.read()
+	if err != nil {
+		return err
+	}
+	// changes made by others since the last read are published along with the ones made by fn
+	before := f.cache
+	if before == nil {
+		before = d.clone()
+	}
+	if err := fn(d); err != nil {
+		return err
+	}
+	buf, err := f.format.encode(d, prev)
+	if err != nil {
+		return fmt.Errorf("encoding data file: %w", err)
+	}
+	if err := writeFileAtomic(f.file, buf); err != nil {
+		return fmt.Errorf("writing data file: %w", err)
+	}
+
+	// the written file is read back as what others read, since a format may not keep everything as is
+	f.cache, f.cacheInfo = nil, nil
+	if info, err := os.Stat(f.file); err == nil {
+		if written, _, err := f.read(); err == nil {
+			d, f.cache, f.cacheInfo = written, written, info
+		}
+	}
+	f.feed.publish(diffItemTables(before, d)...)
+	return nil
+}
+
+// sameFileVersion reports whether two stats describe the same version of a file.
+// As every write replaces the file, the inode alone tells a change in most cases, the modification time and the
+// size guard against the inode being reused.
+func sameFileVersion(a, b os.FileInfo) bool {
+	return os.SameFile(a, b) && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
+
+// writeFileAtomic replaces the file at path with buf, the file contains either the old or the new content even if
+// the process crashes half way.
+func writeFileAtomic(path string, buf []byte) (err error) {
+	dir, name := filepath.Split(path)
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(buf); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
@@ -1,28 +1,119 @@
 package storage
 
-import "github.com/tevino/the-clean-architecture-demo/todo/entity"
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	fsDataFileName = "items.json"
+	fsLockFileName = "lock"
+)
 
 // FileSystem implements a file system based storage.
+//
+// All items are kept as JSON in a single data file under the given directory, along with a lock file which makes
+// it safe for multiple processes to share one directory. The data file is stamped with SchemaVersion, data of an
+// older version has to be migrated with MigrateFileSystem before use.
 type FileSystem struct {
-	path string
+	fileStore
 }
 
 // NewFileSystem creates a FileSystem with given data path.
 func NewFileSystem(path string) *FileSystem {
-	return &FileSystem{path}
+	return &FileSystem{fileStore{
+		file:     filepath.Join(path, fsDataFileName),
+		lockFile: filepath.Join(path, fsLockFileName),
+		format:   jsonFormat{},
+	}}
+}
+
+// MigrateFileSystem migrates the data under given path to SchemaVersion, the data file is backed up next to it
+// before being migrated. Nothing is written if dryRun is true, the report tells what would be changed.
+func MigrateFileSystem(path string, dryRun bool) (*MigrationReport, error) {
+	file := filepath.Join(path, fsDataFileName)
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return &MigrationReport{From: SchemaVersion, To: SchemaVersion}, nil
+	}
+	unlock, err := lockFile(filepath.Join(path, fsLockFileName))
+	if err != nil {
+		return nil, fmt.Errorf("locking data file: %w", err)
+	}
+	defer unlock()
+
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading data file: %w", err)
+	}
+	var data struct {
+		Version int
+		NextID  json.Number
+		Items   []map[string]interface{}
+	}
+	if err := decodeJSONKeepNumbers(buf, &data); err != nil {
+		return nil, fmt.Errorf("decoding data file: %w", err)
+	}
+	version := jsonSchemaVersion(data.Version)
+	m, err := newMigrator(version)
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range data.Items {
+		if err := m.migrate(it); err != nil {
+			return nil, err
+		}
+	}
+	report := m.report()
+	if dryRun || version == SchemaVersion {
+		return report, nil
+	}
+
+	report.Backup = backupFileName(file, version)
+	if err := writeFileAtomic(report.Backup, buf); err != nil {
+		return nil, fmt.Errorf("writing backup: %w", err)
+	}
+	data.Version = SchemaVersion
+	if buf, err = json.Marshal(&data); err != nil {
+		return nil, fmt.Errorf("encoding data file: %w", err)
+	}
+	if err := writeFileAtomic(file, buf); err != nil {
+		return nil, fmt.Errorf("writing data file: %w", err)
+	}
+	return report, nil
+}
+
+// jsonData is the content of the data file.
+type jsonData struct {
+	Version int
+	*itemTable
 }
 
-// SaveItem is not implemented yet.
-func (f *FileSystem) SaveItem(item *entity.Item) (int64, error) {
-	return 0, nil
+// jsonSchemaVersion returns the schema version of data stamped with given version, data without one is of version 1.
+func jsonSchemaVersion(stamped int) int {
+	if stamped == 0 {
+		return 1
+	}
+	return stamped
 }
 
-// GetItemsByParentID is not implemented yet.
-func (f *FileSystem) GetItemsByParentID(parentID int64) ([]*entity.Item, error) {
-	return nil, nil
+// jsonFormat stores an itemTable as is along with the schema version.
+type jsonFormat struct{}
+
+func (jsonFormat) decode(buf []byte) (*itemTable, error) {
+	data := &jsonData{itemTable: newItemTable()}
+	if err := json.Unmarshal(buf, data); err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(jsonSchemaVersion(data.Version)); err != nil {
+		return nil, err
+	}
+	data.reindex()
+	return data.itemTable, nil
 }
 
-// GetItemByID is not implemented yet.
-func (f *FileSystem) GetItemByID(int64) (*entity.Item, error) {
-	return nil, nil
+func (jsonFormat) encode(t *itemTable, _ []byte) ([]byte, error) {
+	return json.Marshal(&jsonData{Version: SchemaVersion, itemTable: t})
 }
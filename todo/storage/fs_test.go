@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+func TestFileSystemPersistsItems(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	items := addTestingItems(t, NewFileSystem(dir))
+	items[0].Rank = "z"
+	_, err := NewFileSystem(dir).SaveItem(items[0])
+	assert.NoError(t, err)
+
+	reopened := NewFileSystem(dir)
+	for _, it := range items {
+		got, err := reopened.GetItemByID(it.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, it.Title, got.Title)
+	}
+	top, err := reopened.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"top2", "top1"}, []string{top[0].Title, top[1].Title})
+	assert.Equal(t, "z", top[1].Rank)
+
+	// new IDs continue after the existing ones
+	id, err := reopened.SaveItem(&entity.Item{})
+	assert.NoError(t, err)
+	assert.Equal(t, items[len(items)-1].ID+1, id)
+}
+
+func TestFileSystemSeesChangesOfOtherInstances(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a, b := NewFileSystem(dir), NewFileSystem(dir)
+
+	item := addTestingItems(t, a)[0]
+	_, err := b.GetItemByID(item.ID)
+	assert.NoError(t, err)
+
+	item.Title = "updated by a"
+	_, err = a.SaveItem(item)
+	assert.NoError(t, err)
+	got, err := b.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, item.Title, got.Title)
+}
+
+func TestFileSystemConcurrentWritersDoNotLoseItems(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	const writers, itemsPerWriter = 4, 10
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		// every writer has its own instance, just like separate processes
+		go func(fs *FileSystem) {
+			defer wg.Done()
+			for i := 0; i < itemsPerWriter; i++ {
+				_, err := fs.SaveItem(&entity.Item{})
+				assert.NoError(t, err)
+			}
+		}(NewFileSystem(dir))
+	}
+	wg.Wait()
+
+	items, err := NewFileSystem(dir).GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Len(t, items, writers*itemsPerWriter)
+	ids := make(map[int64]bool)
+	for _, it := range items {
+		ids[it.ID] = true
+	}
+	assert.Len(t, ids, writers*itemsPerWriter)
+}
+
+func TestFileSystemIgnoresLeftoverTempFiles(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	item := addTestingItems(t, NewFileSystem(dir))[0]
+	// a crash during writing leaves a partial temp file behind
+	err := ioutil.WriteFile(filepath.Join(dir, "."+fsDataFileName+".tmp123"), []byte(`{"Items": [`), 0600)
+	assert.NoError(t, err)
+
+	fs := NewFileSystem(dir)
+	_, err = fs.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	_, err = fs.SaveItem(&entity.Item{})
+	assert.NoError(t, err)
+}
+
+func TestFileSystemCorruptedDataFile(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	err := ioutil.WriteFile(filepath.Join(dir, fsDataFileName), []byte("not json"), 0600)
+	assert.NoError(t, err)
+
+	fs := NewFileSystem(dir)
+	_, err = fs.GetItemByID(1)
+	assert.Error(t, err)
+	_, err = fs.SaveItem(&entity.Item{})
+	assert.Error(t, err)
+}
+
+func TestFileSystemEmitsChangesOfOtherInstances(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a, b := NewFileSystem(dir), NewFileSystem(dir)
+
+	events, cancel := a.Subscribe()
+	defer cancel()
+	items := addTestingItems(t, b)
+	// found by a periodical check without a read of a
+	for _, it := range items {
+		assertItemEvent(t, events, use.ItemCreated, it.ID)
+	}
+
+	items[2].State = entity.ItemStateCompleted
+	_, err := b.SaveItem(items[2])
+	assert.NoError(t, err)
+	// found by a read of a
+	_, err = a.GetItemByID(items[2].ID)
+	assert.NoError(t, err)
+	e := assertItemEvent(t, events, use.ItemUpdated, items[2].ID)
+	assert.Equal(t, entity.ItemStateCompleted, e.Item.State)
+}
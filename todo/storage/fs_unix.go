@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package storage
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile acquires an exclusive lock on the file at path, blocks until the lock is available.
+// The lock is released by the OS if the process exits without calling the returned function.
+func lockFile(path string) (unlock func(), err error) {
+	fl, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(fl.Fd()), syscall.LOCK_EX); err != nil {
+		fl.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(fl.Fd()), syscall.LOCK_UN)
+		fl.Close()
+	}, nil
+}
+
+// syncDir flushes the directory entries so that a rename within it survives a crash.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
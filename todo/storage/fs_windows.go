@@ -0,0 +1,33 @@
+//go:build windows
+// +build windows
+
+package storage
+
+import (
+	"os"
+	"time"
+)
+
+const lockRetryInterval = 10 * time.Millisecond
+
+// lockFile acquires an exclusive lock by creating the file at path, blocks until the file could be created.
+func lockFile(path string) (unlock func(), err error) {
+	for {
+		fl, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			return func() {
+				fl.Close()
+				os.Remove(path)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// syncDir is a no-op, directories could not be synced on Windows.
+func syncDir(string) error {
+	return nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// tableIndex indexes items of an itemTable, it shares pointers with the table.
+type tableIndex struct {
+	// byID maps IDs of all items, including those in the trash, to their positions in the table.
+	byID map[int64]int
+	// children are items not in the trash by their parent IDs, sorted by rank then ID.
+	children map[int64][]*entity.Item
+	// due are items not in the trash with a due, sorted by due then ID.
+	due []*entity.Item
+}
+
+func newTableIndex(items []*entity.Item) *tableIndex {
+	x := &tableIndex{byID: make(map[int64]int, len(items)), children: make(map[int64][]*entity.Item)}
+	for i, it := range items {
+		x.byID[it.ID] = i
+		if isDeleted(it) {
+			continue
+		}
+		x.children[it.ParentItemID] = append(x.children[it.ParentItemID], it)
+		if !it.Due.IsZero() {
+			x.due = append(x.due, it)
+		}
+	}
+	for _, siblings := range x.children {
+		siblings := siblings
+		sort.Slice(siblings, func(i, j int) bool { return rankLess(siblings[i], siblings[j]) })
+	}
+	sort.Slice(x.due, func(i, j int) bool { return dueLess(x.due[i], x.due[j]) })
+	return x
+}
+
+// clone returns a copy of the index for items, which are copies of the indexed items at the same positions.
+func (x *tableIndex) clone(items []*entity.Item) *tableIndex {
+	c := &tableIndex{
+		byID:     make(map[int64]int, len(x.byID)),
+		children: make(map[int64][]*entity.Item, len(x.children)),
+		due:      make([]*entity.Item, len(x.due)),
+	}
+	for id, i := range x.byID {
+		c.byID[id] = i
+	}
+	for parentID, siblings := range x.children {
+		copied := make([]*entity.Item, len(siblings))
+		for i, it := range siblings {
+			copied[i] = items[x.byID[it.ID]]
+		}
+		c.children[parentID] = copied
+	}
+	for i, it := range x.due {
+		c.due[i] = items[x.byID[it.ID]]
+	}
+	return c
+}
+
+// insert adds it to the lookups of items not in the trash, it must not be in them yet.
+func (x *tableIndex) insert(it *entity.Item) {
+	if isDeleted(it) {
+		return
+	}
+	x.children[it.ParentItemID] = insertItem(x.children[it.ParentItemID], it, rankLess)
+	if !it.Due.IsZero() {
+		x.due = insertItem(x.due, it, dueLess)
+	}
+}
+
+// drop removes it from the lookups of items not in the trash, it must be unchanged since it was inserted.
+func (x *tableIndex) drop(it *entity.Item) {
+	if isDeleted(it) {
+		return
+	}
+	siblings := dropItem(x.children[it.ParentItemID], it, rankLess)
+	if len(siblings) == 0 {
+		delete(x.children, it.ParentItemID)
+	} else {
+		x.children[it.ParentItemID] = siblings
+	}
+	if !it.Due.IsZero() {
+		x.due = dropItem(x.due, it, dueLess)
+	}
+}
+
+// dueBetween returns items due in [from, to).
+func (x *tableIndex) dueBetween(from, to time.Time) []*entity.Item {
+	i := sort.Search(len(x.due), func(i int) bool { return !x.due[i].Due.Before(from) })
+	j := sort.Search(len(x.due), func(j int) bool { return !x.due[j].Due.Before(to) })
+	if j < i {
+		return nil
+	}
+	return x.due[i:j]
+}
+
+func insertItem(items []*entity.Item, it *entity.Item, less func(a, b *entity.Item) bool) []*entity.Item {
+	i := sort.Search(len(items), func(i int) bool { return !less(items[i], it) })
+	items = append(items, nil)
+	copy(items[i+1:], items[i:])
+	items[i] = it
+	return items
+}
+
+func dropItem(items []*entity.Item, it *entity.Item, less func(a, b *entity.Item) bool) []*entity.Item {
+	i := sort.Search(len(items), func(i int) bool { return !less(items[i], it) })
+	if i < len(items) && items[i].ID == it.ID {
+		items = append(items[:i], items[i+1:]...)
+	}
+	return items
+}
+
+// rankLess orders siblings by rank, items of the same rank are ordered by ID.
+func rankLess(a, b *entity.Item) bool {
+	if a.Rank != b.Rank {
+		return a.Rank < b.Rank
+	}
+	return a.ID < b.ID
+}
+
+// dueLess orders items by due, items of the same due are ordered by ID.
+func dueLess(a, b *entity.Item) bool {
+	if !a.Due.Equal(b.Due) {
+		return a.Due.Before(b.Due)
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// changeItemTableRandomly makes a random change to table.
+func changeItemTableRandomly(t *testing.T, r *rand.Rand, table *itemTable) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	randomID := func() int64 { return r.Int63n(table.NextID + 1) }
+	switch r.Intn(8) {
+	case 0, 1, 2:
+		item := &entity.Item{ParentItemID: randomID(), Rank: strconv.FormatInt(r.Int63n(20)+1, 36)}
+		if r.Intn(2) == 0 {
+			item.Due = now.Add(time.Duration(r.Intn(100)) * time.Hour)
+		}
+		assert.NoError(t, table.saveItem(item))
+	case 3, 4:
+		if it := table.find(randomID()); it != nil && !isDeleted(it) {
+			moved := copyItem(it)
+			moved.ParentItemID, moved.Rank, moved.Due = randomID(), "i", time.Time{}
+			assert.NoError(t, table.saveItem(moved))
+		}
+	case 5:
+		_ = table.deleteItem(randomID(), now)
+	case 6:
+		_ = table.restoreItem(randomID(), now)
+	case 7:
+		if r.Intn(10) == 0 {
+			table.emptyTrash()
+		} else {
+			table.removeItem(randomID())
+		}
+	}
+}
+
+func TestItemTableIndexStaysUpToDate(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	table := newItemTable()
+	for i := 0; i < 2000; i++ {
+		changeItemTableRandomly(t, r, table)
+		if i%100 == 0 {
+			table = table.clone()
+		}
+	}
+
+	assert.NotEmpty(t, table.index.due)
+	assert.Equal(t, newTableIndex(table.Items), table.index)
+	clone := table.clone()
+	assert.Equal(t, newTableIndex(clone.Items), clone.index)
+}
+
+func TestItemTableTrackRollsBack(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	table := newItemTable()
+	for i := 0; i < 500; i++ {
+		changeItemTableRandomly(t, r, table)
+	}
+
+	byID := func(table *itemTable) map[int64]*entity.Item {
+		items := make(map[int64]*entity.Item, len(table.Items))
+		for _, it := range table.Items {
+			items[it.ID] = it
+		}
+		return items
+	}
+	for i := 0; i < 20; i++ {
+		before := table.clone()
+		failed := errors.New("failed")
+		_, err := table.track(func() error {
+			for j := 0; j < 50; j++ {
+				changeItemTableRandomly(t, r, table)
+			}
+			// a nested change is rolled back along with its parent
+			_, err := table.track(func() error {
+				changeItemTableRandomly(t, r, table)
+				return nil
+			})
+			assert.NoError(t, err)
+			return failed
+		})
+		assert.Equal(t, failed, err)
+		assert.Equal(t, before.NextID, table.NextID)
+		assert.Equal(t, byID(before), byID(table))
+		assert.Equal(t, newTableIndex(table.Items), table.index)
+		assert.Nil(t, table.changes)
+
+		changes, err := table.track(func() error {
+			changeItemTableRandomly(t, r, table)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, diffItemTables(before, table), changes.events(table))
+	}
+}
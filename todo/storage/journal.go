@@ -0,0 +1,678 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+const (
+	journalLogFileName      = "journal.log"
+	journalSnapshotFileName = "snapshot.json"
+	journalLockFileName     = "lock"
+
+	// DefaultJournalCompactSize is the default size of the log at which a Journal compacts itself.
+	DefaultJournalCompactSize = 1 << 20
+)
+
+// ErrCorruptedJournal is returned when a record other than the last one of the log could not be read.
+var ErrCorruptedJournal = errors.New("Journal is corrupted")
+
+// Journal is a storage that appends every change as a record to a log file, the state is rebuilt by replaying the
+// log on open. Once the log grows beyond CompactSize, the state is written to a snapshot and the log starts over.
+//
+// A Journal holds an exclusive lock on its directory until closed, other processes opening the same directory
+// wait for the lock. The snapshot is stamped with SchemaVersion, a Journal of an older version has to be migrated
+// with MigrateJournal before being opened.
+type Journal struct {
+	// CompactSize is the size in bytes of the log that triggers a compaction.
+	CompactSize int64
+	// OnError is called with the error of a compaction, which is retried on the next append. The change which
+	// triggered the compaction is committed nevertheless. It is called with the Journal locked, so it must not use
+	// the Journal.
+	OnError func(err error)
+
+	path  string
+	mu    sync.Mutex
+	table *itemTable
+	seq   uint64
+	log   journalLog
+	size  int64
+	// broken is the error which left the log in an unknown state, appends are refused once it is set
+	broken error
+	unlock func()
+	feed   feed
+}
+
+// journalLog is the log file of a Journal.
+type journalLog interface {
+	io.Writer
+	io.Seeker
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+type journalOp string
+
+const (
+	journalOpSaveItem journalOp = "save_item"
+	journalOpPutItems journalOp = "put_items"
+	// journalOpIncreaseOrderAfter is only found in logs written before ranks, which are replayed on migration.
+	journalOpIncreaseOrderAfter journalOp = "increase_order_after"
+)
+
+// journalRecord is a single change in the log.
+type journalRecord struct {
+	Seq   uint64
+	Op    journalOp
+	Item  *entity.Item   `json:",omitempty"`
+	Items []*entity.Item `json:",omitempty"`
+	// Removed are IDs of items removed by a put_items record, after Items are put.
+	Removed []int64 `json:",omitempty"`
+}
+
+// journalSnapshot is the state of a Journal up to the record of Seq, records in the log are of the same Version.
+type journalSnapshot struct {
+	Version int
+	Seq     uint64
+	Table   *itemTable
+}
+
+// NewJournal opens the Journal in given directory, the directory is created if not exists.
+func NewJournal(path string) (*Journal, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, fmt.Errorf("creating journal directory: %w", err)
+	}
+	unlock, err := lockFile(filepath.Join(path, journalLockFileName))
+	if err != nil {
+		return nil, fmt.Errorf("locking journal directory: %w", err)
+	}
+	j := &Journal{
+		CompactSize: DefaultJournalCompactSize,
+		path:        path,
+		table:       newItemTable(),
+		unlock:      unlock,
+	}
+	if err := j.open(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return j, nil
+}
+
+// MigrateJournal migrates the Journal under given path to SchemaVersion, the snapshot and the log are backed up
+// into a directory next to them before being migrated. Nothing is written if dryRun is true, the report tells what
+// would be changed.
+//
+// The migrated records are written as a new snapshot and the log starts over.
+func MigrateJournal(path string, dryRun bool) (*MigrationReport, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &MigrationReport{From: SchemaVersion, To: SchemaVersion}, nil
+	}
+	unlock, err := lockFile(filepath.Join(path, journalLockFileName))
+	if err != nil {
+		return nil, fmt.Errorf("locking journal directory: %w", err)
+	}
+	defer unlock()
+
+	version, err := journalSchemaVersion(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMigrator(version)
+	if err != nil {
+		return nil, err
+	}
+	if version == SchemaVersion {
+		return m.report(), nil
+	}
+
+	j := &Journal{path: path, table: newItemTable()}
+	snapshotPath, logPath := filepath.Join(path, journalSnapshotFileName), filepath.Join(path, journalLogFileName)
+	snapshot, err := ioutil.ReadFile(snapshotPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	if err == nil {
+		if err := j.migrateSnapshot(snapshot, m); err != nil {
+			return nil, err
+		}
+	}
+	log, err := ioutil.ReadFile(logPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading log: %w", err)
+	}
+	var migrateErr error
+	_, err = j.replay(bytes.NewReader(log), func(line []byte) (*journalRecord, error) {
+		rec, err := migrateJournalRecord(line, m)
+		if errors.Is(err, errMigration) {
+			// the record is intact, it must not be taken as a torn one
+			migrateErr = err
+		}
+		return rec, err
+	})
+	if migrateErr != nil {
+		return nil, migrateErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	report := m.report()
+	if dryRun {
+		return report, nil
+	}
+
+	backup := filepath.Join(path, backupFileName("backup", version))
+	if err := os.MkdirAll(backup, 0700); err != nil {
+		return nil, fmt.Errorf("creating backup directory: %w", err)
+	}
+	for name, buf := range map[string][]byte{journalSnapshotFileName: snapshot, journalLogFileName: log} {
+		if buf == nil {
+			continue
+		}
+		if err := writeFileAtomic(filepath.Join(backup, name), buf); err != nil {
+			return nil, fmt.Errorf("writing backup: %w", err)
+		}
+	}
+	report.Backup = backup
+
+	// records up to the new snapshot are skipped, so the log could be left as is if the truncation fails
+	if err := j.writeSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := os.Truncate(logPath, 0); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("truncating log: %w", err)
+	}
+	return report, nil
+}
+
+// migrateSnapshot loads the snapshot after migrating its items.
+func (j *Journal) migrateSnapshot(buf []byte, m *migrator) error {
+	var snapshot struct {
+		Seq   uint64
+		Table struct {
+			NextID json.Number
+			Items  []map[string]interface{}
+		}
+	}
+	if err := decodeJSONKeepNumbers(buf, &snapshot); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+	for _, it := range snapshot.Table.Items {
+		if err := m.migrate(it); err != nil {
+			return err
+		}
+	}
+	migrated, err := json.Marshal(&snapshot.Table)
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if err := json.Unmarshal(migrated, j.table); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+	j.table.reindex()
+	j.seq = snapshot.Seq
+	return nil
+}
+
+// migrateJournalRecord decodes the record in line after migrating its items.
+func migrateJournalRecord(line []byte, m *migrator) (*journalRecord, error) {
+	buf, err := journalRecordPayload(line)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Seq     uint64
+		Op      journalOp
+		Item    map[string]interface{}   `json:",omitempty"`
+		Items   []map[string]interface{} `json:",omitempty"`
+		Removed []int64                  `json:",omitempty"`
+	}
+	if err := decodeJSONKeepNumbers(buf, &raw); err != nil {
+		return nil, fmt.Errorf("decoding record: %w", err)
+	}
+	for _, it := range append(raw.Items, raw.Item) {
+		if err := m.migrate(it); err != nil {
+			return nil, fmt.Errorf("record %d: %w", raw.Seq, err)
+		}
+	}
+	migrated, err := json.Marshal(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("encoding record: %w", err)
+	}
+	var rec journalRecord
+	if err := json.Unmarshal(migrated, &rec); err != nil {
+		return nil, fmt.Errorf("decoding record: %w", err)
+	}
+	return &rec, nil
+}
+
+// journalSchemaVersion returns the schema version of the Journal under path. A Journal without a snapshot is a new
+// one, unless it has a log which was written before schema versions were introduced.
+func journalSchemaVersion(path string) (int, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(path, journalSnapshotFileName))
+	if err == nil {
+		var snapshot struct{ Version int }
+		if err := json.Unmarshal(buf, &snapshot); err != nil {
+			return 0, fmt.Errorf("decoding snapshot: %w", err)
+		}
+		return jsonSchemaVersion(snapshot.Version), nil
+	}
+	if !os.IsNotExist(err) {
+		return 0, fmt.Errorf("reading snapshot: %w", err)
+	}
+	info, err := os.Stat(filepath.Join(path, journalLogFileName))
+	if err == nil && info.Size() > 0 {
+		return 1, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("checking log: %w", err)
+	}
+	return SchemaVersion, nil
+}
+
+// Close closes the log and releases the lock on the directory.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.log == nil {
+		return nil
+	}
+	err := j.log.Close()
+	j.log = nil
+	j.unlock()
+	return err
+}
+
+// SaveItem appends the item to the log, return its id.
+func (j *Journal) SaveItem(item *entity.Item) (int64, error) {
+	if item == nil {
+		return -1, ErrNilItem
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	touchItem(item)
+
+	// the ID and the revision are assigned before appending so that the record could be replayed as is
+	saved := copyItem(item)
+	if it := j.table.find(saved.ID); saved.ID > 0 && it != nil && isDeleted(it) {
+		return -1, ErrItemNotFound
+	}
+	if latest, err := j.table.itemByID(saved.ID); saved.ID > 0 && err == nil {
+		if saved.Revision != latest.Revision {
+			return -1, &ConflictError{ItemID: saved.ID, Revision: saved.Revision, Latest: latest.Revision}
+		}
+		saved.Revision++
+	} else {
+		saved.ID = j.table.NextID
+		saved.Revision = 1
+	}
+	if err := j.append(&journalRecord{Op: journalOpSaveItem, Item: saved}); err != nil {
+		return -1, err
+	}
+	item.ID, item.Revision = saved.ID, saved.Revision
+	return item.ID, nil
+}
+
+// RunInTx runs fn within a transaction, changes made through the Storage given to fn are appended as a single
+// record if fn succeeds or discarded otherwise. Other calls to the Journal block until the transaction ends, so fn
+// must not use the Journal directly.
+func (j *Journal) RunInTx(fn func(use.Storage) error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	changes, err := j.table.track(func() error {
+		return fn(&tableTx{j.table, &j.feed})
+	})
+	if err != nil {
+		return err
+	}
+	// the changes are made again by appending the record, so they are not kept if the record could not be appended
+	events := changes.events(j.table)
+	changes.rollback(j.table)
+	if len(events) == 0 {
+		return nil
+	}
+	rec := &journalRecord{Op: journalOpPutItems}
+	for _, e := range events {
+		if e.Type == use.ItemRemoved {
+			rec.Removed = append(rec.Removed, e.Item.ID)
+		} else {
+			rec.Items = append(rec.Items, e.Item)
+		}
+	}
+	return j.append(rec)
+}
+
+// DeleteItem moves the item of given ID and its descendants to the trash.
+func (j *Journal) DeleteItem(id int64) error {
+	return j.RunInTx(func(tx use.Storage) error {
+		return tx.DeleteItem(id)
+	})
+}
+
+// RestoreItem moves the item of given ID back from the trash.
+func (j *Journal) RestoreItem(id int64) error {
+	return j.RunInTx(func(tx use.Storage) error {
+		return tx.RestoreItem(id)
+	})
+}
+
+// EmptyTrash removes items in the trash, returns the number of removed items.
+func (j *Journal) EmptyTrash() (n int, err error) {
+	err = j.RunInTx(func(tx use.Storage) error {
+		n, err = tx.EmptyTrash()
+		return err
+	})
+	return n, err
+}
+
+// Subscribe returns a channel of changes made to items from now on, the channel is closed once cancel is called.
+func (j *Journal) Subscribe() (<-chan use.ItemEvent, func()) {
+	return j.feed.subscribe()
+}
+
+// GetItemsByParentID returns items of given parent.
+func (j *Journal) GetItemsByParentID(parentID int64) ([]*entity.Item, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.table.itemsByParentID(parentID), nil
+}
+
+// GetItemByID returns items of given ID.
+func (j *Journal) GetItemByID(id int64) (*entity.Item, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.table.itemByID(id)
+}
+
+// GetItemsDueBetween returns items due in [from, to) sorted by due, items of the same due are sorted by ID.
+func (j *Journal) GetItemsDueBetween(from, to time.Time) ([]*entity.Item, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.table.itemsDueBetween(from, to), nil
+}
+
+// append writes a record to the log and applies it to the state, the log is compacted if it grows too large.
+//
+// A record which fails to be written is truncated from the log, so that it is not followed by the next one. If the
+// log could not be truncated, no more records are appended.
+func (j *Journal) append(rec *journalRecord) error {
+	if j.log == nil {
+		return os.ErrClosed
+	}
+	if j.broken != nil {
+		return fmt.Errorf("journal refuses to append after a failure: %w", j.broken)
+	}
+	rec.Seq = j.seq + 1
+	line, err := encodeJournalRecord(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := j.log.Write(line); err != nil {
+		return j.discardAppend(fmt.Errorf("appending to journal: %w", err))
+	}
+	if err := j.log.Sync(); err != nil {
+		return j.discardAppend(fmt.Errorf("syncing journal: %w", err))
+	}
+	j.size += int64(len(line))
+	j.feed.publish(j.apply(rec)...)
+
+	if j.size >= j.CompactSize {
+		if err := j.compact(); err != nil && j.OnError != nil {
+			j.OnError(fmt.Errorf("compacting journal: %w", err))
+		}
+	}
+	return nil
+}
+
+// discardAppend truncates what a failed append has written, returns err of the append.
+func (j *Journal) discardAppend(err error) error {
+	if truncErr := j.truncateLog(j.size); truncErr != nil {
+		j.broken = truncErr
+	}
+	return err
+}
+
+// truncateLog truncates the log to given size and appends from there on. If the log is truncated but could not be
+// seeked, no more records are appended.
+func (j *Journal) truncateLog(size int64) error {
+	if err := j.log.Truncate(size); err != nil {
+		return fmt.Errorf("truncating log: %w", err)
+	}
+	if _, err := j.log.Seek(size, io.SeekStart); err != nil {
+		j.broken = fmt.Errorf("seeking log: %w", err)
+		return j.broken
+	}
+	j.size = size
+	return nil
+}
+
+// apply applies a record to the state, returns events of the changes.
+func (j *Journal) apply(rec *journalRecord) []use.ItemEvent {
+	var events []use.ItemEvent
+	put := func(it *entity.Item) {
+		if e, ok := itemEvent(j.table.find(it.ID), it); ok {
+			events = append(events, e)
+		}
+		j.table.putItem(it)
+	}
+	switch rec.Op {
+	case journalOpSaveItem:
+		put(rec.Item)
+	case journalOpIncreaseOrderAfter:
+		for _, it := range moveAfterSameRank(j.table, rec.Item) {
+			put(it)
+		}
+	case journalOpPutItems:
+		for _, it := range rec.Items {
+			put(it)
+		}
+		for _, id := range rec.Removed {
+			if it := j.table.removeItem(id); it != nil {
+				events = append(events, removedItemEvent(it))
+			}
+		}
+	}
+	j.seq = rec.Seq
+	return events
+}
+
+// moveAfterSameRank returns copies of siblings of item of the same rank with new ranks right after it, which is
+// what increasing orders after item did, as ranks migrated from orders are in the same order.
+func moveAfterSameRank(t *itemTable, item *entity.Item) []*entity.Item {
+	var same []*entity.Item
+	next := ""
+	for _, it := range t.itemsByParentID(item.ParentItemID) {
+		if it.ID == item.ID {
+			continue
+		}
+		if it.Rank == item.Rank {
+			same = append(same, it)
+		} else if it.Rank > item.Rank {
+			next = it.Rank
+			break
+		}
+	}
+	prev := item.Rank
+	for _, it := range same {
+		rank, ok := entity.RankBetween(prev, next)
+		if !ok {
+			break
+		}
+		it.Rank, prev = rank, rank
+		it.Revision++
+	}
+	return same
+}
+
+// compact writes the state to the snapshot and starts a new log.
+//
+// Records up to the snapshot are skipped on replay, so a crash between writing the snapshot and truncating the
+// log does not apply any record twice.
+func (j *Journal) compact() error {
+	if err := j.writeSnapshot(); err != nil {
+		return err
+	}
+	if err := j.truncateLog(0); err != nil {
+		return err
+	}
+	return j.log.Sync()
+}
+
+func (j *Journal) writeSnapshot() error {
+	buf, err := json.Marshal(&journalSnapshot{Version: SchemaVersion, Seq: j.seq, Table: j.table})
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(j.path, journalSnapshotFileName), buf); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// open loads the snapshot and replays the log on top of it.
+// An incomplete last record, which is left by a crash during appending, is discarded.
+func (j *Journal) open() error {
+	buf, err := ioutil.ReadFile(filepath.Join(j.path, journalSnapshotFileName))
+	switch {
+	case os.IsNotExist(err):
+		version, err := journalSchemaVersion(j.path)
+		if err != nil {
+			return err
+		}
+		if err := checkSchemaVersion(version); err != nil {
+			return err
+		}
+		// a new journal, it is stamped with the version right away
+		if err := j.writeSnapshot(); err != nil {
+			return err
+		}
+	case err != nil:
+		return fmt.Errorf("reading snapshot: %w", err)
+	default:
+		snapshot := &journalSnapshot{Table: j.table}
+		if err := json.Unmarshal(buf, snapshot); err != nil {
+			return fmt.Errorf("decoding snapshot: %w", err)
+		}
+		if err := checkSchemaVersion(jsonSchemaVersion(snapshot.Version)); err != nil {
+			return err
+		}
+		j.table.reindex()
+		j.seq = snapshot.Seq
+	}
+
+	log, err := os.OpenFile(filepath.Join(j.path, journalLogFileName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("opening log: %w", err)
+	}
+	valid, err := j.replay(log, decodeJournalRecord)
+	if err != nil {
+		log.Close()
+		return err
+	}
+	// drop the torn write, if any, so that new records are appended right after the last valid one
+	j.log = log
+	if err := j.truncateLog(valid); err != nil {
+		log.Close()
+		j.log = nil
+		return err
+	}
+	return nil
+}
+
+// replay applies all valid records read from r with decode, returns the size of the valid part.
+func (j *Journal) replay(r io.Reader, decode func(line []byte) (*journalRecord, error)) (int64, error) {
+	var valid int64
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// a line without the ending newline is a torn write
+			return valid, nil
+		}
+		if err != nil {
+			return valid, fmt.Errorf("reading log: %w", err)
+		}
+		rec, err := decode(line)
+		if err != nil {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				// the last record is torn
+				return valid, nil
+			}
+			return valid, fmt.Errorf("%w: record at offset %d: %v", ErrCorruptedJournal, valid, err)
+		}
+		valid += int64(len(line))
+		if rec.Seq <= j.seq {
+			// already in the snapshot
+			continue
+		}
+		j.apply(rec)
+	}
+}
+
+// encodeJournalRecord encodes a record as a line of its checksum and its JSON form.
+func encodeJournalRecord(rec *journalRecord) ([]byte, error) {
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("encoding record: %w", err)
+	}
+	return encodeJournalPayload(buf), nil
+}
+
+func encodeJournalPayload(buf []byte) []byte {
+	line := strconv.AppendUint(nil, uint64(crc32.ChecksumIEEE(buf)), 16)
+	line = append(line, ' ')
+	line = append(line, buf...)
+	return append(line, '\n')
+}
+
+var errJournalChecksum = errors.New("checksum mismatch")
+
+// journalRecordPayload returns the JSON form of the record in line after verifying its checksum.
+func journalRecordPayload(line []byte) ([]byte, error) {
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	sep := bytes.IndexByte(line, ' ')
+	if sep < 0 {
+		return nil, errJournalChecksum
+	}
+	sum, err := strconv.ParseUint(string(line[:sep]), 16, 32)
+	if err != nil {
+		return nil, errJournalChecksum
+	}
+	buf := line[sep+1:]
+	if uint32(sum) != crc32.ChecksumIEEE(buf) {
+		return nil, errJournalChecksum
+	}
+	return buf, nil
+}
+
+func decodeJournalRecord(line []byte) (*journalRecord, error) {
+	buf, err := journalRecordPayload(line)
+	if err != nil {
+		return nil, err
+	}
+	var rec journalRecord
+	if err := json.Unmarshal(buf, &rec); err != nil {
+		return nil, fmt.Errorf("decoding record: %w", err)
+	}
+	if rec.Item == nil && rec.Op != journalOpPutItems {
+		return nil, fmt.Errorf("decoding record: %w", ErrNilItem)
+	}
+	return &rec, nil
+}
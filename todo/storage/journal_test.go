@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+func openTestingJournal(t *testing.T, dir string) *Journal {
+	j, err := NewJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func assertSameItems(t *testing.T, expected, actual *Journal) {
+	assert.Equal(t, expected.table.NextID, actual.table.NextID)
+	assert.ElementsMatch(t, expected.table.Items, actual.table.Items)
+}
+
+func TestJournalReplaysOnOpen(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	items := addTestingItems(t, j)
+	items[0].Rank = "z"
+	_, err := j.SaveItem(items[0])
+	assert.NoError(t, err)
+	items[2].State = entity.ItemStateCompleted
+	_, err = j.SaveItem(items[2])
+	assert.NoError(t, err)
+	assert.NoError(t, j.Close())
+
+	reopened := openTestingJournal(t, dir)
+	defer reopened.Close()
+	assertSameItems(t, j, reopened)
+
+	got, err := reopened.GetItemByID(items[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "z", got.Rank)
+	got, err = reopened.GetItemByID(items[2].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ItemStateCompleted, got.State)
+}
+
+func TestJournalReplaysTransactions(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	err := j.RunInTx(func(tx use.Storage) error {
+		items := addTestingItems(t, tx)
+		items[0].Rank = "z"
+		_, err := tx.SaveItem(items[0])
+		return err
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, j.Close())
+
+	// the whole transaction is a single record
+	log, err := ioutil.ReadFile(filepath.Join(dir, journalLogFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(log, []byte{'\n'}))
+
+	reopened := openTestingJournal(t, dir)
+	defer reopened.Close()
+	assertSameItems(t, j, reopened)
+}
+
+func TestJournalReplaysTrash(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	items := addTestingItems(t, j)
+	assert.NoError(t, j.DeleteItem(items[0].ID))
+	assert.NoError(t, j.DeleteItem(items[3].ID))
+	assert.NoError(t, j.RestoreItem(items[3].ID))
+	n, err := j.EmptyTrash()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Len(t, j.table.Items, 2)
+	assert.NoError(t, j.Close())
+
+	reopened := openTestingJournal(t, dir)
+	defer reopened.Close()
+	assertSameItems(t, j, reopened)
+}
+
+func TestJournalRecoversFromTornWrite(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	addTestingItems(t, j)
+	assert.NoError(t, j.Close())
+
+	logPath := filepath.Join(dir, journalLogFileName)
+	intact, err := ioutil.ReadFile(logPath)
+	assert.NoError(t, err)
+
+	for _, torn := range []string{
+		`1234`,
+		`1234 {"Seq": 5, "Op": "save_item", "Ite`,
+		"1234 {\"Seq\": 5, \"Op\": \"save_item\", \"Item\": {}}\n",
+	} {
+		assert.NoError(t, ioutil.WriteFile(logPath, append(intact, torn...), 0600))
+
+		reopened := openTestingJournal(t, dir)
+		assertSameItems(t, j, reopened)
+		// new records must be appended after the last intact one
+		_, err = reopened.SaveItem(&entity.Item{Title: "after recovery"})
+		assert.NoError(t, err)
+		assert.NoError(t, reopened.Close())
+
+		reopened = openTestingJournal(t, dir)
+		items, err := reopened.GetItemsByParentID(entity.RootID)
+		assert.NoError(t, err)
+		assert.Len(t, items, 3)
+		assert.NoError(t, reopened.Close())
+
+		assert.NoError(t, ioutil.WriteFile(logPath, intact, 0600))
+	}
+}
+
+func TestJournalCorruptedInTheMiddle(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	addTestingItems(t, j)
+	assert.NoError(t, j.Close())
+
+	logPath := filepath.Join(dir, journalLogFileName)
+	buf, err := ioutil.ReadFile(logPath)
+	assert.NoError(t, err)
+	buf[len(buf)/2]++
+	assert.NoError(t, ioutil.WriteFile(logPath, buf, 0600))
+
+	_, err = NewJournal(dir)
+	assert.True(t, errors.Is(err, ErrCorruptedJournal))
+}
+
+func TestJournalCompacts(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	j.CompactSize = 1024
+	for i := 0; i < 20; i++ {
+		addTestingItems(t, j)
+	}
+	assert.NoError(t, j.Close())
+
+	info, err := os.Stat(filepath.Join(dir, journalLogFileName))
+	assert.NoError(t, err)
+	assert.True(t, info.Size() < 1024)
+	_, err = os.Stat(filepath.Join(dir, journalSnapshotFileName))
+	assert.NoError(t, err)
+
+	reopened := openTestingJournal(t, dir)
+	defer reopened.Close()
+	assertSameItems(t, j, reopened)
+}
+
+func TestJournalSkipsRecordsInSnapshot(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	items := addTestingItems(t, j)
+	items[0].Rank = "z"
+	_, err := j.SaveItem(items[0])
+	assert.NoError(t, err)
+	logPath := filepath.Join(dir, journalLogFileName)
+	log, err := ioutil.ReadFile(logPath)
+	assert.NoError(t, err)
+
+	// simulate a crash after the snapshot is written but before the log is truncated
+	j.CompactSize = 0
+	_, err = j.SaveItem(&entity.Item{Title: "triggers compaction"})
+	assert.NoError(t, err)
+	assert.NoError(t, j.Close())
+	assert.NoError(t, ioutil.WriteFile(logPath, log, 0600))
+
+	reopened := openTestingJournal(t, dir)
+	defer reopened.Close()
+	assertSameItems(t, j, reopened)
+}
+
+// failingLog fails to write, writing half of a record, or to truncate while it is told to.
+type failingLog struct {
+	journalLog
+	failWrite, failTruncate bool
+}
+
+func (l *failingLog) Write(p []byte) (int, error) {
+	if l.failWrite {
+		n, _ := l.journalLog.Write(p[:len(p)/2])
+		return n, errors.New("disk full")
+	}
+	return l.journalLog.Write(p)
+}
+
+func (l *failingLog) Truncate(size int64) error {
+	if l.failTruncate {
+		return errors.New("read-only file system")
+	}
+	return l.journalLog.Truncate(size)
+}
+
+func TestJournalDiscardsFailedAppends(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	addTestingItems(t, j)
+	log := &failingLog{journalLog: j.log, failWrite: true}
+	j.log = log
+	_, err := j.SaveItem(&entity.Item{Title: "lost"})
+	assert.Error(t, err)
+
+	// the half written record is not followed by the next one
+	log.failWrite = false
+	_, err = j.SaveItem(&entity.Item{Title: "kept"})
+	assert.NoError(t, err)
+	assert.NoError(t, j.Close())
+	reopened := openTestingJournal(t, dir)
+	assertSameItems(t, j, reopened)
+
+	// no more records are appended if the half written one could not be truncated
+	log = &failingLog{journalLog: reopened.log, failWrite: true, failTruncate: true}
+	reopened.log = log
+	_, err = reopened.SaveItem(&entity.Item{Title: "lost"})
+	assert.Error(t, err)
+	log.failWrite, log.failTruncate = false, false
+	_, err = reopened.SaveItem(&entity.Item{Title: "refused"})
+	assert.Error(t, err)
+	assert.NoError(t, reopened.Close())
+	reopened = openTestingJournal(t, dir)
+	defer reopened.Close()
+	assertSameItems(t, j, reopened)
+}
+
+func TestJournalRetriesCompaction(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	defer j.Close()
+	var errs []error
+	j.OnError = func(err error) { errs = append(errs, err) }
+	log := &failingLog{journalLog: j.log, failTruncate: true}
+	j.log = log
+	j.CompactSize = 0
+
+	// the change is committed though the compaction fails
+	id, err := j.SaveItem(&entity.Item{Title: "committed"})
+	assert.NoError(t, err)
+	_, err = j.GetItemByID(id)
+	assert.NoError(t, err)
+	assert.Len(t, errs, 1)
+	assert.NotZero(t, j.size)
+
+	log.failTruncate = false
+	_, err = j.SaveItem(&entity.Item{Title: "compacted"})
+	assert.NoError(t, err)
+	assert.Len(t, errs, 1)
+	assert.Zero(t, j.size)
+}
+
+func TestJournalClosed(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	assert.NoError(t, j.Close())
+	assert.NoError(t, j.Close())
+	_, err := j.SaveItem(&entity.Item{})
+	assert.True(t, errors.Is(err, os.ErrClosed))
+}
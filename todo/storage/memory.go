@@ -2,29 +2,48 @@ package storage
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"sort"
+	"sync"
 	"time"
 
 	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
 )
 
-// Errors
+// Errors of use.Storage, see package use.
 var (
-	ErrNilItem      = errors.New("Item could not be nil")
-	ErrItemNotFound = errors.New("Item not found")
+	ErrNilItem        = use.ErrNilItem
+	ErrItemNotFound   = use.ErrItemNotFound
+	ErrItemNotDeleted = use.ErrItemNotDeleted
+	ErrParentDeleted  = use.ErrParentDeleted
 )
 
-// Memory is a memory based volatile storage.
+// ConflictError is returned when an item is saved based on a stale revision, it wraps use.ErrConflict.
+type ConflictError struct {
+	ItemID   int64
+	Revision uint64
+	Latest   uint64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: item[%d] is at revision %d, saving revision %d", use.ErrConflict, e.ItemID, e.Latest, e.Revision)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return use.ErrConflict
+}
+
+// Memory is a memory based volatile storage, it is safe for concurrent use.
+// Items are copied on both read and write, so no pointer is shared between the storage and its callers.
 type Memory struct {
-	id    int64
-	items []*entity.Item
+	mu    sync.RWMutex
+	items *itemTable
+	feed  feed
 }
 
 // NewMemory creates a Memory.
 func NewMemory() *Memory {
-	return &Memory{1, make([]*entity.Item, 0)}
+	return &Memory{items: newItemTable()}
 }
 
 // SaveItem saves an item into memory, return its id.
@@ -32,71 +51,87 @@ func (m *Memory) SaveItem(item *entity.Item) (int64, error) {
 	if item == nil {
 		return -1, ErrNilItem
 	}
-	// update timestamps
-	now := time.Now().UTC()
-	if item.CreatedAt.IsZero() {
-		item.CreatedAt = now
-	}
-	item.UpdatedAt = now
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
+	touchItem(item)
+	var old *entity.Item
 	if item.ID > 0 {
-		for i, it := range m.items {
-			if it.ID == item.ID {
-				m.items[i] = copyItem(item)
-				return item.ID, nil
-			}
-		}
+		// the item in the table is replaced rather than changed by saveItem
+		old = m.items.find(item.ID)
+	}
+	if err := m.items.saveItem(item); err != nil {
+		return -1, err
 	}
+	if e, ok := itemEvent(old, item); ok {
+		m.feed.publish(e)
+	}
+	return item.ID, nil
+}
 
-	// item does not exist
-	item.ID += m.id
-	m.items = append(m.items, copyItem(item))
-	m.id++
+// DeleteItem moves the item of given ID and its descendants to the trash.
+func (m *Memory) DeleteItem(id int64) error {
+	return m.RunInTx(func(tx use.Storage) error {
+		return tx.DeleteItem(id)
+	})
+}
 
-	return item.ID, nil
+// RestoreItem moves the item of given ID back from the trash.
+func (m *Memory) RestoreItem(id int64) error {
+	return m.RunInTx(func(tx use.Storage) error {
+		return tx.RestoreItem(id)
+	})
 }
 
-// IncreaseOrderAfter increases order by one for items after given one.
-func (m *Memory) IncreaseOrderAfter(item *entity.Item) error {
-	items, _ := m.GetItemsByParentID(item.ParentItemID)
-	for _, it := range items {
-		if it.ID == item.ID {
-			continue
-		}
-		if it.Order >= item.Order {
-			it.Order++
-		}
-	}
-	return nil
+// EmptyTrash removes items in the trash, returns the number of removed items.
+func (m *Memory) EmptyTrash() (n int, err error) {
+	err = m.RunInTx(func(tx use.Storage) error {
+		n, err = tx.EmptyTrash()
+		return err
+	})
+	return n, err
 }
 
 // GetItemsByParentID returns items of given parent.
 func (m *Memory) GetItemsByParentID(parentID int64) ([]*entity.Item, error) {
-	items := []*entity.Item{}
-	for _, it := range m.items {
-		if it.ParentItemID == parentID {
-			items = append(items, it)
-		}
-	}
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].Order < items[j].Order
-	})
-	return items, nil
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.items.itemsByParentID(parentID), nil
 }
 
 // GetItemByID returns items of given ID.
 func (m *Memory) GetItemByID(id int64) (*entity.Item, error) {
-	if id == entity.RootID {
-		return entity.RootItem, nil
-	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.items.itemByID(id)
+}
 
-	for _, it := range m.items {
-		if it.ID == id {
-			return it, nil
-		}
+// GetItemsDueBetween returns items due in [from, to) sorted by due, items of the same due are sorted by ID.
+func (m *Memory) GetItemsDueBetween(from, to time.Time) ([]*entity.Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.items.itemsDueBetween(from, to), nil
+}
+
+// RunInTx runs fn within a transaction, changes made through the Storage given to fn are discarded if fn returns an
+// error. Other calls to the Memory block until the transaction ends, so fn must not use the Memory directly.
+func (m *Memory) RunInTx(fn func(use.Storage) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changes, err := m.items.track(func() error {
+		return fn(&tableTx{m.items, &m.feed})
+	})
+	if err != nil {
+		return err
 	}
+	m.feed.publish(changes.events(m.items)...)
+	return nil
+}
 
-	return nil, ErrItemNotFound
+// Subscribe returns a channel of changes made to items from now on, the channel is closed once cancel is called.
+func (m *Memory) Subscribe() (<-chan use.ItemEvent, func()) {
+	return m.feed.subscribe()
 }
 
 func copyItem(it *entity.Item) *entity.Item {
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+func TestMemoryGetItemsDueBetween(t *testing.T) {
+	t.Parallel()
+	m := NewMemory()
+	items := addTestingItems(t, m)
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i, due := range []time.Time{day.Add(time.Hour), day, day.Add(24 * time.Hour)} {
+		items[i].Due = due
+		_, err := m.SaveItem(items[i])
+		assert.NoError(t, err)
+	}
+
+	due, err := m.GetItemsDueBetween(day, day.Add(24*time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, due, 2) {
+		assert.Equal(t, items[1].ID, due[0].ID)
+		assert.Equal(t, items[0].ID, due[1].ID)
+	}
+
+	// items in the trash are not due
+	assert.NoError(t, m.DeleteItem(items[1].ID))
+	due, err = m.GetItemsDueBetween(day, day.Add(24*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+	// neither are their descendants
+	assert.NoError(t, m.DeleteItem(items[0].ID))
+	due, err = m.GetItemsDueBetween(time.Time{}, day.AddDate(1, 0, 0))
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+// benchmarkSizes are the numbers of items in benchmarks, lookups should take about the same time at every size.
+var benchmarkSizes = []int{1000, 10000, 100000}
+
+// benchmarkStart is the due of the first item in benchmarks, items are due a minute apart.
+var benchmarkStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newBenchmarkMemory creates a Memory of n items, where every tenth item is at the top level and the rest are
+// its children.
+func newBenchmarkMemory(b *testing.B, n int) *Memory {
+	m := NewMemory()
+	err := m.RunInTx(func(tx use.Storage) error {
+		ranks := entity.SpreadRanks(10)
+		var parentID int64
+		for i := 0; i < n; i++ {
+			item := &entity.Item{Title: fmt.Sprint(i), Rank: ranks[i%10], ParentItemID: parentID}
+			if i%10 == 0 {
+				item.ParentItemID = entity.RootID
+			}
+			item.Due = benchmarkStart.Add(time.Duration(i) * time.Minute)
+			if _, err := tx.SaveItem(item); err != nil {
+				return err
+			}
+			if i%10 == 0 {
+				parentID = item.ID
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return m
+}
+
+func benchmarkMemory(b *testing.B, fn func(b *testing.B, m *Memory, n int)) {
+	for _, n := range benchmarkSizes {
+		m := newBenchmarkMemory(b, n)
+		b.Run(fmt.Sprintf("items=%d", n), func(b *testing.B) {
+			fn(b, m, n)
+		})
+	}
+}
+
+func BenchmarkMemoryGetItemByID(b *testing.B) {
+	benchmarkMemory(b, func(b *testing.B, m *Memory, n int) {
+		for i := 0; i < b.N; i++ {
+			if _, err := m.GetItemByID(int64(i%n) + 1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMemoryGetItemsByParentID(b *testing.B) {
+	benchmarkMemory(b, func(b *testing.B, m *Memory, n int) {
+		for i := 0; i < b.N; i++ {
+			items, err := m.GetItemsByParentID(int64(i%n/10*10) + 1)
+			if err != nil || len(items) != 9 {
+				b.Fatal(len(items), err)
+			}
+		}
+	})
+}
+
+func BenchmarkMemoryGetItemsDueBetween(b *testing.B) {
+	benchmarkMemory(b, func(b *testing.B, m *Memory, n int) {
+		for i := 0; i < b.N; i++ {
+			from := benchmarkStart.Add(time.Duration(i%(n-10)) * time.Minute)
+			items, err := m.GetItemsDueBetween(from, from.Add(10*time.Minute))
+			if err != nil || len(items) != 10 {
+				b.Fatal(len(items), err)
+			}
+		}
+	})
+}
+
+func BenchmarkMemorySaveItem(b *testing.B) {
+	benchmarkMemory(b, func(b *testing.B, m *Memory, n int) {
+		for i := 0; i < b.N; i++ {
+			item, err := m.GetItemByID(int64(i%n) + 1)
+			if err != nil {
+				b.Fatal(err)
+			}
+			item.Due = item.Due.Add(time.Second)
+			if _, err := m.SaveItem(item); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkMemoryRunInTx changes the state of an item among its siblings within a transaction, the way use cases
+// change items, it should take about the same time at every size.
+func BenchmarkMemoryRunInTx(b *testing.B) {
+	benchmarkMemory(b, func(b *testing.B, m *Memory, n int) {
+		for i := 0; i < b.N; i++ {
+			err := m.RunInTx(func(tx use.Storage) error {
+				// one of the children rather than the top level items, which have n/10 siblings
+				item, err := tx.GetItemByID(int64(i%n/10*10) + 2)
+				if err != nil {
+					return err
+				}
+				if _, err := tx.GetItemsByParentID(item.ParentItemID); err != nil {
+					return err
+				}
+				item.State = entity.ItemStateCompleted
+				if i%2 == 1 {
+					item.State = entity.ItemStateNormal
+				}
+				_, err = tx.SaveItem(item)
+				return err
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the schema written by storages which have their own schema, i.e. FileSystem and
+// Journal. Data written before schema versions were introduced is of version 1.
+//
+// Storages of other formats, e.g. TodoTxt, follow the format rather than a schema of their own, so they are not
+// versioned.
+const SchemaVersion = 3
+
+// Errors of schema versions.
+var (
+	ErrOutdatedSchema = errors.New("Data is of an outdated schema and has to be migrated")
+	ErrUnknownSchema  = errors.New("Data is of a schema newer than known")
+)
+
+// errMigration is returned when a migration fails, which tells it from failures of reading the data.
+var errMigration = errors.New("migration failed")
+
+// migration upgrades an item of the schema version before version to version.
+type migration struct {
+	version     int
+	description string
+	// migrate changes the item, which is decoded from JSON with numbers kept as json.Number, in place.
+	// It returns whether the item has been changed.
+	migrate func(item map[string]interface{}) (bool, error)
+}
+
+// migrations are all migrations in the order of versions, the last one is to SchemaVersion.
+var migrations = []migration{
+	{2, "Set CompletedAt of completed items, which was never set, to their UpdatedAt", migrateCompletedAt},
+	{3, "Replace Order of items with Rank", migrateOrderToRank},
+}
+
+// MigrationReport describes a migration of the stored data.
+type MigrationReport struct {
+	// From and To are the schema versions before and after the migration, they are the same if nothing has to be
+	// migrated.
+	From, To int
+	Steps    []MigrationStep
+	// Backup is the path of the backup of the data before the migration, it is empty if nothing has been written.
+	Backup string
+}
+
+// MigrationStep is a migration from the version before Version to Version.
+type MigrationStep struct {
+	Version     int
+	Description string
+	// ItemIDs are IDs of items changed by this step in ascending order.
+	ItemIDs []int64
+}
+
+func (r *MigrationReport) String() string {
+	if r.From == r.To {
+		return fmt.Sprintf("Schema is up to date at version %d", r.To)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Schema migrates from version %d to %d", r.From, r.To)
+	for _, s := range r.Steps {
+		fmt.Fprintf(&b, "\n  %d: %s, %d items changed", s.Version, s.Description, len(s.ItemIDs))
+	}
+	if r.Backup != "" {
+		fmt.Fprintf(&b, "\nBackup is written to %s", r.Backup)
+	}
+	return b.String()
+}
+
+// checkSchemaVersion returns an error if data of version could not be used as is.
+func checkSchemaVersion(version int) error {
+	switch {
+	case version < SchemaVersion:
+		return fmt.Errorf("%w: version %d, current version %d", ErrOutdatedSchema, version, SchemaVersion)
+	case version > SchemaVersion:
+		return fmt.Errorf("%w: version %d, known version %d", ErrUnknownSchema, version, SchemaVersion)
+	}
+	return nil
+}
+
+// migrator runs migrations after a version on items one by one, and records what are changed.
+type migrator struct {
+	from int
+	// changed holds IDs of changed items of each migration to run
+	changed []map[int64]bool
+}
+
+func newMigrator(from int) (*migrator, error) {
+	if from > SchemaVersion {
+		return nil, checkSchemaVersion(from)
+	}
+	m := &migrator{from: from}
+	for _, mig := range migrations {
+		if mig.version > from {
+			m.changed = append(m.changed, make(map[int64]bool))
+		}
+	}
+	return m, nil
+}
+
+func (m *migrator) migrate(item map[string]interface{}) error {
+	if item == nil {
+		return nil
+	}
+	var i int
+	for _, mig := range migrations {
+		if mig.version <= m.from {
+			continue
+		}
+		changed, err := mig.migrate(item)
+		if err != nil {
+			return fmt.Errorf("%w: to version %d: %v", errMigration, mig.version, err)
+		}
+		if changed {
+			id, _ := item["ID"].(json.Number)
+			n, _ := id.Int64()
+			m.changed[i][n] = true
+		}
+		i++
+	}
+	return nil
+}
+
+func (m *migrator) report() *MigrationReport {
+	r := &MigrationReport{From: m.from, To: SchemaVersion}
+	var i int
+	for _, mig := range migrations {
+		if mig.version <= m.from {
+			continue
+		}
+		step := MigrationStep{Version: mig.version, Description: mig.description, ItemIDs: []int64{}}
+		for id := range m.changed[i] {
+			step.ItemIDs = append(step.ItemIDs, id)
+		}
+		sort.Slice(step.ItemIDs, func(a, b int) bool {
+			return step.ItemIDs[a] < step.ItemIDs[b]
+		})
+		r.Steps = append(r.Steps, step)
+		i++
+	}
+	return r
+}
+
+// decodeJSONKeepNumbers decodes JSON into v with numbers kept as json.Number, so that int64 IDs are not rounded.
+func decodeJSONKeepNumbers(buf []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(buf))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// backupFileName returns the name of the backup of given file at given schema version.
+func backupFileName(name string, version int) string {
+	return fmt.Sprintf("%s.v%d-%s.bak", name, version, time.Now().UTC().Format("20060102T150405"))
+}
+
+func migrateCompletedAt(item map[string]interface{}) (bool, error) {
+	// 1 is ItemStateCompleted, migrations stick to the values at the time they were written
+	state, _ := item["State"].(json.Number)
+	completedAt, _ := item["CompletedAt"].(string)
+	if state.String() != "1" || (completedAt != "" && completedAt != (time.Time{}).Format(time.RFC3339)) {
+		return false, nil
+	}
+	updatedAt, ok := item["UpdatedAt"].(string)
+	if !ok {
+		return false, fmt.Errorf("item[%v] has no UpdatedAt", item["ID"])
+	}
+	item["CompletedAt"] = updatedAt
+	return true, nil
+}
+
+func migrateOrderToRank(item map[string]interface{}) (bool, error) {
+	order, ok := item["Order"].(json.Number)
+	if !ok {
+		order = "0"
+	}
+	n, err := strconv.ParseUint(order.String(), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("item[%v] has an invalid Order: %v", item["ID"], err)
+	}
+	delete(item, "Order")
+	item["Rank"] = orderRank(n)
+	return true, nil
+}
+
+// orderRank returns the rank of an item at given order, ranks are in the same order as orders, those of the same
+// order are the same. It also reads the orders of text formats written before ranks.
+func orderRank(order uint64) string {
+	// base 36 numbers of a fixed width, which is enough for any uint64, with a trailing digit so that the rank never
+	// ends with 0
+	s := strconv.FormatUint(order, 36)
+	return strings.Repeat("0", 13-len(s)) + s + "i"
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+const (
+	testingV1Completed = `{"ID":1,"Title":"done","State":1,"CompletedAt":"0001-01-01T00:00:00Z",` +
+		`"UpdatedAt":"2020-01-02T03:04:05.000000006Z","Revision":2,"Order":2}`
+	testingV1Normal = `{"ID":2,"Title":"todo","State":0,"CompletedAt":"0001-01-01T00:00:00Z",` +
+		`"UpdatedAt":"2020-01-02T03:04:05Z","Revision":1,"Order":1}`
+	testingV1First = `{"ID":3,"Title":"first","State":0,"CompletedAt":"0001-01-01T00:00:00Z",` +
+		`"UpdatedAt":"2020-01-02T03:04:05Z","Revision":1,"Order":1}`
+)
+
+var testingV1CompletedAt = time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+func TestMigrationsAreInOrder(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+2, m.version)
+	}
+	assert.Equal(t, SchemaVersion, migrations[len(migrations)-1].version)
+}
+
+func TestFileSystemMigrates(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, fsDataFileName)
+	v1 := `{"NextID":3,"Items":[` + testingV1Completed + `,` + testingV1Normal + `]}`
+	assert.NoError(t, ioutil.WriteFile(file, []byte(v1), 0600))
+
+	_, err := NewFileSystem(dir).GetItemByID(1)
+	assert.True(t, errors.Is(err, ErrOutdatedSchema))
+
+	report, err := MigrateFileSystem(dir, true)
+	assert.NoError(t, err)
+	assert.Equal(t, &MigrationReport{From: 1, To: SchemaVersion, Steps: []MigrationStep{
+		{Version: 2, Description: migrations[0].description, ItemIDs: []int64{1}},
+		{Version: 3, Description: migrations[1].description, ItemIDs: []int64{1, 2}},
+	}}, report)
+	buf, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, v1, string(buf), "dry run must not change anything")
+
+	report, err = MigrateFileSystem(dir, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, report.Steps[0].ItemIDs)
+	backup, err := ioutil.ReadFile(report.Backup)
+	assert.NoError(t, err)
+	assert.Equal(t, v1, string(backup))
+
+	s := NewFileSystem(dir)
+	done, err := s.GetItemByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, testingV1CompletedAt, done.CompletedAt)
+	assert.Equal(t, uint64(2), done.Revision)
+	todo, err := s.GetItemByID(2)
+	assert.NoError(t, err)
+	assert.True(t, todo.CompletedAt.IsZero())
+	top, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 1}, []int64{top[0].ID, top[1].ID}, "ranks are in the order of orders")
+	id, err := s.SaveItem(&entity.Item{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), id)
+
+	// nothing to do once migrated
+	report, err = MigrateFileSystem(dir, false)
+	assert.NoError(t, err)
+	assert.Equal(t, &MigrationReport{From: SchemaVersion, To: SchemaVersion}, report)
+}
+
+func TestFileSystemRejectsUnknownSchema(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, fsDataFileName), []byte(`{"Version":99}`), 0600))
+
+	_, err := NewFileSystem(dir).GetItemByID(1)
+	assert.True(t, errors.Is(err, ErrUnknownSchema))
+	_, err = MigrateFileSystem(dir, false)
+	assert.True(t, errors.Is(err, ErrUnknownSchema))
+}
+
+func TestJournalMigrates(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// a version 1 journal has a snapshot without a version, or no snapshot at all
+	snapshot := `{"Seq":1,"Table":{"NextID":3,"Items":[` + testingV1Normal + `]}}`
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, journalSnapshotFileName), []byte(snapshot), 0600))
+	var log []byte
+	for _, rec := range []string{
+		`{"Seq":1,"Op":"save_item","Item":` + testingV1Normal + `}`,
+		`{"Seq":2,"Op":"put_items","Items":[` + testingV1Completed + `]}`,
+		// "first" is inserted before the item of the same order
+		`{"Seq":3,"Op":"save_item","Item":` + testingV1First + `}`,
+		`{"Seq":4,"Op":"increase_order_after","Item":` + testingV1First + `}`,
+	} {
+		log = append(log, encodeJournalPayload([]byte(rec))...)
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, journalLogFileName), log, 0600))
+
+	_, err := NewJournal(dir)
+	assert.True(t, errors.Is(err, ErrOutdatedSchema))
+
+	report, err := MigrateJournal(dir, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, report.Steps[0].ItemIDs)
+	assert.Empty(t, report.Backup)
+	_, err = NewJournal(dir)
+	assert.True(t, errors.Is(err, ErrOutdatedSchema), "dry run must not change anything")
+
+	report, err = MigrateJournal(dir, false)
+	assert.NoError(t, err)
+	backup, err := ioutil.ReadFile(filepath.Join(report.Backup, journalLogFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, log, backup)
+
+	j := openTestingJournal(t, dir)
+	defer j.Close()
+	done, err := j.GetItemByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, testingV1CompletedAt, done.CompletedAt)
+	todo, err := j.GetItemByID(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "todo", todo.Title)
+	var titles []string
+	top, err := j.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	for _, it := range top {
+		titles = append(titles, it.Title)
+	}
+	assert.Equal(t, []string{"first", "todo", "done"}, titles)
+}
+
+func TestNewJournalIsStamped(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j := openTestingJournal(t, dir)
+	addTestingItems(t, j)
+	assert.NoError(t, j.Close())
+
+	version, err := journalSchemaVersion(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+	report, err := MigrateJournal(dir, false)
+	assert.NoError(t, err)
+	assert.Equal(t, &MigrationReport{From: SchemaVersion, To: SchemaVersion}, report)
+}
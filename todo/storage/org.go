@@ -0,0 +1,606 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// Org is a storage that reads and writes an Emacs Org-mode(https://orgmode.org) file.
+//
+// Every item is a headline nested under the headline of its parent. Tasks are TODO or DONE headlines, so are other
+// items which are completed. Due is the DEADLINE, CompletedAt is CLOSED, Priority is the priority cookie, Estimate is
+// the EFFORT property and Description is the body, other fields are kept in the property drawer. Tags of items are
+// tags of headlines, other tags, unknown properties and other planning entries, e.g. SCHEDULED, are kept as they are,
+// so is the text before the first headline. Headlines without an ID are taken as new items, their IDs are written to
+// the file on the next change. A title which would be read as something else, e.g. one ending with a word like
+// tags, is escaped with a zero-width space as Org does.
+type Org struct {
+	fileStore
+}
+
+// NewOrg creates an Org with given file path, a lock file is created next to the file.
+func NewOrg(path string) *Org {
+	return &Org{fileStore{
+		file:     path,
+		lockFile: path + ".lock",
+		format:   orgFormat{},
+	}}
+}
+
+// Property keys known to Org, they are prefixed to stay clear of those used by Org itself, e.g. ID of org-id.
+// ITEM_DUE and ITEM_COMPLETED keep the precise DEADLINE and CLOSED as long as they are not changed in the file.
+// ITEM_PARENT is only written for items whose parent does not exist, which are written to the top level, as are
+// items in the trash, which have ITEM_DELETED.
+const (
+	orgKeyID          = "ITEM_ID"
+	orgKeyType        = "ITEM_TYPE"
+	orgKeyParent      = "ITEM_PARENT"
+	orgKeyRank        = "ITEM_RANK"
+	orgKeyRevision    = "ITEM_REVISION"
+	orgKeyCreated     = "ITEM_CREATED"
+	orgKeyUpdated     = "ITEM_UPDATED"
+	orgKeyDue         = "ITEM_DUE"
+	orgKeyRecurrence  = "ITEM_RRULE"
+	orgKeyReminders   = "ITEM_REMIND"
+	orgKeyCompleted   = "ITEM_COMPLETED"
+	orgKeyDeleted     = "ITEM_DELETED"
+	orgKeyDeletedWith = "ITEM_DELETED_WITH"
+	// orgKeyOrder is only read from files written before ranks, it is written as ITEM_RANK.
+	orgKeyOrder = "ITEM_ORDER"
+	// orgKeyEffort is the property of Org itself for estimates, in H:MM.
+	orgKeyEffort = "EFFORT"
+)
+
+const (
+	orgKeywordTodo = "TODO"
+	orgKeywordDone = "DONE"
+
+	orgPlanningDeadline = "DEADLINE"
+	orgPlanningClosed   = "CLOSED"
+
+	orgDrawerBegin = ":PROPERTIES:"
+	orgDrawerEnd   = ":END:"
+
+	// orgEscape is the zero-width space, which is put before a title starting with a keyword or a priority cookie
+	// and after a title ending with a word like tags.
+	orgEscape = "\u200b"
+
+	orgDateLayout = "2006-01-02 Mon"
+	orgTimeLayout = "2006-01-02 Mon 15:04"
+)
+
+var orgItemTypes = map[entity.ItemType]string{
+	entity.ItemTypeTask:     "task",
+	entity.ItemTypeProject:  "project",
+	entity.ItemTypeCategory: "category",
+	entity.ItemTypeView:     "view",
+}
+
+var (
+	orgPlanningPattern = regexp.MustCompile(`([A-Z]+):\s*([<\[][^>\]]*[>\]])`)
+	orgPropertyPattern = regexp.MustCompile(`^:([^:\s]+):(?:\s+(.*))?$`)
+	orgEffortPattern   = regexp.MustCompile(`^([0-9]+):([0-5][0-9])$`)
+	orgPriorityPattern = regexp.MustCompile(`^\[#([A-Z])\](?:\s|$)`)
+	orgTagsPattern     = regexp.MustCompile(`^(?:(.*?)\s+)?:([^\s:]+(?::[^\s:]+)*):$`)
+)
+
+// orgProperty is a property in the drawer of a headline.
+type orgProperty struct {
+	key, value string
+}
+
+// orgHeadline is a headline parsed from an Org file, along with what it has but an item does not.
+type orgHeadline struct {
+	level int
+	item  *entity.Item
+	// tags holds tags which are not valid tags of items, see entity.ParseTag
+	tags []string
+	// planning holds planning entries other than DEADLINE and CLOSED as they are
+	planning []string
+	// deadline is the DEADLINE as it is, which is written back as long as the due is not changed
+	deadline string
+	// priority is the letter of the priority cookie, see priorityLetter
+	priority   string
+	properties []orgProperty
+	// hasRank tells whether ITEM_RANK is in the drawer, the rank comes from the position among siblings otherwise
+	hasRank bool
+}
+
+// orgDocument is a parsed Org file.
+type orgDocument struct {
+	preamble  []string
+	headlines []*orgHeadline
+}
+
+type orgFormat struct{}
+
+func (orgFormat) decode(buf []byte) (*itemTable, error) {
+	doc, err := parseOrg(buf)
+	if err != nil {
+		return nil, err
+	}
+	t := newItemTable()
+	for _, h := range doc.headlines {
+		t.putItem(h.item)
+	}
+	return t, nil
+}
+
+// encode writes items as nested headlines in the order of their ranks, what is not known to items is taken from
+// the headline of the same ID in prev.
+func (orgFormat) encode(t *itemTable, prev []byte) ([]byte, error) {
+	doc, err := parseOrg(prev)
+	if err != nil {
+		return nil, err
+	}
+	headlines := make(map[int64]*orgHeadline, len(doc.headlines))
+	for _, h := range doc.headlines {
+		headlines[h.item.ID] = h
+	}
+
+	var buf bytes.Buffer
+	for _, line := range doc.preamble {
+		buf.WriteString(line + "\n")
+	}
+
+	seen := make(map[int64]bool, len(t.Items))
+	var write func(it *entity.Item, level int, orphan bool)
+	write = func(it *entity.Item, level int, orphan bool) {
+		seen[it.ID] = true
+		writeOrgHeadline(&buf, it, level, orphan, headlines[it.ID])
+		for _, child := range t.itemsByParentID(it.ID) {
+			if !seen[child.ID] {
+				write(child, level+1, false)
+			}
+		}
+	}
+	for _, it := range t.itemsByParentID(entity.RootID) {
+		write(it, 1, false)
+	}
+
+	// items which could not be reached from the root are written to the top level with their parents kept in
+	// properties, those whose parent does not exist go first, then those in a cycle
+	exists := make(map[int64]bool, len(t.Items))
+	for _, it := range t.Items {
+		exists[it.ID] = true
+	}
+	for _, orphanRootsOnly := range []bool{true, false} {
+		for _, it := range t.Items {
+			if !seen[it.ID] && !(orphanRootsOnly && exists[it.ParentItemID]) {
+				write(it, 1, true)
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func writeOrgHeadline(buf *bytes.Buffer, it *entity.Item, level int, orphan bool, prev *orgHeadline) {
+	if prev == nil {
+		prev = &orgHeadline{}
+	}
+
+	words := []string{strings.Repeat("*", level)}
+	switch {
+	case it.State == entity.ItemStateCompleted:
+		words = append(words, orgKeywordDone)
+	case it.Type == entity.ItemTypeTask:
+		words = append(words, orgKeywordTodo)
+	}
+	if p := priorityLetter(it, prev.priority); p != "" {
+		words = append(words, "[#"+p+"]")
+	}
+	if it.Title != "" {
+		words = append(words, escapeOrgTitle(it.Title))
+	}
+	if tags := append(append([]string(nil), it.Tags...), prev.tags...); len(tags) > 0 {
+		words = append(words, ":"+strings.Join(tags, ":")+":")
+	}
+	buf.WriteString(strings.Join(words, " ") + "\n")
+
+	var planning []string
+	if !it.Due.IsZero() {
+		deadline := prev.deadline
+		if due, err := parseOrgTimestamp(deadline); err != nil || !sameOrgTimestamp(due, it.Due) {
+			deadline = formatOrgTimestamp(it.Due, "<", ">")
+		}
+		planning = append(planning, orgPlanningDeadline+": "+deadline)
+	}
+	planning = append(planning, prev.planning...)
+	if !it.CompletedAt.IsZero() {
+		planning = append(planning, orgPlanningClosed+": "+formatOrgTimestamp(it.CompletedAt, "[", "]"))
+	}
+	if len(planning) > 0 {
+		buf.WriteString(strings.Join(planning, " ") + "\n")
+	}
+
+	buf.WriteString(orgDrawerBegin + "\n")
+	property := func(key, value string) {
+		buf.WriteString(":" + key + ":")
+		if value != "" {
+			buf.WriteString(" " + value)
+		}
+		buf.WriteString("\n")
+	}
+	property(orgKeyID, strconv.FormatInt(it.ID, 10))
+	if it.Type != entity.ItemTypeTask {
+		property(orgKeyType, orgItemTypes[it.Type])
+	}
+	if orphan {
+		property(orgKeyParent, strconv.FormatInt(it.ParentItemID, 10))
+	}
+	// an item without a rank is written as is, rather than ranked by its position when read
+	property(orgKeyRank, it.Rank)
+	property(orgKeyRevision, strconv.FormatUint(it.Revision, 10))
+	if !it.CreatedAt.IsZero() {
+		property(orgKeyCreated, it.CreatedAt.Format(time.RFC3339Nano))
+	}
+	if !it.UpdatedAt.IsZero() {
+		property(orgKeyUpdated, it.UpdatedAt.Format(time.RFC3339Nano))
+	}
+	if !it.Due.IsZero() {
+		property(orgKeyDue, it.Due.Format(time.RFC3339Nano))
+	}
+	if it.Recurrence != "" {
+		property(orgKeyRecurrence, it.Recurrence)
+	}
+	if len(it.Reminders) > 0 {
+		property(orgKeyReminders, formatReminders(it.Reminders))
+	}
+	if !it.CompletedAt.IsZero() {
+		property(orgKeyCompleted, it.CompletedAt.Format(time.RFC3339Nano))
+	}
+	if isDeleted(it) {
+		property(orgKeyDeleted, it.DeletedAt.Format(time.RFC3339Nano))
+		property(orgKeyDeletedWith, strconv.FormatInt(it.DeletedWithItemID, 10))
+	}
+	if it.Estimate > 0 {
+		property(orgKeyEffort, formatOrgEffort(it.Estimate))
+	}
+	for _, p := range prev.properties {
+		// an EFFORT which could not be read is replaced by the estimate
+		if it.Estimate > 0 && strings.EqualFold(p.key, orgKeyEffort) {
+			continue
+		}
+		property(p.key, p.value)
+	}
+	buf.WriteString(orgDrawerEnd + "\n")
+
+	if it.Description != "" {
+		for _, line := range strings.Split(it.Description, "\n") {
+			if isOrgHeadlineLine(strings.TrimLeft(line, ",")) {
+				line = "," + line
+			}
+			buf.WriteString(line + "\n")
+		}
+	}
+}
+
+// parseOrg parses headlines and assigns IDs, in the order of headlines, to those without one or with one which is
+// already taken by a previous headline, e.g. a copied headline.
+func parseOrg(buf []byte) (*orgDocument, error) {
+	doc := &orgDocument{}
+	// body holds the lines after the drawer of each headline
+	var body [][]string
+	// stage is where the next line of the last headline is expected to be: 0 for the planning, 1 for the drawer,
+	// 2 within the drawer and 3 for the body
+	var stage int
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(nil, len(buf)+1)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if isOrgHeadlineLine(line) {
+			doc.headlines = append(doc.headlines, parseOrgHeadline(line))
+			body = append(body, nil)
+			stage = 0
+			continue
+		}
+		if len(doc.headlines) == 0 {
+			doc.preamble = append(doc.preamble, line)
+			continue
+		}
+
+		h := doc.headlines[len(doc.headlines)-1]
+		text := strings.TrimSpace(line)
+		switch {
+		case stage == 0 && isOrgPlanningLine(text):
+			if err := parseOrgPlanning(h, text); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			stage = 1
+		case stage <= 1 && strings.EqualFold(text, orgDrawerBegin):
+			stage = 2
+		case stage == 2 && strings.EqualFold(text, orgDrawerEnd):
+			stage = 3
+		case stage == 2:
+			if err := parseOrgProperty(h, text); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		default:
+			stage = 3
+			if strings.HasPrefix(line, ",") && isOrgHeadlineLine(strings.TrimLeft(line, ",")) {
+				line = line[1:]
+			}
+			body[len(body)-1] = append(body[len(body)-1], line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if stage == 2 {
+		return nil, fmt.Errorf("unclosed property drawer of headline: %s", doc.headlines[len(doc.headlines)-1].item.Title)
+	}
+
+	var maxID int64
+	seen := make(map[int64]bool)
+	for i, h := range doc.headlines {
+		it := h.item
+		it.Description = strings.Join(body[i], "\n")
+		if seen[it.ID] {
+			it.ID = 0
+		}
+		seen[it.ID] = true
+		if it.ID > maxID {
+			maxID = it.ID
+		}
+	}
+	for _, h := range doc.headlines {
+		if h.item.ID == 0 {
+			maxID++
+			h.item.ID = maxID
+			h.item.Revision = 1
+		}
+	}
+	nestOrgHeadlines(doc.headlines)
+	return doc, nil
+}
+
+// nestOrgHeadlines sets the parent of each headline to the closest headline before it of a lower level, headlines
+// without ITEM_RANK are ranked by their positions among siblings.
+func nestOrgHeadlines(headlines []*orgHeadline) {
+	type parent struct {
+		h        *orgHeadline
+		children uint64
+	}
+	// parents holds the chain of parents of the current headline, the first one is the top level
+	parents := []*parent{{h: &orgHeadline{item: entity.RootItem}}}
+	for _, h := range headlines {
+		for len(parents) > 1 && parents[len(parents)-1].h.level >= h.level {
+			parents = parents[:len(parents)-1]
+		}
+		p := parents[len(parents)-1]
+		if len(parents) > 1 {
+			h.item.ParentItemID = p.h.item.ID
+		}
+		p.children++
+		if !h.hasRank {
+			h.item.Rank = orderRank(p.children)
+		}
+		parents = append(parents, &parent{h: h})
+	}
+}
+
+func isOrgHeadlineLine(line string) bool {
+	stars := len(line) - len(strings.TrimLeft(line, "*"))
+	return stars > 0 && (stars == len(line) || line[stars] == ' ')
+}
+
+func isOrgPlanningLine(text string) bool {
+	for _, keyword := range []string{orgPlanningDeadline, "SCHEDULED", orgPlanningClosed} {
+		if strings.HasPrefix(text, keyword+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseOrgHeadline(line string) *orgHeadline {
+	text := strings.TrimLeft(line, "*")
+	h := &orgHeadline{level: len(line) - len(text), item: &entity.Item{Type: entity.ItemTypeCategory}}
+	text = strings.TrimSpace(text)
+	it := h.item
+	if m := orgTagsPattern.FindStringSubmatch(text); m != nil {
+		text = m[1]
+		for _, tag := range strings.Split(m[2], ":") {
+			if _, err := entity.ParseTag(tag); err == nil {
+				it.Tags = append(it.Tags, tag)
+			} else {
+				h.tags = append(h.tags, tag)
+			}
+		}
+	}
+	for _, keyword := range []string{orgKeywordTodo, orgKeywordDone} {
+		if hasOrgKeyword(text, keyword) {
+			it.Type = entity.ItemTypeTask
+			if keyword == orgKeywordDone {
+				it.State = entity.ItemStateCompleted
+			}
+			text = strings.TrimSpace(text[len(keyword):])
+			break
+		}
+	}
+	if m := orgPriorityPattern.FindStringSubmatch(text); m != nil {
+		h.priority, it.Priority = m[1], letterPriority(m[1])
+		text = strings.TrimSpace(text[len(m[0]):])
+	}
+	it.Title = strings.TrimSuffix(strings.TrimPrefix(text, orgEscape), orgEscape)
+	return h
+}
+
+func hasOrgKeyword(text, keyword string) bool {
+	return text == keyword || strings.HasPrefix(text, keyword+" ")
+}
+
+// escapeOrgTitle escapes a title with orgEscape if it would be read as something else.
+func escapeOrgTitle(title string) string {
+	if strings.HasPrefix(title, orgEscape) || hasOrgKeyword(title, orgKeywordTodo) ||
+		hasOrgKeyword(title, orgKeywordDone) || orgPriorityPattern.MatchString(title) {
+		title = orgEscape + title
+	}
+	if strings.HasSuffix(title, orgEscape) || orgTagsPattern.MatchString(title) {
+		title += orgEscape
+	}
+	return title
+}
+
+// parseOrgPlanning reads DEADLINE and CLOSED from a planning line, other entries are kept as they are.
+func parseOrgPlanning(h *orgHeadline, text string) error {
+	for _, m := range orgPlanningPattern.FindAllStringSubmatch(text, -1) {
+		var err error
+		switch m[1] {
+		case orgPlanningDeadline:
+			h.deadline = m[2]
+			h.item.Due, err = parseOrgTimestamp(m[2])
+		case orgPlanningClosed:
+			h.item.CompletedAt, err = parseOrgTimestamp(m[2])
+		default:
+			h.planning = append(h.planning, m[0])
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", m[1], err)
+		}
+	}
+	return nil
+}
+
+// parseOrgProperty sets the field of a known property, unknown ones are kept as they are.
+// A precise time in ITEM_DUE or ITEM_COMPLETED is taken only if it is the same as the DEADLINE or CLOSED, which
+// has been read from the planning line before the drawer.
+func parseOrgProperty(h *orgHeadline, text string) error {
+	m := orgPropertyPattern.FindStringSubmatch(text)
+	if m == nil {
+		return fmt.Errorf("invalid property: %s", text)
+	}
+	key, value := m[1], strings.TrimSpace(m[2])
+	it := h.item
+	var err error
+	switch strings.ToUpper(key) {
+	case orgKeyID:
+		it.ID, err = strconv.ParseInt(value, 10, 64)
+	case orgKeyType:
+		if it.Type == entity.ItemTypeTask && it.State == entity.ItemStateNormal {
+			// a TODO headline is always a task
+			break
+		}
+		err = fmt.Errorf("unknown type: %s", value)
+		for t, name := range orgItemTypes {
+			if name == value {
+				it.Type, err = t, nil
+			}
+		}
+	case orgKeyParent:
+		if h.level == 1 {
+			it.ParentItemID, err = strconv.ParseInt(value, 10, 64)
+		}
+	case orgKeyRank:
+		it.Rank = value
+		if value != "" && !entity.ValidRank(value) {
+			err = fmt.Errorf("invalid rank: %s", value)
+		}
+		h.hasRank = err == nil
+	case orgKeyOrder:
+		var order uint64
+		order, err = strconv.ParseUint(value, 10, 64)
+		it.Rank = orderRank(order)
+		h.hasRank = err == nil
+	case orgKeyRevision:
+		it.Revision, err = strconv.ParseUint(value, 10, 64)
+	case orgKeyCreated:
+		it.CreatedAt, err = time.Parse(time.RFC3339Nano, value)
+	case orgKeyUpdated:
+		it.UpdatedAt, err = time.Parse(time.RFC3339Nano, value)
+	case orgKeyDue:
+		var due time.Time
+		if due, err = time.Parse(time.RFC3339Nano, value); err == nil && sameOrgTimestamp(due, it.Due) {
+			it.Due = due
+		}
+	case orgKeyRecurrence:
+		it.Recurrence = value
+	case orgKeyReminders:
+		it.Reminders, err = parseReminders(value)
+	case orgKeyCompleted:
+		var completed time.Time
+		if completed, err = time.Parse(time.RFC3339Nano, value); err == nil && sameOrgTimestamp(completed, it.CompletedAt) {
+			it.CompletedAt = completed
+		}
+	case orgKeyDeleted:
+		it.DeletedAt, err = time.Parse(time.RFC3339Nano, value)
+	case orgKeyDeletedWith:
+		it.DeletedWithItemID, err = strconv.ParseInt(value, 10, 64)
+	case orgKeyEffort:
+		var ok bool
+		if it.Estimate, ok = parseOrgEffort(value); !ok {
+			h.properties = append(h.properties, orgProperty{key, value})
+		}
+	default:
+		h.properties = append(h.properties, orgProperty{key, value})
+	}
+	if err != nil {
+		return fmt.Errorf("property %s: %w", key, err)
+	}
+	return nil
+}
+
+// parseOrgEffort parses an EFFORT in H:MM, e.g. 1:30, it returns false for other forms, e.g. 1d, which are kept as
+// they are.
+func parseOrgEffort(s string) (time.Duration, bool) {
+	m := orgEffortPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	hours, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	minutes, _ := strconv.Atoi(m[2])
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, true
+}
+
+// formatOrgEffort formats an estimate as an EFFORT in H:MM, it is rounded down to minutes.
+func formatOrgEffort(d time.Duration) string {
+	return fmt.Sprintf("%d:%02d", d/time.Hour, d%time.Hour/time.Minute)
+}
+
+// parseOrgTimestamp parses an active or inactive timestamp in local time, repeaters and warnings are ignored.
+func parseOrgTimestamp(s string) (time.Time, error) {
+	if len(s) < 2 {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %s", s)
+	}
+	fields := strings.Fields(s[1 : len(s)-1])
+	if len(fields) == 0 {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %s", s)
+	}
+	date, err := time.ParseInLocation("2006-01-02", fields[0], time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// the weekday is optional
+	for _, f := range fields[1:] {
+		if clock, err := time.Parse("15:04", f); err == nil {
+			return date.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute), nil
+		}
+	}
+	return date, nil
+}
+
+// formatOrgTimestamp formats a time in local time, the time of day is omitted if it is midnight.
+func formatOrgTimestamp(t time.Time, open, close string) string {
+	t = t.Local()
+	layout := orgTimeLayout
+	if t.Hour() == 0 && t.Minute() == 0 {
+		layout = orgDateLayout
+	}
+	return open + t.Format(layout) + close
+}
+
+// sameOrgTimestamp reports whether two times are written as the same timestamp.
+func sameOrgTimestamp(a, b time.Time) bool {
+	return formatOrgTimestamp(a, "", "") == formatOrgTimestamp(b, "", "")
+}
@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+const testingOrg = `#+TITLE: My tasks
+#+STARTUP: overview
+
+* Work                                                        :@home:office:
+** Release
+:PROPERTIES:
+:ITEM_TYPE: project
+:END:
+*** TODO Write changelog                                         :writing:
+DEADLINE: <2020-01-03 Fri 15:00 -1d> SCHEDULED: <2020-01-02 Thu>
+:PROPERTIES:
+:EFFORT:   1:00
+:END:
+Mention the new storages.
+,* This is not a headline
+*** DONE Tag the commit
+CLOSED: [2020-01-04 Sat 10:30]
+* TODO [#D] Call mom
+`
+
+func writeTestingOrg(t *testing.T, content string) (*Org, string) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "todo.org")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return NewOrg(path), dir
+}
+
+func TestOrgReadsFile(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOrg(t, testingOrg)
+	defer os.RemoveAll(dir)
+
+	top, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Len(t, top, 2)
+	assert.Equal(t, &entity.Item{ID: 1, Title: "Work", Type: entity.ItemTypeCategory, Rank: orderRank(1), Tags: []string{"office"}, Revision: 1}, top[0])
+	assert.Equal(t, &entity.Item{ID: 5, Title: "Call mom", Type: entity.ItemTypeTask, Rank: orderRank(2), Priority: entity.ItemPriorityLow, Revision: 1}, top[1])
+
+	release, err := s.GetItemByID(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "Release", release.Title)
+	assert.Equal(t, entity.ItemTypeProject, release.Type)
+	assert.Equal(t, int64(1), release.ParentItemID)
+
+	tasks, err := s.GetItemsByParentID(release.ID)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	changelog, tag := tasks[0], tasks[1]
+	assert.Equal(t, "Write changelog", changelog.Title)
+	assert.Equal(t, []string{"writing"}, changelog.Tags)
+	assert.Equal(t, time.Hour, changelog.Estimate)
+	assert.Equal(t, entity.ItemStateNormal, changelog.State)
+	assert.True(t, time.Date(2020, 1, 3, 15, 0, 0, 0, time.Local).Equal(changelog.Due))
+	assert.Equal(t, "Mention the new storages.\n* This is not a headline", changelog.Description)
+	assert.Equal(t, "Tag the commit", tag.Title)
+	assert.Equal(t, entity.ItemStateCompleted, tag.State)
+	assert.True(t, time.Date(2020, 1, 4, 10, 30, 0, 0, time.Local).Equal(tag.CompletedAt))
+}
+
+func TestOrgKeepsWhatItDoesNotUnderstand(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOrg(t, testingOrg)
+	defer os.RemoveAll(dir)
+
+	changelog, err := s.GetItemByID(3)
+	assert.NoError(t, err)
+	changelog.Title = "Write release notes"
+	_, err = s.SaveItem(changelog)
+	assert.NoError(t, err)
+
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	content := string(buf)
+	assert.True(t, strings.HasPrefix(content, "#+TITLE: My tasks\n#+STARTUP: overview\n\n* Work :office:@home:\n"))
+	assert.Contains(t, content, "\n*** TODO Write release notes :writing:\n"+
+		"DEADLINE: <2020-01-03 Fri 15:00 -1d> SCHEDULED: <2020-01-02 Thu>\n:PROPERTIES:\n:ITEM_ID: 3\n")
+	assert.Contains(t, content, ":EFFORT: 1:00\n:END:\nMention the new storages.\n,* This is not a headline\n")
+	assert.Contains(t, content, "\n*** DONE Tag the commit\nCLOSED: [2020-01-04 Sat 10:30]\n")
+	assert.Contains(t, content, "\n* TODO [#D] Call mom\n")
+
+	// everything reads back the same
+	reread := NewOrg(s.file)
+	for id := int64(1); id <= 5; id++ {
+		before, err := s.GetItemByID(id)
+		assert.NoError(t, err)
+		after, err := reread.GetItemByID(id)
+		assert.NoError(t, err)
+		assert.Equal(t, before, after)
+	}
+
+	// the deadline is rewritten once the due is changed
+	changelog, err = s.GetItemByID(3)
+	assert.NoError(t, err)
+	changelog.Due = time.Date(2020, 1, 5, 0, 0, 0, 0, time.Local)
+	_, err = s.SaveItem(changelog)
+	assert.NoError(t, err)
+	buf, err = ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(buf), "\nDEADLINE: <2020-01-05 Sun> SCHEDULED: <2020-01-02 Thu>\n")
+}
+
+func TestOrgPriority(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOrg(t, testingOrg)
+	defer os.RemoveAll(dir)
+
+	call, err := s.GetItemByID(5)
+	assert.NoError(t, err)
+	call.Priority = entity.ItemPriorityHigh
+	_, err = s.SaveItem(call)
+	assert.NoError(t, err)
+	work, err := s.GetItemByID(1)
+	assert.NoError(t, err)
+	work.Priority = entity.ItemPriorityMedium
+	_, err = s.SaveItem(work)
+	assert.NoError(t, err)
+
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(buf), "\n* [#B] Work :office:@home:\n")
+	assert.Contains(t, string(buf), "\n* TODO [#A] Call mom\n")
+	reread := NewOrg(s.file)
+	call, err = reread.GetItemByID(5)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ItemPriorityHigh, call.Priority)
+	work, err = reread.GetItemByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ItemPriorityMedium, work.Priority)
+	assert.Equal(t, "Work", work.Title)
+}
+
+func TestOrgEscapesTitles(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOrg(t, "")
+	defer os.RemoveAll(dir)
+
+	titles := []string{"Check :foo:", ":foo:bar:", "[#A] is a cookie", "TODO list", "DONE", "\u200bspaced\u200b"}
+	for _, title := range titles {
+		for _, typ := range []entity.ItemType{entity.ItemTypeTask, entity.ItemTypeCategory} {
+			_, err := s.SaveItem(&entity.Item{Title: title, Type: typ, ParentItemID: entity.RootID, Tags: []string{"work"}})
+			assert.NoError(t, err)
+			_, err = s.SaveItem(&entity.Item{Title: title, Type: typ, ParentItemID: entity.RootID, Priority: entity.ItemPriorityLow})
+			assert.NoError(t, err)
+		}
+	}
+
+	items, err := NewOrg(s.file).GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	var got []string
+	for _, it := range items {
+		assert.NotEqual(t, []string{"foo"}, it.Tags, it.Title)
+		got = append(got, it.Title+"/"+orgItemTypes[it.Type])
+	}
+	var expected []string
+	for _, title := range titles {
+		task, category := title+"/task", title+"/category"
+		expected = append(expected, task, task, category, category)
+	}
+	assert.ElementsMatch(t, expected, got)
+}
+
+func TestOrgKeepsEffortItDoesNotUnderstand(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOrg(t, "* TODO Release\n:PROPERTIES:\n:EFFORT: 2d\n:END:\n")
+	defer os.RemoveAll(dir)
+
+	release, err := s.GetItemByID(1)
+	assert.NoError(t, err)
+	assert.Zero(t, release.Estimate)
+	release.Title = "Release 1.0"
+	_, err = s.SaveItem(release)
+	assert.NoError(t, err)
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(buf), "\n:EFFORT: 2d\n")
+
+	// replaced once estimated
+	release.Estimate = 90 * time.Minute
+	_, err = s.SaveItem(release)
+	assert.NoError(t, err)
+	buf, err = ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(buf), "\n:EFFORT: 1:30\n:END:\n")
+	assert.NotContains(t, string(buf), "2d")
+}
+
+func TestOrgPicksUpOutsideEdits(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOrg(t, "")
+	defer os.RemoveAll(dir)
+
+	due := time.Date(2020, 1, 2, 15, 4, 5, 6, time.UTC)
+	item := &entity.Item{Title: "Task", Due: due}
+	_, err := s.SaveItem(item)
+	assert.NoError(t, err)
+	got, err := s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	assert.True(t, due.Equal(got.Due))
+
+	// the task is marked as done and rescheduled in Emacs
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	edited := strings.Replace(string(buf), "* TODO Task", "* DONE Task", 1)
+	edited = strings.Replace(edited, formatOrgTimestamp(due, "<", ">"), "<2020-02-01 Sat>", 1)
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, ioutil.WriteFile(s.file, []byte(edited), 0600))
+
+	got, err = s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ItemStateCompleted, got.State)
+	assert.True(t, time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local).Equal(got.Due))
+}
+
+func TestOrgKeepsOrphans(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOrg(t, "")
+	defer os.RemoveAll(dir)
+
+	orphan := &entity.Item{Title: "orphan", ParentItemID: 42}
+	_, err := s.SaveItem(orphan)
+	assert.NoError(t, err)
+
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(buf), "* TODO orphan\n:PROPERTIES:\n:ITEM_ID: 1\n:ITEM_PARENT: 42\n")
+	got, err := NewOrg(s.file).GetItemByID(orphan.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), got.ParentItemID)
+}
+
+func TestOrgInvalidProperty(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOrg(t, "* TODO Task\n:PROPERTIES:\n:ITEM_RANK: First\n:END:\n")
+	defer os.RemoveAll(dir)
+
+	_, err := s.GetItemByID(1)
+	assert.Error(t, err)
+}
+
+func TestOrgReadsOrderOfOlderFiles(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOrg(t, "* TODO A\n:PROPERTIES:\n:ITEM_ID: 1\n:ITEM_ORDER: 2\n:END:\n"+
+		"* TODO B\n:PROPERTIES:\n:ITEM_ID: 2\n:ITEM_ORDER: 1\n:END:\n")
+	defer os.RemoveAll(dir)
+
+	top, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	if assert.Len(t, top, 2) {
+		assert.Equal(t, []string{"B", "A"}, []string{top[0].Title, top[1].Title})
+		assert.Equal(t, orderRank(2), top[1].Rank)
+	}
+
+	// written back as ranks
+	_, err = s.SaveItem(top[0])
+	assert.NoError(t, err)
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(buf), "ITEM_ORDER")
+	assert.Contains(t, string(buf), ":ITEM_RANK: "+orderRank(2)+"\n")
+}
@@ -0,0 +1,533 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// Outline is a storage that reads and writes the indented outline used by the built-in template.
+//
+// "+" starts a category, "*" a project, "=" a view, "[ ]" and "[x]" a task, each level of children is indented by 4
+// spaces, words of the title starting with "#" and a letter are tags. Lines after an item are its details, the first
+// detail of a task is its due if it could be parsed as one, lines starting with "RRULE:", "REMIND:", "PRIORITY:" and
+// "ESTIMATE:" are the fields as typed in the editor, the rest is the description. A word of the title which could be
+// mistaken for a tag, and a detail which could be mistaken for an item, a due or a field, are escaped with a leading
+// "\".
+//
+// The order of items is their position in the file, ranks are kept in memory as long as they are in that order,
+// otherwise items are ranked by their positions. So are IDs, timestamps, revisions, and states and dues of items
+// other than tasks, which are kept in memory only: when the file is read, items are matched to the previous read by
+// their parent, title and type, a matched item keeps its ID and its revision is increased if changed in the file.
+// Items without a match get new IDs, all IDs start over in a new process. So is the trash, items in the trash are
+// not written to the file.
+type Outline struct {
+	fileStore
+}
+
+// NewOutline creates an Outline with given file path, a lock file is created next to the file.
+func NewOutline(path string) *Outline {
+	return &Outline{fileStore{
+		file:     path,
+		lockFile: path + ".lock",
+		format:   &outlineFormat{},
+	}}
+}
+
+const (
+	outlineIndent         = "    "
+	outlineCategoryPrefix = "+ "
+	outlineProjectPrefix  = "* "
+	outlineViewPrefix     = "= "
+	outlineTaskPrefix     = "[ ] "
+	outlineDonePrefix     = "[x] "
+	outlineEscape         = `\`
+	outlineTagPrefix      = "#"
+	outlineRecurrenceKey  = "RRULE:"
+	outlineRemindersKey   = "REMIND:"
+	outlinePriorityKey    = "PRIORITY:"
+	outlineEstimateKey    = "ESTIMATE:"
+	outlineDateLayout     = "2006-01-02"
+	outlineTimeLayout     = "2006-01-02 15:04"
+)
+
+// outlineNode is an item parsed from the outline with its children.
+type outlineNode struct {
+	item     *entity.Item
+	children []*outlineNode
+}
+
+type outlineFormat struct {
+	// last is the table last decoded or encoded, it is where IDs and fields that are not in the file come from.
+	last *itemTable
+}
+
+func (f *outlineFormat) decode(buf []byte) (*itemTable, error) {
+	nodes, err := parseOutline(buf)
+	if err != nil {
+		return nil, err
+	}
+	last := f.last
+	if last == nil {
+		last = newItemTable()
+	}
+	t := &itemTable{NextID: last.NextID, Items: []*entity.Item{}}
+	used := make(map[int64]bool)
+	now := time.Now().UTC()
+
+	var walk func(nodes []*outlineNode, parentID int64)
+	walk = func(nodes []*outlineNode, parentID int64) {
+		siblings, olds := make([]*entity.Item, len(nodes)), make([]*entity.Item, len(nodes))
+		for i, n := range nodes {
+			siblings[i] = n.item
+			siblings[i].ParentItemID = parentID
+			if olds[i] = matchOutlineItem(last, n.item, used); olds[i] != nil {
+				used[olds[i].ID] = true
+			}
+		}
+		rankOutlineSiblings(siblings, olds)
+		for i, n := range nodes {
+			it := n.item
+			if old := olds[i]; old != nil {
+				carryOverOutlineItem(old, it, now)
+			} else {
+				it.ID = t.NextID
+				t.NextID++
+				it.CreatedAt, it.UpdatedAt, it.Revision = now, now, 1
+				if it.State == entity.ItemStateCompleted {
+					it.CompletedAt = now
+				}
+			}
+			t.Items = append(t.Items, it)
+			walk(n.children, it.ID)
+		}
+	}
+	walk(nodes, entity.RootID)
+	for _, old := range last.Items {
+		if isDeleted(old) {
+			t.Items = append(t.Items, copyItem(old))
+		}
+	}
+
+	t.reindex()
+	f.last = t.clone()
+	return t, nil
+}
+
+// encode writes items in the order of their ranks, the written table is kept for the next decode.
+func (f *outlineFormat) encode(t *itemTable, _ []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	written := &itemTable{NextID: t.NextID, Items: []*entity.Item{}}
+	seen := make(map[int64]bool, len(t.Items))
+
+	var write func(it *entity.Item, level int)
+	write = func(it *entity.Item, level int) {
+		seen[it.ID] = true
+		writeOutlineItem(&buf, it, level)
+		written.Items = append(written.Items, it)
+		for _, child := range t.itemsByParentID(it.ID) {
+			if !seen[child.ID] {
+				write(child, level+1)
+			}
+		}
+	}
+	var lastRank string
+	for _, it := range t.itemsByParentID(entity.RootID) {
+		lastRank = it.Rank
+		write(it, 0)
+	}
+
+	// items which could not be reached from the root are written to the root level rather than lost, those whose
+	// parent does not exist go first, then those in a cycle
+	exists := make(map[int64]bool, len(t.Items))
+	for _, it := range t.Items {
+		exists[it.ID] = true
+	}
+	for _, orphanRootsOnly := range []bool{true, false} {
+		for _, it := range t.Items {
+			if seen[it.ID] || isDeleted(it) || (orphanRootsOnly && exists[it.ParentItemID]) {
+				continue
+			}
+			orphan := copyItem(it)
+			orphan.ParentItemID = entity.RootID
+			if rank, ok := entity.RankBetween(lastRank, ""); ok {
+				orphan.Rank, lastRank = rank, rank
+			}
+			write(orphan, 0)
+		}
+	}
+
+	for _, it := range t.Items {
+		if isDeleted(it) {
+			written.Items = append(written.Items, it)
+		}
+	}
+	written.reindex()
+	f.last = written
+	return buf.Bytes(), nil
+}
+
+// rankOutlineSiblings ranks siblings in the order of the file, olds are the items they are matched to, nil if new.
+// Matched siblings keep their ranks if those are still in the order of the file, new ones get ranks between them,
+// otherwise, as well as when all of them are new, siblings are ranked by their positions.
+func rankOutlineSiblings(siblings, olds []*entity.Item) {
+	if keepOutlineRanks(siblings, olds) {
+		return
+	}
+	for i, it := range siblings {
+		it.Rank = orderRank(uint64(i + 1))
+	}
+}
+
+// keepOutlineRanks ranks siblings by the ranks of olds as described in rankOutlineSiblings, returns false if they
+// could not be kept.
+func keepOutlineRanks(siblings, olds []*entity.Item) bool {
+	var prev *entity.Item
+	for _, old := range olds {
+		if old == nil {
+			continue
+		}
+		if prev != nil && !rankLess(prev, old) {
+			return false
+		}
+		prev = old
+	}
+	if prev == nil {
+		return false
+	}
+	for i, it := range siblings {
+		if olds[i] != nil {
+			it.Rank = olds[i].Rank
+			continue
+		}
+		var prevRank, nextRank string
+		if i > 0 {
+			prevRank = siblings[i-1].Rank
+		}
+		for _, old := range olds[i+1:] {
+			if old != nil {
+				nextRank = old.Rank
+				break
+			}
+		}
+		rank, ok := entity.RankBetween(prevRank, nextRank)
+		if !ok {
+			return false
+		}
+		it.Rank = rank
+	}
+	return true
+}
+
+// matchOutlineItem returns the first unused item of last with the same parent, title and type as it.
+func matchOutlineItem(last *itemTable, it *entity.Item, used map[int64]bool) *entity.Item {
+	for _, old := range last.Items {
+		if !used[old.ID] && !isDeleted(old) && old.ParentItemID == it.ParentItemID && old.Title == it.Title && old.Type == it.Type {
+			return old
+		}
+	}
+	return nil
+}
+
+// carryOverOutlineItem fills it with what the outline does not have from old, the revision is increased if it has
+// been changed in the file.
+func carryOverOutlineItem(old, it *entity.Item, now time.Time) {
+	it.ID, it.CreatedAt, it.UpdatedAt = old.ID, old.CreatedAt, old.UpdatedAt
+	it.Revision, it.CompletedAt = old.Revision, old.CompletedAt
+	if it.Type != entity.ItemTypeTask {
+		// only tasks have states and dues in the file
+		it.State, it.Due = old.State, old.Due
+	}
+	// keep the precision which is lost in the file
+	if formatOutlineDue(old.Due) == formatOutlineDue(it.Due) {
+		it.Due = old.Due
+	}
+	if normalizeOutlineDescription(old.Description) == normalizeOutlineDescription(it.Description) {
+		it.Description = old.Description
+	}
+	if strings.Join(old.Tags, " ") == strings.Join(it.Tags, " ") {
+		it.Tags = old.Tags
+	}
+	if formatReminders(old.Reminders) == formatReminders(it.Reminders) {
+		it.Reminders = old.Reminders
+	}
+	if old.State == it.State && old.Due.Equal(it.Due) && old.Description == it.Description && old.Rank == it.Rank &&
+		old.Recurrence == it.Recurrence && reflect.DeepEqual(old.Reminders, it.Reminders) &&
+		reflect.DeepEqual(old.Tags, it.Tags) && old.Priority == it.Priority && old.Estimate == it.Estimate {
+		return
+	}
+	it.Revision++
+	it.UpdatedAt = now
+	switch {
+	case it.State != entity.ItemStateCompleted:
+		it.CompletedAt = time.Time{}
+	case it.CompletedAt.IsZero():
+		it.CompletedAt = now
+	}
+}
+
+func writeOutlineItem(buf *bytes.Buffer, it *entity.Item, level int) {
+	indent := strings.Repeat(outlineIndent, level)
+	var prefix string
+	switch {
+	case it.Type == entity.ItemTypeCategory:
+		prefix = outlineCategoryPrefix
+	case it.Type == entity.ItemTypeProject:
+		prefix = outlineProjectPrefix
+	case it.Type == entity.ItemTypeView:
+		prefix = outlineViewPrefix
+	case it.State == entity.ItemStateCompleted:
+		prefix = outlineDonePrefix
+	default:
+		prefix = outlineTaskPrefix
+	}
+	buf.WriteString(indent + prefix + formatOutlineTitle(it) + "\n")
+
+	expectDue := it.Type == entity.ItemTypeTask
+	if expectDue && !it.Due.IsZero() {
+		buf.WriteString(indent + formatOutlineDue(it.Due) + "\n")
+		expectDue = false
+	}
+	field := func(key, value string) {
+		buf.WriteString(indent + key + " " + value + "\n")
+	}
+	if it.Recurrence != "" {
+		field(outlineRecurrenceKey, it.Recurrence)
+	}
+	if len(it.Reminders) > 0 {
+		field(outlineRemindersKey, formatReminders(it.Reminders))
+	}
+	if name, ok := priorityNames[it.Priority]; ok {
+		field(outlinePriorityKey, name)
+	}
+	if it.Estimate > 0 {
+		field(outlineEstimateKey, entity.FormatEstimate(it.Estimate))
+	}
+	for _, line := range strings.Split(normalizeOutlineDescription(it.Description), "\n") {
+		if line == "" {
+			continue
+		}
+		if isOutlineItemLine(line) || strings.HasPrefix(line, outlineEscape) || parseOutlineField(&entity.Item{}, line) {
+			line = outlineEscape + line
+		} else if _, err := parseOutlineDue(line); expectDue && err == nil {
+			line = outlineEscape + line
+		}
+		buf.WriteString(indent + line + "\n")
+		expectDue = false
+	}
+}
+
+// parseOutline parses the outline into a tree, blank lines are ignored.
+func parseOutline(buf []byte) ([]*outlineNode, error) {
+	var roots []*outlineNode
+	// levels holds the last node of each level, from the root level to the level of the last node
+	var levels []*outlineNode
+	var last *outlineNode
+	expectDue := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(nil, len(buf)+1)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.Replace(scanner.Text(), "\t", outlineIndent, -1)
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+
+		if isOutlineItemLine(text) {
+			level := (len(line) - len(strings.TrimLeft(line, " "))) / len(outlineIndent)
+			if level > len(levels) {
+				// deeper than a child of the last node
+				level = len(levels)
+			}
+			n := &outlineNode{item: parseOutlineItemLine(text)}
+			if level == 0 {
+				roots = append(roots, n)
+			} else {
+				parent := levels[level-1]
+				parent.children = append(parent.children, n)
+			}
+			levels = append(levels[:level], n)
+			last = n
+			expectDue = n.item.Type == entity.ItemTypeTask
+			continue
+		}
+
+		if last == nil {
+			return nil, fmt.Errorf("line %d: detail before the first item: %s", lineNo, text)
+		}
+		if parseOutlineField(last.item, text) {
+			continue
+		}
+		if expectDue {
+			expectDue = false
+			if due, err := parseOutlineDue(text); err == nil {
+				last.item.Due = due
+				continue
+			}
+		}
+		last.item.Description += strings.TrimPrefix(text, outlineEscape) + "\n"
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+func isOutlineItemLine(text string) bool {
+	for _, prefix := range []string{outlineCategoryPrefix, outlineProjectPrefix, outlineViewPrefix, outlineTaskPrefix, outlineDonePrefix} {
+		if strings.HasPrefix(text+" ", prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseOutlineItemLine(text string) *entity.Item {
+	it := &entity.Item{Type: entity.ItemTypeTask}
+	text += " "
+	switch {
+	case strings.HasPrefix(text, outlineCategoryPrefix):
+		it.Type = entity.ItemTypeCategory
+		text = text[len(outlineCategoryPrefix):]
+	case strings.HasPrefix(text, outlineProjectPrefix):
+		it.Type = entity.ItemTypeProject
+		text = text[len(outlineProjectPrefix):]
+	case strings.HasPrefix(text, outlineViewPrefix):
+		it.Type = entity.ItemTypeView
+		text = text[len(outlineViewPrefix):]
+	case strings.HasPrefix(text, outlineDonePrefix):
+		it.State = entity.ItemStateCompleted
+		text = text[len(outlineDonePrefix):]
+	default:
+		text = text[len(outlineTaskPrefix):]
+	}
+	var words []string
+	for _, w := range strings.Fields(text) {
+		switch {
+		case strings.HasPrefix(w, outlineEscape):
+			words = append(words, w[len(outlineEscape):])
+		case isOutlineTag(w):
+			it.Tags = append(it.Tags, w[len(outlineTagPrefix):])
+		default:
+			words = append(words, w)
+		}
+	}
+	it.Title = strings.Join(words, " ")
+	return it
+}
+
+// formatOutlineTitle formats the title of an item followed by its tags, words of the title which could be mistaken
+// for tags are escaped.
+func formatOutlineTitle(it *entity.Item) string {
+	words := strings.Fields(it.Title)
+	for i, w := range words {
+		if strings.HasPrefix(w, outlineEscape) || isOutlineTag(w) {
+			words[i] = outlineEscape + w
+		}
+	}
+	for _, tag := range it.Tags {
+		words = append(words, outlineTagPrefix+tag)
+	}
+	return strings.Join(words, " ")
+}
+
+// isOutlineTag returns true if a word of a title is a tag, which starts with "#" and a letter.
+func isOutlineTag(word string) bool {
+	r, _ := utf8.DecodeRuneInString(strings.TrimPrefix(word, outlineTagPrefix))
+	return strings.HasPrefix(word, outlineTagPrefix) && unicode.IsLetter(r)
+}
+
+// parseOutlineField sets the field of a detail starting with a key, the key is case-insensitive as it is in the
+// editor. It returns false if the detail is not a field, the field is already set, or the value is malformed.
+func parseOutlineField(it *entity.Item, text string) bool {
+	var key string
+	for _, k := range []string{outlineRecurrenceKey, outlineRemindersKey, outlinePriorityKey, outlineEstimateKey} {
+		if strings.HasPrefix(strings.ToUpper(text), k) {
+			key = k
+		}
+	}
+	if key == "" {
+		return false
+	}
+	value := strings.TrimSpace(text[len(key):])
+	var err error
+	switch key {
+	case outlineRecurrenceKey:
+		if it.Recurrence != "" || value == "" {
+			return false
+		}
+		it.Recurrence = value
+	case outlineRemindersKey:
+		if len(it.Reminders) > 0 {
+			return false
+		}
+		it.Reminders, err = parseReminders(strings.Replace(value, " ", "", -1))
+	case outlinePriorityKey:
+		if it.Priority != entity.ItemPriorityNone {
+			return false
+		}
+		err = fmt.Errorf("unknown priority: %s", value)
+		for p, name := range priorityNames {
+			if strings.EqualFold(name, value) {
+				it.Priority, err = p, nil
+			}
+		}
+	case outlineEstimateKey:
+		if it.Estimate > 0 {
+			return false
+		}
+		it.Estimate, err = entity.ParseEstimate(value)
+	}
+	if err != nil {
+		it.Reminders, it.Estimate = nil, 0
+		return false
+	}
+	return true
+}
+
+// parseOutlineDue parses a due written by formatOutlineDue, or "today" and "tomorrow" typed by hand.
+func parseOutlineDue(s string) (time.Time, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	switch s {
+	case "today":
+		return today, nil
+	case "tom", "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+	if due, err := time.ParseInLocation(outlineTimeLayout, s, time.Local); err == nil {
+		return due, nil
+	}
+	return time.ParseInLocation(outlineDateLayout, s, time.Local)
+}
+
+// formatOutlineDue formats a due in local time, the time of day is omitted if it is midnight.
+func formatOutlineDue(due time.Time) string {
+	if due.IsZero() {
+		return ""
+	}
+	due = due.Local()
+	if due.Hour() == 0 && due.Minute() == 0 {
+		return due.Format(outlineDateLayout)
+	}
+	return due.Format(outlineTimeLayout)
+}
+
+// normalizeOutlineDescription returns the description as it would be read from the outline.
+func normalizeOutlineDescription(desc string) string {
+	var lines []string
+	for _, line := range strings.Split(desc, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line+"\n")
+		}
+	}
+	return strings.Join(lines, "")
+}
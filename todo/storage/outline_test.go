@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// testingOutline is the built-in template of package use, with dues as dates.
+const testingOutline = `
++ Inbox
+
+Inbox is the place to dump your thoughts into.
+
+    [ ] Welcome!
+    2020-01-02
+    This is the description
+
+    [ ] Press ? to show help
+    2020-01-02 15:04
+
+    [x] Use j/k to move down/up
+    today
++ Projects
+`
+
+func writeTestingOutline(t *testing.T, content string) (*Outline, string) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "todo.md")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return NewOutline(path), dir
+}
+
+func outlineTitles(t *testing.T, s *Outline, parentID int64) []string {
+	items, err := s.GetItemsByParentID(parentID)
+	assert.NoError(t, err)
+	titles := make([]string, len(items))
+	for i, it := range items {
+		titles[i] = it.Title
+	}
+	return titles
+}
+
+func TestOutlineReadsTemplate(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOutline(t, testingOutline)
+	defer os.RemoveAll(dir)
+
+	top, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Len(t, top, 2)
+	inbox := top[0]
+	assert.Equal(t, "Inbox", inbox.Title)
+	assert.Equal(t, entity.ItemTypeCategory, inbox.Type)
+	assert.Equal(t, "Inbox is the place to dump your thoughts into.\n", inbox.Description)
+
+	tasks, err := s.GetItemsByParentID(inbox.ID)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 3)
+	assert.Equal(t, "Welcome!", tasks[0].Title)
+	assert.True(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local).Equal(tasks[0].Due))
+	assert.Equal(t, "This is the description\n", tasks[0].Description)
+	assert.True(t, time.Date(2020, 1, 2, 15, 4, 0, 0, time.Local).Equal(tasks[1].Due))
+	assert.Equal(t, entity.ItemStateCompleted, tasks[2].State)
+	assert.False(t, tasks[2].CompletedAt.IsZero())
+	now := time.Now()
+	assert.True(t, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).Equal(tasks[2].Due))
+	for i, it := range tasks {
+		assert.Equal(t, orderRank(uint64(i+1)), it.Rank)
+		assert.Equal(t, entity.ItemTypeTask, it.Type)
+	}
+}
+
+func TestOutlineRoundTrip(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOutline(t, "")
+	defer os.RemoveAll(dir)
+
+	due := time.Date(2020, 1, 2, 15, 4, 5, 6, time.Local)
+	items := []*entity.Item{
+		{Title: "Category", Type: entity.ItemTypeCategory, Rank: orderRank(1), Description: "+ not an item\n\\ escaped\n"},
+		{Title: "Project", Type: entity.ItemTypeProject, Rank: orderRank(2)},
+	}
+	for _, it := range items {
+		_, err := s.SaveItem(it)
+		assert.NoError(t, err)
+	}
+	items = append(items,
+		&entity.Item{Title: "Task", ParentItemID: items[0].ID, Rank: orderRank(1), Due: due, Description: "line 1\nline 2\n"},
+		&entity.Item{Title: "No due", ParentItemID: items[0].ID, Rank: orderRank(2), Description: "today\n"},
+		&entity.Item{Title: "Done", ParentItemID: items[1].ID, Rank: orderRank(1), State: entity.ItemStateCompleted},
+		&entity.Item{Title: "Sub task", ParentItemID: items[1].ID, Rank: orderRank(2)},
+	)
+	for _, it := range items[2:] {
+		_, err := s.SaveItem(it)
+		assert.NoError(t, err)
+	}
+
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.Equal(t, `+ Category
+\+ not an item
+\\ escaped
+    [ ] Task
+    2020-01-02 15:04
+    line 1
+    line 2
+    [ ] No due
+    \today
+* Project
+    [x] Done
+    [ ] Sub task
+`, string(buf))
+
+	// the precise due and all other fields are kept within the process
+	for _, it := range items {
+		got, err := s.GetItemByID(it.ID)
+		assert.NoError(t, err)
+		// the location of the due may differ after being copied
+		assert.True(t, it.Due.Equal(got.Due))
+		got.Due = it.Due
+		assert.Equal(t, it, got)
+	}
+
+	// a new process reads the same outline
+	reopened := NewOutline(s.file)
+	assert.Equal(t, []string{"Category", "Project"}, outlineTitles(t, reopened, entity.RootID))
+	children, err := reopened.GetItemsByParentID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "today\n", children[1].Description)
+	assert.True(t, children[1].Due.IsZero())
+}
+
+func TestOutlinePicksUpOutsideEdits(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOutline(t, "+ A\n    [ ] a1\n    [ ] a2\n+ B\n")
+	defer os.RemoveAll(dir)
+
+	before := make(map[string]*entity.Item)
+	for _, id := range []int64{1, 2, 3, 4} {
+		it, err := s.GetItemByID(id)
+		assert.NoError(t, err)
+		before[it.Title] = it
+	}
+
+	// a2 is completed and moved before a1, a new task is added to B
+	time.Sleep(10 * time.Millisecond)
+	edited := "+ A\n    [x] a2\n    [ ] a1\n+ B\n    [ ] b1\n"
+	assert.NoError(t, ioutil.WriteFile(s.file, []byte(edited), 0600))
+
+	assert.Equal(t, []string{"a2", "a1"}, outlineTitles(t, s, before["A"].ID))
+	a2, err := s.GetItemByID(before["a2"].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ItemStateCompleted, a2.State)
+	assert.Equal(t, before["a2"].Revision+1, a2.Revision)
+	assert.False(t, a2.CompletedAt.IsZero())
+
+	unchanged, err := s.GetItemByID(before["B"].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, before["B"], unchanged)
+
+	b1, err := s.GetItemsByParentID(before["B"].ID)
+	assert.NoError(t, err)
+	assert.Len(t, b1, 1)
+	assert.Equal(t, int64(5), b1[0].ID)
+
+	// a stale copy is rejected
+	_, err = s.SaveItem(before["a2"])
+	assert.Error(t, err)
+}
+
+func TestOutlineInsertsBetween(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOutline(t, "[ ] a\n[ ] b\n")
+	defer os.RemoveAll(dir)
+
+	rank, ok := entity.RankBetween(orderRank(1), orderRank(2))
+	assert.True(t, ok)
+	_, err := s.SaveItem(&entity.Item{Title: "between", Rank: rank})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "between", "b"}, outlineTitles(t, s, entity.RootID))
+}
+
+func TestOutlineKeepsRanksInOrder(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOutline(t, "")
+	defer os.RemoveAll(dir)
+
+	a := &entity.Item{Title: "a", Rank: "c"}
+	b := &entity.Item{Title: "b", Rank: "o"}
+	for _, it := range []*entity.Item{a, b} {
+		_, err := s.SaveItem(it)
+		assert.NoError(t, err)
+	}
+	ranks := func() []string {
+		items, err := s.GetItemsByParentID(entity.RootID)
+		assert.NoError(t, err)
+		var ranks []string
+		for _, it := range items {
+			ranks = append(ranks, it.Title+":"+it.Rank)
+		}
+		return ranks
+	}
+	assert.Equal(t, []string{"a:c", "b:o"}, ranks())
+
+	// a new item goes between
+	assert.NoError(t, ioutil.WriteFile(s.file, []byte("[ ] a\n[ ] new\n[ ] b\n"), 0600))
+	between, _ := entity.RankBetween("c", "o")
+	assert.Equal(t, []string{"a:c", "new:" + between, "b:o"}, ranks())
+
+	// items out of the order of their ranks are ranked by their positions
+	assert.NoError(t, ioutil.WriteFile(s.file, []byte("[ ] b\n[ ] a\n"), 0600))
+	assert.Equal(t, []string{"b:" + orderRank(1), "a:" + orderRank(2)}, ranks())
+	moved, err := s.GetItemByID(b.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, b.Revision+1, moved.Revision)
+}
+
+func TestOutlineKeepsOrphans(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOutline(t, "")
+	defer os.RemoveAll(dir)
+
+	_, err := s.SaveItem(&entity.Item{Title: "orphan", ParentItemID: 42})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"orphan"}, outlineTitles(t, s, entity.RootID))
+}
+
+func TestOutlineKeepsTrashInMemory(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOutline(t, "+ A\n    [ ] a1\n+ B\n")
+	defer os.RemoveAll(dir)
+
+	top, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.NoError(t, s.DeleteItem(top[0].ID))
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.Equal(t, "+ B\n", string(buf))
+
+	// an item of the same title is a new one
+	assert.NoError(t, ioutil.WriteFile(s.file, []byte("+ A\n+ B\n"), 0600))
+	assert.Equal(t, []string{"A", "B"}, outlineTitles(t, s, entity.RootID))
+	assert.NoError(t, s.RestoreItem(top[0].ID))
+	assert.Equal(t, []string{"A", "A", "B"}, outlineTitles(t, s, entity.RootID))
+	assert.Equal(t, []string{"a1"}, outlineTitles(t, s, top[0].ID))
+}
+
+func TestOutlineReadsFields(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOutline(t, `
+[ ] Report #work for \#1 #team-a/q1
+rrule: FREQ=DAILY
+Remind: 15m, 1d
+PRIORITY: high
+ESTIMATE: 2h
+PRIORITY: low
+\ESTIMATE: is a description
+`)
+	defer os.RemoveAll(dir)
+
+	report, err := s.GetItemByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Report for #1", report.Title)
+	assert.Equal(t, []string{"work", "team-a/q1"}, report.Tags)
+	assert.Equal(t, "FREQ=DAILY", report.Recurrence)
+	assert.Equal(t, []time.Duration{15 * time.Minute, 24 * time.Hour}, report.Reminders)
+	assert.Equal(t, entity.ItemPriorityHigh, report.Priority)
+	assert.Equal(t, 2*time.Hour, report.Estimate)
+	// a field given twice is the description as is an escaped one
+	assert.Equal(t, "PRIORITY: low\nESTIMATE: is a description\n", report.Description)
+
+	// and the description is escaped when written
+	_, err = s.SaveItem(report)
+	assert.NoError(t, err)
+	reread, err := NewOutline(s.file).GetItemByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, report.Description, reread.Description)
+	assert.Equal(t, report.Title, reread.Title)
+}
+
+func TestOutlineDetailBeforeFirstItem(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingOutline(t, "some notes\n+ A\n")
+	defer os.RemoveAll(dir)
+
+	_, err := s.GetItemByID(1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package storage
+
+import "github.com/tevino/the-clean-architecture-demo/todo/entity"
+
+// letterPriority maps a priority letter of todo.txt and Org files to the priority of items, A is high, B is medium
+// and the rest are low.
+func letterPriority(letter string) entity.ItemPriority {
+	switch letter {
+	case "":
+		return entity.ItemPriorityNone
+	case "A":
+		return entity.ItemPriorityHigh
+	case "B":
+		return entity.ItemPriorityMedium
+	}
+	return entity.ItemPriorityLow
+}
+
+// priorityLetter returns the priority letter of an item, prev is kept as long as it is of the same priority.
+func priorityLetter(it *entity.Item, prev string) string {
+	if letterPriority(prev) == it.Priority {
+		return prev
+	}
+	switch it.Priority {
+	case entity.ItemPriorityHigh:
+		return "A"
+	case entity.ItemPriorityMedium:
+		return "B"
+	case entity.ItemPriorityLow:
+		return "C"
+	}
+	return ""
+}
+
+// priorityNames are names of priorities as typed in the editor, there is none for ItemPriorityNone.
+var priorityNames = map[entity.ItemPriority]string{
+	entity.ItemPriorityLow:    "low",
+	entity.ItemPriorityMedium: "medium",
+	entity.ItemPriorityHigh:   "high",
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// ReminderFile implements use.ReminderState on top of a file containing the time reminders are delivered until.
+type ReminderFile struct {
+	path string
+}
+
+// NewReminderFile creates a ReminderFile with given file path, the file is created when the state is first set.
+func NewReminderFile(path string) *ReminderFile {
+	return &ReminderFile{path: path}
+}
+
+// RemindedUntil returns the time in the file, it is zero if the file does not exist.
+func (f *ReminderFile) RemindedUntil() (time.Time, error) {
+	buf, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading reminder file: %w", err)
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(bytes.TrimSpace(buf)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing reminder file: %w", err)
+	}
+	return t, nil
+}
+
+// SetRemindedUntil replaces the time in the file.
+func (f *ReminderFile) SetRemindedUntil(t time.Time) error {
+	if err := writeFileAtomic(f.path, []byte(t.Format(time.RFC3339Nano)+"\n")); err != nil {
+		return fmt.Errorf("writing reminder file: %w", err)
+	}
+	return nil
+}
+
+// formatReminders formats reminders of an item as a comma separated list for text formats, e.g. 15m,1d.
+func formatReminders(reminders []time.Duration) string {
+	parts := make([]string, len(reminders))
+	for i, d := range reminders {
+		parts[i] = entity.FormatReminder(d)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseReminders parses reminders formatted by formatReminders.
+func parseReminders(s string) ([]time.Duration, error) {
+	var reminders []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		d, err := entity.ParseReminder(part)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, d)
+	}
+	return reminders, nil
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReminderFile(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "reminders")
+
+	until, err := NewReminderFile(path).RemindedUntil()
+	assert.NoError(t, err)
+	assert.True(t, until.IsZero())
+
+	now := time.Now()
+	assert.NoError(t, NewReminderFile(path).SetRemindedUntil(now))
+	until, err = NewReminderFile(path).RemindedUntil()
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(until), until)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("yesterday"), 0644))
+	_, err = NewReminderFile(path).RemindedUntil()
+	assert.Error(t, err)
+}
@@ -1,7 +1,11 @@
 package storage
 
 import (
-	"sort"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,138 +16,150 @@ import (
 )
 
 func foreachImplementations(t *testing.T, test func(use.Storage)) {
+	fsDir, journalDir, todoTxtDir, orgDir := tempDir(t), tempDir(t), tempDir(t), tempDir(t)
+	defer os.RemoveAll(fsDir)
+	defer os.RemoveAll(journalDir)
+	defer os.RemoveAll(todoTxtDir)
+	defer os.RemoveAll(orgDir)
+
+	journal, err := NewJournal(journalDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
 	for _, imp := range []use.Storage{
 		NewMemory(),
-		// NewFileSystem("temp/path")
+		NewFileSystem(fsDir),
+		journal,
+		NewTodoTxt(filepath.Join(todoTxtDir, "todo.txt")),
+		NewOrg(filepath.Join(orgDir, "todo.org")),
 	} {
 		t.Logf("Testing storage implementation: %T", imp)
 		test(imp)
 	}
 }
 
-func TestSaveItemIDNotZero(t *testing.T) {
-	foreachImplementations(t, func(s use.Storage) {
-		id, err := s.SaveItem(&entity.Item{})
-		assert.NoError(t, err)
-		assert.NotZero(t, id)
-	})
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "todo-storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
 }
 
-func TestSaveItemNilErr(t *testing.T) {
+// TestSaveItemRejectsStaleRevisionWithConflictError complements the conformance tests with the details of
+// ConflictError, which all implementations here return.
+func TestSaveItemRejectsStaleRevisionWithConflictError(t *testing.T) {
 	foreachImplementations(t, func(s use.Storage) {
-		_, err := s.SaveItem(nil)
-		assert.Equal(t, ErrNilItem, err)
-	})
-}
+		item := addTestingItems(t, s)[2]
+		stale := *item
 
-func TestSaveItemUpdatesExistingItem(t *testing.T) {
-	foreachImplementations(t, func(s use.Storage) {
-		item := addTestingItems(t, s)[0]
-		item.Description = "updated description"
-		item.Title = "updated title"
-		item.Order++
-		item.State = entity.ItemStateCompleted
-		item.Due = item.Due.Add(time.Second)
-
-		oldUpdatedAt := item.UpdatedAt
+		item.Title = "first writer"
 		_, err := s.SaveItem(item)
 		assert.NoError(t, err)
-		newItem, err := s.GetItemByID(item.ID)
-		assert.NoError(t, err)
-		assert.True(t, newItem.UpdatedAt.After(oldUpdatedAt))
-		assert.Equal(t, item, newItem)
-	})
-}
-
-func TestGetItemsByParentIDReturnsNoRootID(t *testing.T) {
-	foreachImplementations(t, func(s use.Storage) {
-		addTestingItems(t, s)
-		items, err := s.GetItemsByParentID(entity.RootID)
-		assert.NoError(t, err)
-		assert.NotEmpty(t, items)
-		for _, it := range items {
-			assert.NotEqual(t, it.ID, entity.RootID)
-		}
-	})
-}
-
-func TestIncreasesOrderAfter(t *testing.T) {
-	foreachImplementations(t, func(s use.Storage) {
-		// Add duplicate items to break the order
-		item := addTestingItems(t, s)[0]
-		addTestingItems(t, s)
-
-		err := s.IncreaseOrderAfter(item)
-		assert.NoError(t, err)
-
-		// test if order of items after the inserted one are updated
-		items, err := s.GetItemsByParentID(item.ParentItemID)
-		assert.NoError(t, err)
-		assert.True(t, len(items) > 1)
-
-		for _, it := range items {
-			if it.ID == item.ID {
-				continue
-			}
-			assert.True(t, it.Order > item.Order)
-		}
-	})
-}
-
-func TestGetItemsByParentIDReturnsSortedASC(t *testing.T) {
-	foreachImplementations(t, func(s use.Storage) {
-		// Add duplicate items to break the order
-		addTestingItems(t, s)
-		addTestingItems(t, s)
-
-		items, err := s.GetItemsByParentID(entity.RootID)
-		assert.NoError(t, err)
-		assert.True(t, sort.SliceIsSorted(items, func(i int, j int) bool {
-			return items[i].Order < items[j].Order
-		}))
-	})
-}
-
-func TestGetItemByIDReturnsRootItem(t *testing.T) {
-	foreachImplementations(t, func(s use.Storage) {
-		it, err := s.GetItemByID(entity.RootID)
-		assert.NoError(t, err)
-		assert.Equal(t, entity.RootItem, it)
-	})
-}
 
-func TestGetItemByID(t *testing.T) {
-	foreachImplementations(t, func(s use.Storage) {
-		items := addTestingItems(t, s)
-		it, err := s.GetItemByID(items[1].ID)
-		assert.NoError(t, err)
-		assert.Equal(t, items[1], it)
+		_, err = s.SaveItem(&stale)
+		var conflict *ConflictError
+		assert.True(t, errors.As(err, &conflict))
+		assert.Equal(t, &ConflictError{ItemID: item.ID, Revision: stale.Revision, Latest: item.Revision}, conflict)
 	})
 }
 
-func TestGetItemByIDErrItemNotFound(t *testing.T) {
-	foreachImplementations(t, func(s use.Storage) {
-		_, err := s.GetItemByID(42)
-		assert.Equal(t, ErrItemNotFound, err)
-	})
+// assertItemEvent asserts the next event is of given type and item.
+func assertItemEvent(t *testing.T, events <-chan use.ItemEvent, typ use.ItemEventType, id int64) use.ItemEvent {
+	select {
+	case e := <-events:
+		assert.Equal(t, typ, e.Type)
+		assert.Equal(t, id, e.Item.ID)
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no event of item[%d]", id)
+		return use.ItemEvent{}
+	}
 }
 
 func addTestingItems(t *testing.T, s use.Storage) []*entity.Item {
-	t1 := &entity.Item{Title: "top1", ParentItemID: entity.RootID, Order: 1, Type: entity.ItemTypeCategory}
+	t1 := &entity.Item{Title: "top1", ParentItemID: entity.RootID, Rank: "c", Type: entity.ItemTypeCategory}
 	t1ID, err := s.SaveItem(t1)
 	assert.NoError(t, err)
 
-	t2 := &entity.Item{Title: "top2", ParentItemID: entity.RootID, Order: 2, Type: entity.ItemTypeCategory}
+	t2 := &entity.Item{Title: "top2", ParentItemID: entity.RootID, Rank: "o", Type: entity.ItemTypeCategory}
 	t2ID, err := s.SaveItem(t2)
 	assert.NoError(t, err)
 
-	s1 := &entity.Item{Title: "sub1", ParentItemID: t1ID, Order: 1, Type: entity.ItemTypeTask}
+	s1 := &entity.Item{Title: "sub1", ParentItemID: t1ID, Rank: "i", Type: entity.ItemTypeTask}
 	_, err = s.SaveItem(s1)
 	assert.NoError(t, err)
 
-	s2 := &entity.Item{Title: "sub2", ParentItemID: t2ID, Order: 1, Type: entity.ItemTypeTask}
+	s2 := &entity.Item{Title: "sub2", ParentItemID: t2ID, Rank: "i", Type: entity.ItemTypeTask}
 	_, err = s.SaveItem(s2)
 	assert.NoError(t, err)
 
 	return []*entity.Item{t1, t2, s1, s2}
 }
+
+// TestTextFilesRoundTrip saves an item in each text format and reads it back with a new storage, which is what a new
+// process does, the fields of the item a format keeps are compared.
+func TestTextFilesRoundTrip(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	formats := map[string]func(string) use.Storage{
+		"todo.txt": func(path string) use.Storage { return NewTodoTxt(path) },
+		"todo.org": func(path string) use.Storage { return NewOrg(path) },
+		"todo.md":  func(path string) use.Storage { return NewOutline(path) },
+	}
+	for _, c := range []struct {
+		name string
+		item *entity.Item
+		// fields returns the fields to compare
+		fields func(*entity.Item) []interface{}
+	}{
+		{
+			name: "View",
+			item: &entity.Item{Title: "Urgent", Description: `due < today+1d and title ~ "a b"`, Type: entity.ItemTypeView},
+			fields: func(it *entity.Item) []interface{} {
+				return []interface{}{it.Type, it.Title, strings.TrimSpace(it.Description)}
+			},
+		},
+		{
+			name:   "Recurrence",
+			item:   &entity.Item{Title: "Weekly report", Recurrence: "FREQ=WEEKLY;BYDAY=MO,-1FR;COUNT=3"},
+			fields: func(it *entity.Item) []interface{} { return []interface{}{it.Recurrence} },
+		},
+		{
+			name:   "Reminders",
+			item:   &entity.Item{Title: "Report", Due: time.Now(), Reminders: []time.Duration{15 * time.Minute, 26 * time.Hour, 0}},
+			fields: func(it *entity.Item) []interface{} { return []interface{}{it.Reminders} },
+		},
+		{
+			name:   "Tags",
+			item:   &entity.Item{Title: "Report for #team-b", Tags: []string{"work", "team-a/q1"}},
+			fields: func(it *entity.Item) []interface{} { return []interface{}{it.Title, it.Tags} },
+		},
+		{
+			name:   "Priority",
+			item:   &entity.Item{Title: "Report", Priority: entity.ItemPriorityMedium},
+			fields: func(it *entity.Item) []interface{} { return []interface{}{it.Priority} },
+		},
+		{
+			name:   "Estimate",
+			item:   &entity.Item{Title: "Release", Type: entity.ItemTypeProject, Estimate: 2 * time.Hour},
+			fields: func(it *entity.Item) []interface{} { return []interface{}{it.Type, it.Estimate} },
+		},
+	} {
+		for name, open := range formats {
+			path := filepath.Join(dir, c.name+"-"+name)
+			item := copyItem(c.item)
+			_, err := open(path).SaveItem(item)
+			assert.NoError(t, err, c.name, name)
+
+			items, err := open(path).GetItemsByParentID(entity.RootID)
+			if assert.NoError(t, err, c.name, name) && assert.Len(t, items, 1, c.name, name) {
+				assert.Equal(t, c.fields(item), c.fields(items[0]), c.name, name)
+			}
+		}
+	}
+}
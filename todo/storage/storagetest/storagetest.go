@@ -0,0 +1,875 @@
+// Package storagetest implements the conformance tests of use.Storage, which any implementation could run against
+// to prove it is compatible with the use cases.
+//
+// Errors are matched with errors.Is, an implementation returns (or wraps) the errors of package use, e.g.
+// use.ErrConflict and use.ErrItemNotFound, for the corresponding failures.
+package storagetest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+// Factory creates an empty storage for a single test, cleanup is called once the test is done, it could be nil.
+type Factory func(t *testing.T) (s use.Storage, cleanup func())
+
+// Run runs all conformance tests as subtests of t, each test runs on a new storage created by newStorage.
+func Run(t *testing.T, newStorage Factory) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			s, cleanup := newStorage(t)
+			if cleanup != nil {
+				defer cleanup()
+			}
+			c.test(t, s)
+		})
+	}
+}
+
+// eventTimeout is how long to wait for an event before a test fails.
+var eventTimeout = 5 * time.Second
+
+var cases = []struct {
+	name string
+	test func(*testing.T, use.Storage)
+}{
+	{"SaveItemIDNotZero", testSaveItemIDNotZero},
+	{"SaveItemNilErr", testSaveItemNilErr},
+	{"SaveItemUpdatesExistingItem", testSaveItemUpdatesExistingItem},
+	{"SaveItemIncreasesRevision", testSaveItemIncreasesRevision},
+	{"SaveItemRejectsStaleRevision", testSaveItemRejectsStaleRevision},
+	{"SaveItemCopiesItem", testSaveItemCopiesItem},
+	{"GetItemByID", testGetItemByID},
+	{"GetItemByIDReturnsRootItem", testGetItemByIDReturnsRootItem},
+	{"GetItemByIDErrItemNotFound", testGetItemByIDErrItemNotFound},
+	{"GetItemsByParentIDReturnsNoRootID", testGetItemsByParentIDReturnsNoRootID},
+	{"GetItemsByParentIDReturnsSortedByRank", testGetItemsByParentIDReturnsSortedByRank},
+	{"GetItemsReturnCopies", testGetItemsReturnCopies},
+	{"GetItemsDueBetweenReturnsSortedByDue", testGetItemsDueBetweenReturnsSortedByDue},
+	{"SaveItemWithRankOnlyChangesItself", testSaveItemWithRankOnlyChangesItself},
+	{"DeleteItemMovesSubtreeToTrash", testDeleteItemMovesSubtreeToTrash},
+	{"DeleteItemOnlyChangesSubtree", testDeleteItemOnlyChangesSubtree},
+	{"DeleteItemErrItemNotFound", testDeleteItemErrItemNotFound},
+	{"RestoreItem", testRestoreItem},
+	{"RestoreItemKeepsSeparateDeletions", testRestoreItemKeepsSeparateDeletions},
+	{"RestoreItemErrors", testRestoreItemErrors},
+	{"EmptyTrash", testEmptyTrash},
+	{"RunInTxCommits", testRunInTxCommits},
+	{"RunInTxRollsBack", testRunInTxRollsBack},
+	{"RunInTxNested", testRunInTxNested},
+	{"RunInTxRollsBackOnErrorOfAnyStep", testRunInTxRollsBackOnErrorOfAnyStep},
+	{"RunInTxRollsBackOnConflict", testRunInTxRollsBackOnConflict},
+	{"ConcurrentSaveItem", testConcurrentSaveItem},
+	{"ConcurrentSaveItemOfSameRevision", testConcurrentSaveItemOfSameRevision},
+	{"ConcurrentRunInTx", testConcurrentRunInTx},
+	{"LargeVolume", testLargeVolume},
+	{"SubscribeEmitsChanges", testSubscribeEmitsChanges},
+	{"SubscribeEmitsTrashChanges", testSubscribeEmitsTrashChanges},
+}
+
+func testSaveItemIDNotZero(t *testing.T, s use.Storage) {
+	id, err := s.SaveItem(&entity.Item{})
+	assert.NoError(t, err)
+	assert.NotZero(t, id)
+}
+
+func testSaveItemNilErr(t *testing.T, s use.Storage) {
+	_, err := s.SaveItem(nil)
+	assert.True(t, errors.Is(err, use.ErrNilItem), "got %v", err)
+}
+
+func testSaveItemUpdatesExistingItem(t *testing.T, s use.Storage) {
+	item := addItems(t, s)[0]
+	item.Description = "updated description"
+	item.Title = "updated title"
+	item.Rank = "d"
+	item.State = entity.ItemStateCompleted
+	item.Due = item.Due.Add(time.Second)
+
+	oldUpdatedAt := item.UpdatedAt
+	_, err := s.SaveItem(item)
+	assert.NoError(t, err)
+	newItem, err := s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	assert.True(t, newItem.UpdatedAt.After(oldUpdatedAt))
+	assert.Equal(t, item, newItem)
+}
+
+func testSaveItemIncreasesRevision(t *testing.T, s use.Storage) {
+	item := &entity.Item{}
+	_, err := s.SaveItem(item)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), item.Revision)
+
+	_, err = s.SaveItem(item)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), item.Revision)
+	got, err := s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), got.Revision)
+}
+
+func testSaveItemRejectsStaleRevision(t *testing.T, s use.Storage) {
+	item := addItems(t, s)[2]
+	first, err := s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	second, err := s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+
+	first.Title = "first writer"
+	_, err = s.SaveItem(first)
+	assert.NoError(t, err)
+
+	second.Title = "second writer"
+	_, err = s.SaveItem(second)
+	assert.True(t, errors.Is(err, use.ErrConflict), "got %v", err)
+
+	got, err := s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, first, got)
+}
+
+func testSaveItemCopiesItem(t *testing.T, s use.Storage) {
+	item := addItems(t, s)[0]
+	saved := *item
+	item.Title = "changed after saving"
+
+	got, err := s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &saved, got)
+}
+
+func testGetItemByID(t *testing.T, s use.Storage) {
+	items := addItems(t, s)
+	it, err := s.GetItemByID(items[1].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, items[1], it)
+}
+
+func testGetItemByIDReturnsRootItem(t *testing.T, s use.Storage) {
+	it, err := s.GetItemByID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.RootItem, it)
+}
+
+func testGetItemByIDErrItemNotFound(t *testing.T, s use.Storage) {
+	_, err := s.GetItemByID(42)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+}
+
+func testGetItemsByParentIDReturnsNoRootID(t *testing.T, s use.Storage) {
+	addItems(t, s)
+	items, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, items)
+	for _, it := range items {
+		assert.NotEqual(t, it.ID, entity.RootID)
+	}
+}
+
+func testGetItemsByParentIDReturnsSortedByRank(t *testing.T, s use.Storage) {
+	// Add duplicate items to break the order, items of the same rank are sorted by ID
+	addItems(t, s)
+	addItems(t, s)
+	_, err := s.SaveItem(&entity.Item{Title: "first", ParentItemID: entity.RootID, Rank: "1"})
+	assert.NoError(t, err)
+
+	items, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Len(t, items, 5)
+	assert.Equal(t, "first", items[0].Title)
+	assertSorted(t, items)
+}
+
+func testGetItemsDueBetweenReturnsSortedByDue(t *testing.T, s use.Storage) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	var items []*entity.Item
+	for i, due := range []time.Time{day.AddDate(0, 0, 1), day, day.AddDate(0, 0, 2), day} {
+		it := &entity.Item{Title: fmt.Sprintf("due%d", i), ParentItemID: entity.RootID, Due: due}
+		if i == 3 {
+			it.ParentItemID = items[0].ID
+		}
+		_, err := s.SaveItem(it)
+		assert.NoError(t, err)
+		items = append(items, it)
+	}
+	assertDue := func(want ...*entity.Item) {
+		t.Helper()
+		got, err := s.GetItemsDueBetween(day, day.AddDate(0, 0, 2))
+		assert.NoError(t, err)
+		var ids []int64
+		for _, it := range got {
+			ids = append(ids, it.ID)
+		}
+		var wantIDs []int64
+		for _, it := range want {
+			wantIDs = append(wantIDs, it.ID)
+		}
+		assert.Equal(t, wantIDs, ids)
+	}
+	assertDue(items[1], items[3], items[0])
+
+	// items in the trash are not due, neither are their descendants
+	assert.NoError(t, s.DeleteItem(items[0].ID))
+	assertDue(items[1])
+}
+
+func testGetItemsReturnCopies(t *testing.T, s use.Storage) {
+	item := addItems(t, s)[0]
+
+	got, err := s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	got.Title = "changed without saving"
+	items, err := s.GetItemsByParentID(item.ParentItemID)
+	assert.NoError(t, err)
+	items[0].Rank += "1"
+	root, err := s.GetItemByID(entity.RootID)
+	assert.NoError(t, err)
+	root.Title = "changed root"
+
+	got, err = s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, item, got)
+	assert.Equal(t, "", entity.RootItem.Title)
+}
+
+func testSaveItemWithRankOnlyChangesItself(t *testing.T, s use.Storage) {
+	parent := addItems(t, s)[0]
+	var children []*entity.Item
+	for i, rank := range []string{"a", "c", "e", "g"} {
+		it := &entity.Item{Title: fmt.Sprintf("child%d", i+1), ParentItemID: parent.ID, Rank: rank}
+		_, err := s.SaveItem(it)
+		assert.NoError(t, err)
+		children = append(children, it)
+	}
+	before, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+
+	inserted := &entity.Item{Title: "inserted", ParentItemID: parent.ID, Rank: "d"}
+	_, err = s.SaveItem(inserted)
+	assert.NoError(t, err)
+
+	got, err := s.GetItemsByParentID(parent.ID)
+	assert.NoError(t, err)
+	var titles []string
+	for _, it := range got {
+		titles = append(titles, it.Title)
+	}
+	assert.Equal(t, []string{"child1", "child2", "inserted", "child3", "child4", "sub1"}, titles)
+	assertUnchanged(t, s, children)
+
+	after, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after, "items of other parents are untouched")
+}
+
+func testDeleteItemMovesSubtreeToTrash(t *testing.T, s use.Storage) {
+	items := addItems(t, s)
+	assert.NoError(t, s.DeleteItem(items[0].ID))
+
+	for _, it := range []*entity.Item{items[0], items[2]} {
+		_, err := s.GetItemByID(it.ID)
+		assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+	}
+	children, err := s.GetItemsByParentID(items[0].ID)
+	assert.NoError(t, err)
+	assert.Empty(t, children)
+	top, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	if assert.Len(t, top, 1) {
+		assert.Equal(t, items[1].ID, top[0].ID)
+	}
+	// an item in the trash could not be saved
+	_, err = s.SaveItem(items[2])
+	assert.Error(t, err)
+	assertUnchanged(t, s, items[3:])
+}
+
+func testDeleteItemOnlyChangesSubtree(t *testing.T, s use.Storage) {
+	parent := addItems(t, s)[0]
+	var children []*entity.Item
+	for i, rank := range []string{"j", "k", "l"} {
+		it := &entity.Item{Title: fmt.Sprintf("child%d", i+2), ParentItemID: parent.ID, Rank: rank}
+		_, err := s.SaveItem(it)
+		assert.NoError(t, err)
+		children = append(children, it)
+	}
+	assert.NoError(t, s.DeleteItem(children[0].ID))
+
+	got, err := s.GetItemsByParentID(parent.ID)
+	assert.NoError(t, err)
+	var titles []string
+	for _, it := range got {
+		titles = append(titles, it.Title)
+	}
+	assert.Equal(t, []string{"sub1", "child3", "child4"}, titles)
+	assertUnchanged(t, s, children[1:])
+}
+
+func testDeleteItemErrItemNotFound(t *testing.T, s use.Storage) {
+	err := s.DeleteItem(42)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+
+	item := addItems(t, s)[0]
+	assert.NoError(t, s.DeleteItem(item.ID))
+	err = s.DeleteItem(item.ID)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+}
+
+func testRestoreItem(t *testing.T, s use.Storage) {
+	items := addItems(t, s)
+	assert.NoError(t, s.DeleteItem(items[0].ID))
+	assert.NoError(t, s.RestoreItem(items[0].ID))
+
+	top, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	if assert.Len(t, top, 2) {
+		assert.Equal(t, items[0].ID, top[0].ID)
+		assert.Equal(t, items[0].Rank, top[0].Rank)
+		assert.Equal(t, items[1].ID, top[1].ID)
+		assert.Equal(t, items[1].Rank, top[1].Rank)
+	}
+	for _, it := range items {
+		got, err := s.GetItemByID(it.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, it.Title, got.Title)
+		assert.True(t, got.DeletedAt.IsZero())
+		assert.Zero(t, got.DeletedWithItemID)
+	}
+	children, err := s.GetItemsByParentID(items[0].ID)
+	assert.NoError(t, err)
+	assert.Len(t, children, 1)
+}
+
+func testRestoreItemKeepsSeparateDeletions(t *testing.T, s use.Storage) {
+	items := addItems(t, s)
+	// the child is deleted before its parent
+	assert.NoError(t, s.DeleteItem(items[2].ID))
+	assert.NoError(t, s.DeleteItem(items[0].ID))
+
+	assert.NoError(t, s.RestoreItem(items[0].ID))
+	_, err := s.GetItemByID(items[2].ID)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+
+	assert.NoError(t, s.RestoreItem(items[2].ID))
+	_, err = s.GetItemByID(items[2].ID)
+	assert.NoError(t, err)
+}
+
+func testRestoreItemErrors(t *testing.T, s use.Storage) {
+	items := addItems(t, s)
+	err := s.RestoreItem(42)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+	err = s.RestoreItem(items[0].ID)
+	assert.True(t, errors.Is(err, use.ErrItemNotDeleted), "got %v", err)
+
+	assert.NoError(t, s.DeleteItem(items[0].ID))
+	// deleted along with the parent
+	err = s.RestoreItem(items[2].ID)
+	assert.True(t, errors.Is(err, use.ErrParentDeleted), "got %v", err)
+
+	// the parent is deleted after the child
+	assert.NoError(t, s.DeleteItem(items[3].ID))
+	assert.NoError(t, s.DeleteItem(items[1].ID))
+	err = s.RestoreItem(items[3].ID)
+	assert.True(t, errors.Is(err, use.ErrParentDeleted), "got %v", err)
+}
+
+func testEmptyTrash(t *testing.T, s use.Storage) {
+	items := addItems(t, s)
+	n, err := s.EmptyTrash()
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	assert.NoError(t, s.DeleteItem(items[0].ID))
+	n, err = s.EmptyTrash()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	err = s.RestoreItem(items[0].ID)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+	err = s.RestoreItem(items[2].ID)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+	top, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Len(t, top, 1)
+	n, err = s.EmptyTrash()
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func testRunInTxCommits(t *testing.T, s use.Storage) {
+	var item *entity.Item
+	err := s.RunInTx(func(tx use.Storage) error {
+		item = addItems(t, tx)[0]
+		item.Rank = "z"
+		_, err := tx.SaveItem(item)
+		return err
+	})
+	assert.NoError(t, err)
+
+	got, err := s.GetItemByID(item.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, item, got)
+	items, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, item.ID, items[1].ID)
+	}
+}
+
+func testRunInTxRollsBack(t *testing.T, s use.Storage) {
+	existing := addItems(t, s)
+	var added *entity.Item
+	err := s.RunInTx(func(tx use.Storage) error {
+		added = &entity.Item{Title: "rolled back", ParentItemID: entity.RootID, Rank: "1"}
+		if _, err := tx.SaveItem(added); err != nil {
+			return err
+		}
+		moved := *existing[0]
+		moved.Rank = "z"
+		if _, err := tx.SaveItem(&moved); err != nil {
+			return err
+		}
+		changed := *existing[2]
+		changed.Title = "rolled back"
+		if _, err := tx.SaveItem(&changed); err != nil {
+			return err
+		}
+		return io.EOF
+	})
+	assert.Equal(t, io.EOF, err)
+
+	_, err = s.GetItemByID(added.ID)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+	assertUnchanged(t, s, existing)
+}
+
+func testRunInTxNested(t *testing.T, s use.Storage) {
+	var kept, discarded *entity.Item
+	err := s.RunInTx(func(tx use.Storage) error {
+		kept = &entity.Item{Title: "kept"}
+		if _, err := tx.SaveItem(kept); err != nil {
+			return err
+		}
+		err := tx.RunInTx(func(nested use.Storage) error {
+			discarded = &entity.Item{Title: "discarded"}
+			if _, err := nested.SaveItem(discarded); err != nil {
+				return err
+			}
+			return io.EOF
+		})
+		assert.Equal(t, io.EOF, err)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	_, err = s.GetItemByID(kept.ID)
+	assert.NoError(t, err)
+	_, err = s.GetItemByID(discarded.ID)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+}
+
+func testRunInTxRollsBackOnErrorOfAnyStep(t *testing.T, s use.Storage) {
+	existing := addItems(t, s)
+	steps := []func(tx use.Storage) error{
+		func(tx use.Storage) error {
+			_, err := tx.SaveItem(&entity.Item{Title: "new", ParentItemID: existing[0].ID, Rank: "1"})
+			return err
+		},
+		func(tx use.Storage) error {
+			it, err := tx.GetItemByID(existing[2].ID)
+			if err != nil {
+				return err
+			}
+			it.Rank = "z"
+			_, err = tx.SaveItem(it)
+			return err
+		},
+		func(tx use.Storage) error {
+			it, err := tx.GetItemByID(existing[1].ID)
+			if err != nil {
+				return err
+			}
+			it.Title = "changed"
+			_, err = tx.SaveItem(it)
+			return err
+		},
+		func(tx use.Storage) error {
+			return tx.RunInTx(func(nested use.Storage) error {
+				_, err := nested.SaveItem(&entity.Item{Title: "nested"})
+				return err
+			})
+		},
+		func(tx use.Storage) error {
+			return tx.DeleteItem(existing[1].ID)
+		},
+	}
+	injected := errors.New("injected")
+	// the error is injected after each step in turn
+	for failAt := range steps {
+		err := s.RunInTx(func(tx use.Storage) error {
+			for i, step := range steps {
+				if err := step(tx); err != nil {
+					return err
+				}
+				if i == failAt {
+					return injected
+				}
+			}
+			return nil
+		})
+		assert.Equal(t, injected, err)
+		assertUnchanged(t, s, existing)
+		// neither the new item nor the nested one is kept
+		children, err := s.GetItemsByParentID(existing[0].ID)
+		assert.NoError(t, err)
+		assert.Len(t, children, 1, "failing at step %d", failAt)
+		top, err := s.GetItemsByParentID(entity.RootID)
+		assert.NoError(t, err)
+		assert.Len(t, top, 2, "failing at step %d", failAt)
+	}
+}
+
+func testRunInTxRollsBackOnConflict(t *testing.T, s use.Storage) {
+	existing := addItems(t, s)
+	stale := *existing[3]
+	changed := *existing[3]
+	changed.Title = "changed"
+	_, err := s.SaveItem(&changed)
+	assert.NoError(t, err)
+	existing[3] = &changed
+
+	var added *entity.Item
+	err = s.RunInTx(func(tx use.Storage) error {
+		added = &entity.Item{Title: "added"}
+		if _, err := tx.SaveItem(added); err != nil {
+			return err
+		}
+		_, err := tx.SaveItem(&stale)
+		return err
+	})
+	assert.True(t, errors.Is(err, use.ErrConflict), "got %v", err)
+	_, err = s.GetItemByID(added.ID)
+	assert.True(t, errors.Is(err, use.ErrItemNotFound), "got %v", err)
+	assertUnchanged(t, s, existing)
+}
+
+func testConcurrentSaveItem(t *testing.T, s use.Storage) {
+	const workers, rounds = 4, 10
+	parent := addItems(t, s)[0]
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				item := &entity.Item{Title: "concurrent", ParentItemID: parent.ID, Rank: "1"}
+				_, err := s.SaveItem(item)
+				assert.NoError(t, err)
+				_, err = s.GetItemsByParentID(parent.ID)
+				assert.NoError(t, err)
+				_, err = s.GetItemByID(item.ID)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	items, err := s.GetItemsByParentID(parent.ID)
+	assert.NoError(t, err)
+	// the existing child plus all the new ones
+	assert.Len(t, items, workers*rounds+1)
+	ids := make(map[int64]bool)
+	for _, it := range items {
+		ids[it.ID] = true
+	}
+	assert.Len(t, ids, len(items))
+}
+
+func testConcurrentSaveItemOfSameRevision(t *testing.T, s use.Storage) {
+	const workers = 8
+	item := addItems(t, s)[2]
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var saved []string
+	for w := 0; w < workers; w++ {
+		copied := *item
+		copied.Title = fmt.Sprintf("writer%d", w)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.SaveItem(&copied)
+			if errors.Is(err, use.ErrConflict) {
+				return
+			}
+			assert.NoError(t, err)
+			mu.Lock()
+			saved = append(saved, copied.Title)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// only one of the writers of the same revision wins
+	if assert.Len(t, saved, 1) {
+		got, err := s.GetItemByID(item.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, saved[0], got.Title)
+		assert.Equal(t, item.Revision+1, got.Revision)
+	}
+}
+
+func testConcurrentRunInTx(t *testing.T, s use.Storage) {
+	const workers, rounds = 4, 5
+	counter := &entity.Item{Title: "0"}
+	_, err := s.SaveItem(counter)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				// a transaction is retried on conflicts, but no increment is lost
+				for {
+					err := s.RunInTx(func(tx use.Storage) error {
+						it, err := tx.GetItemByID(counter.ID)
+						if err != nil {
+							return err
+						}
+						n, err := strconv.Atoi(it.Title)
+						if err != nil {
+							return err
+						}
+						it.Title = strconv.Itoa(n + 1)
+						_, err = tx.SaveItem(it)
+						return err
+					})
+					if !errors.Is(err, use.ErrConflict) {
+						assert.NoError(t, err)
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.GetItemByID(counter.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(workers*rounds), got.Title)
+}
+
+func testLargeVolume(t *testing.T, s use.Storage) {
+	parents, children := 20, 100
+	if testing.Short() {
+		parents, children = 5, 20
+	}
+	var ids []int64
+	parentRanks, childRanks := entity.SpreadRanks(parents), entity.SpreadRanks(children)
+	err := s.RunInTx(func(tx use.Storage) error {
+		for p := 0; p < parents; p++ {
+			parent := &entity.Item{Title: fmt.Sprintf("parent%d", p), Rank: parentRanks[p], Type: entity.ItemTypeCategory}
+			if _, err := tx.SaveItem(parent); err != nil {
+				return err
+			}
+			ids = append(ids, parent.ID)
+			// saved in reverse order
+			for c := children - 1; c >= 0; c-- {
+				child := &entity.Item{Title: fmt.Sprintf("child%d", c), ParentItemID: parent.ID, Rank: childRanks[c]}
+				if _, err := tx.SaveItem(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	top, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	assert.Len(t, top, parents)
+	assertSorted(t, top)
+	all := make(map[int64]bool)
+	for _, id := range ids {
+		items, err := s.GetItemsByParentID(id)
+		assert.NoError(t, err)
+		assert.Len(t, items, children)
+		assertSorted(t, items)
+		for _, it := range items {
+			all[it.ID] = true
+		}
+	}
+	assert.Len(t, all, parents*children, "IDs are unique")
+
+	// changes after the bulk are kept along with it
+	first, err := s.GetItemsByParentID(ids[0])
+	assert.NoError(t, err)
+	moved := first[0]
+	moved.Rank, _ = entity.RankBetween(first[1].Rank, first[2].Rank)
+	_, err = s.SaveItem(moved)
+	assert.NoError(t, err)
+	got, err := s.GetItemsByParentID(ids[0])
+	assert.NoError(t, err)
+	assert.Equal(t, moved.ID, got[1].ID)
+	last, err := s.GetItemsByParentID(ids[len(ids)-1])
+	assert.NoError(t, err)
+	assert.Equal(t, "child0", last[0].Title)
+}
+
+func testSubscribeEmitsChanges(t *testing.T, s use.Storage) {
+	events, cancel := s.Subscribe()
+	items := addItems(t, s)
+	for _, it := range items {
+		assertItemEvent(t, events, use.ItemCreated, it.ID)
+	}
+
+	items[0].Rank = "z"
+	_, err := s.SaveItem(items[0])
+	assert.NoError(t, err)
+	assertItemEvent(t, events, use.ItemReordered, items[0].ID)
+
+	items[0].Title = "updated"
+	_, err = s.SaveItem(items[0])
+	assert.NoError(t, err)
+	e := assertItemEvent(t, events, use.ItemUpdated, items[0].ID)
+	assert.Equal(t, int64(entity.RootID), e.PreviousParentItemID)
+
+	// a moved item tells where it is from
+	items[2].ParentItemID = items[1].ID
+	_, err = s.SaveItem(items[2])
+	assert.NoError(t, err)
+	e = assertItemEvent(t, events, use.ItemUpdated, items[2].ID)
+	assert.Equal(t, items[0].ID, e.PreviousParentItemID)
+	assert.Equal(t, items[1].ID, e.Item.ParentItemID)
+
+	// changes within a transaction are emitted once it is committed, nothing for a rolled back one
+	_ = s.RunInTx(func(tx use.Storage) error {
+		_, err := tx.SaveItem(&entity.Item{Title: "discarded"})
+		assert.NoError(t, err)
+		return io.EOF
+	})
+	var added int64
+	err = s.RunInTx(func(tx use.Storage) error {
+		added, err = tx.SaveItem(&entity.Item{Title: "added"})
+		return err
+	})
+	assert.NoError(t, err)
+	e = assertItemEvent(t, events, use.ItemCreated, added)
+	assert.Equal(t, "added", e.Item.Title)
+	assert.Equal(t, int64(entity.RootID), e.PreviousParentItemID)
+
+	cancel()
+	for range events {
+		// drained until closed
+	}
+}
+
+func testSubscribeEmitsTrashChanges(t *testing.T, s use.Storage) {
+	items := addItems(t, s)
+	events, cancel := s.Subscribe()
+	defer cancel()
+
+	assert.NoError(t, s.DeleteItem(items[0].ID))
+	assert.Equal(t, map[int64]use.ItemEventType{
+		items[0].ID: use.ItemDeleted,
+		items[2].ID: use.ItemDeleted,
+	}, receiveItemEvents(t, events, 2))
+
+	assert.NoError(t, s.RestoreItem(items[0].ID))
+	assert.Equal(t, map[int64]use.ItemEventType{
+		items[0].ID: use.ItemRestored,
+		items[2].ID: use.ItemRestored,
+	}, receiveItemEvents(t, events, 2))
+
+	assert.NoError(t, s.DeleteItem(items[3].ID))
+	assertItemEvent(t, events, use.ItemDeleted, items[3].ID)
+	_, err := s.EmptyTrash()
+	assert.NoError(t, err)
+	e := assertItemEvent(t, events, use.ItemRemoved, items[3].ID)
+	assert.Equal(t, items[3].Title, e.Item.Title)
+}
+
+// receiveItemEvents receives n events, which are of a single change in no particular order, returns their types by
+// item IDs.
+func receiveItemEvents(t *testing.T, events <-chan use.ItemEvent, n int) map[int64]use.ItemEventType {
+	types := make(map[int64]use.ItemEventType, n)
+	for i := 0; i < n; i++ {
+		select {
+		case e := <-events:
+			types[e.Item.ID] = e.Type
+		case <-time.After(eventTimeout):
+			t.Fatalf("%d of %d events received", i, n)
+		}
+	}
+	return types
+}
+
+// assertItemEvent asserts the next event is of given type and item.
+func assertItemEvent(t *testing.T, events <-chan use.ItemEvent, typ use.ItemEventType, id int64) use.ItemEvent {
+	select {
+	case e := <-events:
+		assert.Equal(t, typ, e.Type)
+		assert.Equal(t, id, e.Item.ID)
+		return e
+	case <-time.After(eventTimeout):
+		t.Fatalf("no event of item[%d]", id)
+		return use.ItemEvent{}
+	}
+}
+
+// assertUnchanged asserts items are stored as given.
+func assertUnchanged(t *testing.T, s use.Storage, items []*entity.Item) {
+	for _, it := range items {
+		got, err := s.GetItemByID(it.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, it, got)
+	}
+}
+
+// assertSorted asserts items are sorted by rank, then by ID.
+func assertSorted(t *testing.T, items []*entity.Item) {
+	assert.True(t, sort.SliceIsSorted(items, func(i int, j int) bool {
+		if items[i].Rank != items[j].Rank {
+			return items[i].Rank < items[j].Rank
+		}
+		return items[i].ID < items[j].ID
+	}))
+}
+
+// addItems adds two categories with a task in each.
+func addItems(t *testing.T, s use.Storage) []*entity.Item {
+	t1 := &entity.Item{Title: "top1", ParentItemID: entity.RootID, Rank: "c", Type: entity.ItemTypeCategory}
+	t1ID, err := s.SaveItem(t1)
+	assert.NoError(t, err)
+
+	t2 := &entity.Item{Title: "top2", ParentItemID: entity.RootID, Rank: "o", Type: entity.ItemTypeCategory}
+	t2ID, err := s.SaveItem(t2)
+	assert.NoError(t, err)
+
+	s1 := &entity.Item{Title: "sub1", ParentItemID: t1ID, Rank: "i", Type: entity.ItemTypeTask}
+	_, err = s.SaveItem(s1)
+	assert.NoError(t, err)
+
+	s2 := &entity.Item{Title: "sub2", ParentItemID: t2ID, Rank: "i", Type: entity.ItemTypeTask}
+	_, err = s.SaveItem(s2)
+	assert.NoError(t, err)
+
+	return []*entity.Item{t1, t2, s1, s2}
+}
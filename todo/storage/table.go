@@ -0,0 +1,396 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use"
+)
+
+// itemTable is a plain set of items shared by file based storages, which load it from and write it to their own
+// formats. Items are stored as copies, no pointer is shared with callers.
+//
+// Lookups go through an index kept up to date by the methods of the table, a table whose Items are set directly
+// must be reindexed before use, so that reading a table never changes it. Items in the table are never changed in
+// place but replaced with changed copies, which keeps the ones recorded by a changeLog as they were.
+type itemTable struct {
+	NextID int64
+	Items  []*entity.Item
+
+	index *tableIndex
+	// changes records the changes made to the table, nil if not recording.
+	changes *changeLog
+}
+
+func newItemTable() *itemTable {
+	t := &itemTable{NextID: 1, Items: []*entity.Item{}}
+	t.reindex()
+	return t
+}
+
+// reindex builds the index from Items.
+func (t *itemTable) reindex() {
+	t.index = newTableIndex(t.Items)
+}
+
+// clone returns a deep copy of the table.
+func (t *itemTable) clone() *itemTable {
+	c := &itemTable{NextID: t.NextID, Items: make([]*entity.Item, len(t.Items))}
+	for i, it := range t.Items {
+		c.Items[i] = copyItem(it)
+	}
+	c.index = t.index.clone(c.Items)
+	return c
+}
+
+// track records the changes made to the table by fn, which are rolled back if fn fails or panics. Changes of a
+// successful fn are also recorded by the log the table was recording into, if any, so calls to track could be nested.
+func (t *itemTable) track(fn func() error) (*changeLog, error) {
+	parent := t.changes
+	changes := &changeLog{nextID: t.NextID, before: make(map[int64]*entity.Item)}
+	t.changes = changes
+	committed := false
+	defer func() {
+		t.changes = nil
+		if !committed {
+			changes.rollback(t)
+		}
+		t.changes = parent
+	}()
+	if err := fn(); err != nil {
+		return nil, err
+	}
+	committed = true
+	parent.merge(changes)
+	return changes, nil
+}
+
+// saveItem inserts or replaces an item, a new ID is assigned to item if it does not exist yet.
+// The revision of item is increased, an existing item is replaced only if item is based on its latest revision.
+func (t *itemTable) saveItem(item *entity.Item) error {
+	if item.ID > 0 {
+		if it := t.find(item.ID); it != nil {
+			if isDeleted(it) {
+				return ErrItemNotFound
+			}
+			if item.Revision != it.Revision {
+				return &ConflictError{ItemID: item.ID, Revision: item.Revision, Latest: it.Revision}
+			}
+			item.Revision++
+			t.replace(copyItem(item))
+			return nil
+		}
+	}
+
+	// item does not exist
+	item.ID = t.NextID
+	item.Revision = 1
+	t.append(copyItem(item))
+	t.NextID++
+	return nil
+}
+
+// putItem inserts or replaces an item keeping its ID.
+func (t *itemTable) putItem(item *entity.Item) {
+	if t.find(item.ID) != nil {
+		t.replace(copyItem(item))
+		return
+	}
+	t.append(copyItem(item))
+	if item.ID >= t.NextID {
+		t.NextID = item.ID + 1
+	}
+}
+
+// replace puts it in place of the item of the same ID, which must exist.
+func (t *itemTable) replace(it *entity.Item) {
+	x := t.index
+	i := x.byID[it.ID]
+	t.changes.record(it.ID, t.Items[i])
+	x.drop(t.Items[i])
+	t.Items[i] = it
+	x.insert(it)
+}
+
+// append adds it to the table, its ID must not exist yet.
+func (t *itemTable) append(it *entity.Item) {
+	x := t.index
+	t.changes.record(it.ID, nil)
+	x.byID[it.ID] = len(t.Items)
+	t.Items = append(t.Items, it)
+	x.insert(it)
+}
+
+// update replaces the item of given ID, which must exist, with a copy changed by fn.
+func (t *itemTable) update(id int64, fn func(it *entity.Item)) {
+	it := copyItem(t.find(id))
+	fn(it)
+	t.replace(it)
+}
+
+// deleteItem moves the item of given ID and its descendants to the trash.
+func (t *itemTable) deleteItem(id int64, now time.Time) error {
+	item := t.find(id)
+	if item == nil || isDeleted(item) {
+		return ErrItemNotFound
+	}
+	// descendants already in the trash stay with their own deletion
+	for subtree := []int64{id}; len(subtree) > 0; subtree = subtree[1:] {
+		if isDeleted(t.find(subtree[0])) {
+			// in a cycle
+			continue
+		}
+		for _, child := range t.index.children[subtree[0]] {
+			subtree = append(subtree, child.ID)
+		}
+		t.update(subtree[0], func(it *entity.Item) {
+			it.DeletedAt, it.DeletedWithItemID, it.UpdatedAt = now, id, now
+			it.Revision++
+		})
+	}
+	return nil
+}
+
+// restoreItem moves the item of given ID back from the trash along with the descendants deleted with it, the item
+// goes back to its rank among its siblings.
+func (t *itemTable) restoreItem(id int64, now time.Time) error {
+	item := t.find(id)
+	if item == nil {
+		return ErrItemNotFound
+	}
+	if !isDeleted(item) {
+		return ErrItemNotDeleted
+	}
+	if item.DeletedWithItemID != item.ID {
+		return ErrParentDeleted
+	}
+	// an item whose parent does not exist is kept as an orphan, as it was before the deletion
+	if parent := t.find(item.ParentItemID); parent != nil && isDeleted(parent) {
+		return ErrParentDeleted
+	}
+	for _, it := range t.Items {
+		if isDeleted(it) && it.DeletedWithItemID == id {
+			t.update(it.ID, func(it *entity.Item) {
+				it.DeletedAt, it.DeletedWithItemID, it.UpdatedAt = time.Time{}, 0, now
+				it.Revision++
+			})
+		}
+	}
+	return nil
+}
+
+// emptyTrash removes items in the trash, returns the removed items.
+func (t *itemTable) emptyTrash() []*entity.Item {
+	x := t.index
+	var removed []*entity.Item
+	items := t.Items[:0]
+	for _, it := range t.Items {
+		if isDeleted(it) {
+			t.changes.record(it.ID, it)
+			removed = append(removed, it)
+			delete(x.byID, it.ID)
+		} else {
+			x.byID[it.ID] = len(items)
+			items = append(items, it)
+		}
+	}
+	t.Items = items
+	return removed
+}
+
+// removeItem removes the item of given ID from the table, returns the removed item or nil if not found.
+func (t *itemTable) removeItem(id int64) *entity.Item {
+	x := t.index
+	i, ok := x.byID[id]
+	if !ok {
+		return nil
+	}
+	it := t.Items[i]
+	t.changes.record(id, it)
+	x.drop(it)
+	delete(x.byID, id)
+	t.Items = append(t.Items[:i], t.Items[i+1:]...)
+	for ; i < len(t.Items); i++ {
+		x.byID[t.Items[i].ID] = i
+	}
+	return it
+}
+
+// find returns the item of given ID in the table rather than a copy, or nil if not found.
+func (t *itemTable) find(id int64) *entity.Item {
+	if i, ok := t.index.byID[id]; ok {
+		return t.Items[i]
+	}
+	return nil
+}
+
+// itemsByParentID returns copies of items of given parent sorted by rank, items of the same rank are sorted by ID.
+func (t *itemTable) itemsByParentID(parentID int64) []*entity.Item {
+	return copyItems(t.index.children[parentID])
+}
+
+// itemByID returns a copy of the item of given ID.
+func (t *itemTable) itemByID(id int64) (*entity.Item, error) {
+	if id == entity.RootID {
+		return copyItem(entity.RootItem), nil
+	}
+	if it := t.find(id); it != nil && !isDeleted(it) {
+		return copyItem(it), nil
+	}
+	return nil, ErrItemNotFound
+}
+
+// itemsDueBetween returns copies of items due in [from, to) sorted by due, items of the same due are sorted by ID.
+func (t *itemTable) itemsDueBetween(from, to time.Time) []*entity.Item {
+	return copyItems(t.index.dueBetween(from, to))
+}
+
+func copyItems(items []*entity.Item) []*entity.Item {
+	copied := make([]*entity.Item, len(items))
+	for i, it := range items {
+		copied[i] = copyItem(it)
+	}
+	return copied
+}
+
+// isDeleted reports whether an item is in the trash.
+func isDeleted(it *entity.Item) bool {
+	return !it.DeletedAt.IsZero()
+}
+
+// touchItem updates timestamps of an item which is about to be saved.
+func touchItem(item *entity.Item) {
+	now := time.Now().UTC()
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = now
+	}
+	item.UpdatedAt = now
+}
+
+// changeLog records items of a table as they were before their first changes since the log started.
+type changeLog struct {
+	nextID int64
+	// before are the items by their IDs, nil if an item did not exist.
+	before map[int64]*entity.Item
+	// ids are the IDs of changed items in the order of their first changes.
+	ids []int64
+}
+
+// record records before as the item of given ID before its first change, nothing is done on a nil log.
+func (l *changeLog) record(id int64, before *entity.Item) {
+	if l == nil {
+		return
+	}
+	if _, ok := l.before[id]; ok {
+		return
+	}
+	l.before[id] = before
+	l.ids = append(l.ids, id)
+}
+
+// merge records the changes recorded by nested, which started after l.
+func (l *changeLog) merge(nested *changeLog) {
+	for _, id := range nested.ids {
+		l.record(id, nested.before[id])
+	}
+}
+
+// rollback puts the recorded items back into t, which must not be recording.
+func (l *changeLog) rollback(t *itemTable) {
+	for i := len(l.ids) - 1; i >= 0; i-- {
+		id := l.ids[i]
+		before, now := l.before[id], t.find(id)
+		switch {
+		case before == nil:
+			if now != nil {
+				t.removeItem(id)
+			}
+		case now == nil:
+			t.append(before)
+		default:
+			t.replace(before)
+		}
+	}
+	t.NextID = l.nextID
+}
+
+// events returns events of the recorded changes to the items now in t, followed by those of removed items.
+func (l *changeLog) events(t *itemTable) []use.ItemEvent {
+	var events, removed []use.ItemEvent
+	for _, id := range l.ids {
+		before, now := l.before[id], t.find(id)
+		if now == nil {
+			if before != nil {
+				removed = append(removed, removedItemEvent(before))
+			}
+			continue
+		}
+		if e, ok := itemEvent(before, now); ok {
+			events = append(events, e)
+		}
+	}
+	return append(events, removed...)
+}
+
+// tableTx is a transaction on an itemTable, changes are made to the table directly, the owner of the table is
+// responsible for tracking them with itemTable.track, as well as publishing them to feed.
+type tableTx struct {
+	table *itemTable
+	feed  *feed
+}
+
+// SaveItem saves an item into the table, return its id.
+func (tx *tableTx) SaveItem(item *entity.Item) (int64, error) {
+	if item == nil {
+		return -1, ErrNilItem
+	}
+	touchItem(item)
+	if err := tx.table.saveItem(item); err != nil {
+		return -1, err
+	}
+	return item.ID, nil
+}
+
+// DeleteItem moves the item of given ID and its descendants to the trash.
+func (tx *tableTx) DeleteItem(id int64) error {
+	return tx.table.deleteItem(id, time.Now().UTC())
+}
+
+// RestoreItem moves the item of given ID back from the trash.
+func (tx *tableTx) RestoreItem(id int64) error {
+	return tx.table.restoreItem(id, time.Now().UTC())
+}
+
+// EmptyTrash removes items in the trash, returns the number of removed items.
+func (tx *tableTx) EmptyTrash() (int, error) {
+	return len(tx.table.emptyTrash()), nil
+}
+
+// GetItemsByParentID returns items of given parent.
+func (tx *tableTx) GetItemsByParentID(parentID int64) ([]*entity.Item, error) {
+	return tx.table.itemsByParentID(parentID), nil
+}
+
+// GetItemByID returns items of given ID.
+func (tx *tableTx) GetItemByID(id int64) (*entity.Item, error) {
+	return tx.table.itemByID(id)
+}
+
+// GetItemsDueBetween returns items due in [from, to).
+func (tx *tableTx) GetItemsDueBetween(from, to time.Time) ([]*entity.Item, error) {
+	return tx.table.itemsDueBetween(from, to), nil
+}
+
+// Subscribe subscribes to the owner of the transaction, changes made within the transaction are emitted after it
+// is committed.
+func (tx *tableTx) Subscribe() (<-chan use.ItemEvent, func()) {
+	return tx.feed.subscribe()
+}
+
+// RunInTx runs fn in a nested transaction, which is rolled back alone if fn returns an error.
+func (tx *tableTx) RunInTx(fn func(use.Storage) error) error {
+	_, err := tx.table.track(func() error {
+		return fn(tx)
+	})
+	return err
+}
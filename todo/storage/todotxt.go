@@ -0,0 +1,405 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// TodoTxt is a storage that reads and writes a file in the todo.txt format(https://github.com/todotxt/todo.txt).
+//
+// Each item is a line, fields which the format does not have are kept as key:value extensions, e.g.
+//
+//	(A) 2020-01-02 Call mom +family @phone due:2020-01-03 id:3 parent:1 rank:i
+//
+// Title keeps +project, @context and key:value tokens which are not known to the store as they are, a word of the
+// title which would be read as anything but the title, e.g. a known key:value, is escaped with a leading "\". Blank
+// lines and lines starting with # are kept as they are as well. Lines without an id are taken as new tasks under the
+// root, their IDs are written to the file on the next change.
+type TodoTxt struct {
+	fileStore
+}
+
+// NewTodoTxt creates a TodoTxt with given file path, a lock file is created next to the file.
+func NewTodoTxt(path string) *TodoTxt {
+	return &TodoTxt{fileStore{
+		file:     path,
+		lockFile: path + ".lock",
+		format:   todoTxtFormat{},
+	}}
+}
+
+// Extension keys known to TodoTxt.
+const (
+	todoTxtKeyID          = "id"
+	todoTxtKeyParent      = "parent"
+	todoTxtKeyRank        = "rank"
+	todoTxtKeyRevision    = "rev"
+	todoTxtKeyType        = "type"
+	todoTxtKeyDue         = "due"
+	todoTxtKeyRecurrence  = "rrule"
+	todoTxtKeyReminders   = "remind"
+	todoTxtKeyTags        = "tags"
+	todoTxtKeyEstimate    = "est"
+	todoTxtKeyDescription = "desc"
+	todoTxtKeyCreated     = "created"
+	todoTxtKeyUpdated     = "updated"
+	todoTxtKeyCompleted   = "completed"
+	todoTxtKeyPriority    = "pri"
+	todoTxtKeyDeleted     = "deleted"
+	todoTxtKeyDeletedWith = "deletedwith"
+	// todoTxtKeyOrder is only read from files written before ranks, it is written as rank.
+	todoTxtKeyOrder = "order"
+)
+
+const (
+	todoTxtDateLayout = "2006-01-02"
+	todoTxtEscape     = `\`
+)
+
+var todoTxtItemTypes = map[entity.ItemType]string{
+	entity.ItemTypeTask:     "task",
+	entity.ItemTypeProject:  "project",
+	entity.ItemTypeCategory: "category",
+	entity.ItemTypeView:     "view",
+}
+
+// todoTxtLine is a line of a todo.txt file, raw is kept for lines which are not items.
+type todoTxtLine struct {
+	raw      string
+	item     *entity.Item
+	priority string
+}
+
+type todoTxtFormat struct{}
+
+func (todoTxtFormat) decode(buf []byte) (*itemTable, error) {
+	lines, err := parseTodoTxt(buf)
+	if err != nil {
+		return nil, err
+	}
+	t := newItemTable()
+	for _, l := range lines {
+		if l.item != nil {
+			t.putItem(l.item)
+		}
+	}
+	return t, nil
+}
+
+// encode writes items in the position of their lines in prev, new items are appended in the order of their IDs.
+func (todoTxtFormat) encode(t *itemTable, prev []byte) ([]byte, error) {
+	lines, err := parseTodoTxt(prev)
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[int64]*entity.Item, len(t.Items))
+	for _, it := range t.Items {
+		items[it.ID] = it
+	}
+
+	var buf bytes.Buffer
+	for _, l := range lines {
+		if l.item == nil {
+			buf.WriteString(l.raw)
+			buf.WriteByte('\n')
+			continue
+		}
+		it, ok := items[l.item.ID]
+		if !ok {
+			continue
+		}
+		buf.WriteString(formatTodoTxtItem(it, l.priority))
+		buf.WriteByte('\n')
+		delete(items, it.ID)
+	}
+
+	added := make([]*entity.Item, 0, len(items))
+	for _, it := range items {
+		added = append(added, it)
+	}
+	sort.Slice(added, func(i, j int) bool {
+		return added[i].ID < added[j].ID
+	})
+	for _, it := range added {
+		buf.WriteString(formatTodoTxtItem(it, ""))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// parseTodoTxt parses all lines, IDs are assigned in the order of lines to items without one or with one which is
+// already taken by a previous line, e.g. a copied line.
+func parseTodoTxt(buf []byte) ([]*todoTxtLine, error) {
+	var lines []*todoTxtLine
+	var maxID int64
+	seen := make(map[int64]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(nil, len(buf)+1)
+	for scanner.Scan() {
+		l := parseTodoTxtLine(scanner.Text())
+		if l.item != nil {
+			if seen[l.item.ID] {
+				l.item.ID = 0
+			}
+			seen[l.item.ID] = true
+			if l.item.ID > maxID {
+				maxID = l.item.ID
+			}
+		}
+		lines = append(lines, l)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, l := range lines {
+		if l.item != nil && l.item.ID == 0 {
+			maxID++
+			l.item.ID = maxID
+			l.item.Revision = 1
+		}
+	}
+	return lines, nil
+}
+
+func parseTodoTxtLine(line string) *todoTxtLine {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return &todoTxtLine{raw: line}
+	}
+
+	l := &todoTxtLine{item: &entity.Item{ParentItemID: entity.RootID}}
+	it := l.item
+	tokens := strings.Fields(trimmed)
+	if tokens[0] == "x" {
+		it.State = entity.ItemStateCompleted
+		tokens = tokens[1:]
+		if d, ok := parseTodoTxtDate(tokens); ok {
+			it.CompletedAt = d
+			tokens = tokens[1:]
+			if d, ok := parseTodoTxtDate(tokens); ok {
+				it.CreatedAt = d
+				tokens = tokens[1:]
+			}
+		}
+	} else {
+		if len(tokens) > 0 && isTodoTxtPriority(tokens[0]) {
+			l.priority = tokens[0][1:2]
+			tokens = tokens[1:]
+		}
+		if d, ok := parseTodoTxtDate(tokens); ok {
+			it.CreatedAt = d
+			tokens = tokens[1:]
+		}
+	}
+
+	var words []string
+	for _, tk := range tokens {
+		if strings.HasPrefix(tk, todoTxtEscape) {
+			words = append(words, tk[len(todoTxtEscape):])
+		} else if !parseTodoTxtExtension(l, tk) {
+			words = append(words, tk)
+		}
+	}
+	it.Title = strings.Join(words, " ")
+	it.Priority = letterPriority(l.priority)
+	return l
+}
+
+// parseTodoTxtExtension sets the field of a known key:value token, it returns false if the token is unknown.
+func parseTodoTxtExtension(l *todoTxtLine, token string) bool {
+	sep := strings.IndexByte(token, ':')
+	if sep <= 0 || sep == len(token)-1 {
+		return false
+	}
+	it := l.item
+	backup := *it
+	key, value := token[:sep], token[sep+1:]
+	var err error
+	switch key {
+	case todoTxtKeyID:
+		it.ID, err = strconv.ParseInt(value, 10, 64)
+	case todoTxtKeyParent:
+		it.ParentItemID, err = strconv.ParseInt(value, 10, 64)
+	case todoTxtKeyRank:
+		it.Rank = value
+		if !entity.ValidRank(value) {
+			err = fmt.Errorf("invalid rank: %s", value)
+		}
+	case todoTxtKeyOrder:
+		var order uint64
+		order, err = strconv.ParseUint(value, 10, 64)
+		it.Rank = orderRank(order)
+	case todoTxtKeyRevision:
+		it.Revision, err = strconv.ParseUint(value, 10, 64)
+	case todoTxtKeyType:
+		err = fmt.Errorf("unknown type: %s", value)
+		for t, name := range todoTxtItemTypes {
+			if name == value {
+				it.Type, err = t, nil
+			}
+		}
+	case todoTxtKeyDue:
+		it.Due, err = parseTodoTxtTime(value)
+	case todoTxtKeyRecurrence:
+		it.Recurrence = value
+	case todoTxtKeyReminders:
+		it.Reminders, err = parseReminders(value)
+	case todoTxtKeyEstimate:
+		it.Estimate, err = entity.ParseEstimate(value)
+	case todoTxtKeyTags:
+		it.Tags = strings.Split(value, ",")
+	case todoTxtKeyDescription:
+		it.Description, err = url.PathUnescape(value)
+	case todoTxtKeyCreated:
+		it.CreatedAt, err = parseTodoTxtTime(value)
+	case todoTxtKeyUpdated:
+		it.UpdatedAt, err = parseTodoTxtTime(value)
+	case todoTxtKeyCompleted:
+		it.CompletedAt, err = parseTodoTxtTime(value)
+	case todoTxtKeyDeleted:
+		it.DeletedAt, err = parseTodoTxtTime(value)
+	case todoTxtKeyDeletedWith:
+		it.DeletedWithItemID, err = strconv.ParseInt(value, 10, 64)
+	case todoTxtKeyPriority:
+		if !isTodoTxtPriority("(" + value + ")") {
+			return false
+		}
+		l.priority = value
+	default:
+		return false
+	}
+	if err != nil {
+		// a malformed value is kept in the title rather than lost
+		*it = backup
+		return false
+	}
+	return true
+}
+
+func parseTodoTxtDate(tokens []string) (time.Time, bool) {
+	if len(tokens) == 0 {
+		return time.Time{}, false
+	}
+	d, err := time.Parse(todoTxtDateLayout, tokens[0])
+	return d, err == nil
+}
+
+// parseTodoTxtTime parses either a date or a time in RFC 3339.
+func parseTodoTxtTime(s string) (time.Time, error) {
+	if d, err := time.Parse(todoTxtDateLayout, s); err == nil {
+		return d, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func isTodoTxtPriority(s string) bool {
+	return len(s) == 3 && s[0] == '(' && s[1] >= 'A' && s[1] <= 'Z' && s[2] == ')'
+}
+
+// formatTodoTxtItem formats an item as a line, the standard dates are followed by precise ones in extensions
+// since the format only keeps dates.
+func formatTodoTxtItem(it *entity.Item, priority string) string {
+	priority = priorityLetter(it, priority)
+	var parts []string
+	if it.State == entity.ItemStateCompleted {
+		parts = append(parts, "x")
+		// the creation date is only allowed after the completion date
+		if !it.CompletedAt.IsZero() {
+			parts = append(parts, it.CompletedAt.Format(todoTxtDateLayout))
+			if !it.CreatedAt.IsZero() {
+				parts = append(parts, it.CreatedAt.Format(todoTxtDateLayout))
+			}
+		}
+	} else {
+		if priority != "" {
+			parts = append(parts, "("+priority+")")
+		}
+		if !it.CreatedAt.IsZero() {
+			parts = append(parts, it.CreatedAt.Format(todoTxtDateLayout))
+		}
+	}
+	if it.Title != "" {
+		parts = append(parts, escapeTodoTxtTitle(it.Title))
+	}
+
+	ext := func(key, value string) {
+		parts = append(parts, key+":"+value)
+	}
+	if it.Type != entity.ItemTypeTask {
+		ext(todoTxtKeyType, todoTxtItemTypes[it.Type])
+	}
+	if !it.Due.IsZero() {
+		ext(todoTxtKeyDue, formatTodoTxtTime(it.Due))
+	}
+	if it.Recurrence != "" {
+		ext(todoTxtKeyRecurrence, it.Recurrence)
+	}
+	if len(it.Reminders) > 0 {
+		ext(todoTxtKeyReminders, formatReminders(it.Reminders))
+	}
+	if len(it.Tags) > 0 {
+		ext(todoTxtKeyTags, strings.Join(it.Tags, ","))
+	}
+	if it.Estimate > 0 {
+		ext(todoTxtKeyEstimate, entity.FormatEstimate(it.Estimate))
+	}
+	if it.Description != "" {
+		ext(todoTxtKeyDescription, url.PathEscape(it.Description))
+	}
+	if priority != "" && it.State == entity.ItemStateCompleted {
+		ext(todoTxtKeyPriority, priority)
+	}
+	ext(todoTxtKeyID, strconv.FormatInt(it.ID, 10))
+	ext(todoTxtKeyParent, strconv.FormatInt(it.ParentItemID, 10))
+	if it.Rank != "" {
+		ext(todoTxtKeyRank, it.Rank)
+	}
+	ext(todoTxtKeyRevision, strconv.FormatUint(it.Revision, 10))
+	if !it.CreatedAt.IsZero() {
+		ext(todoTxtKeyCreated, formatTodoTxtTime(it.CreatedAt))
+	}
+	if !it.UpdatedAt.IsZero() {
+		ext(todoTxtKeyUpdated, formatTodoTxtTime(it.UpdatedAt))
+	}
+	if !it.CompletedAt.IsZero() {
+		ext(todoTxtKeyCompleted, formatTodoTxtTime(it.CompletedAt))
+	}
+	if isDeleted(it) {
+		ext(todoTxtKeyDeleted, formatTodoTxtTime(it.DeletedAt))
+		ext(todoTxtKeyDeletedWith, strconv.FormatInt(it.DeletedWithItemID, 10))
+	}
+	return strings.Join(parts, " ")
+}
+
+// escapeTodoTxtTitle escapes words of a title which would be read as anything but the title: a known key:value
+// anywhere, and a completion mark, a priority or a date at first.
+func escapeTodoTxtTitle(title string) string {
+	words := strings.Fields(title)
+	for i, w := range words {
+		escape := strings.HasPrefix(w, todoTxtEscape) || parseTodoTxtExtension(&todoTxtLine{item: &entity.Item{}}, w)
+		if i == 0 {
+			_, isDate := parseTodoTxtDate(words)
+			escape = escape || w == "x" || isTodoTxtPriority(w) || isDate
+		}
+		if escape {
+			words[i] = todoTxtEscape + w
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// formatTodoTxtTime formats a time as a date if it is a date, or in RFC 3339 otherwise.
+func formatTodoTxtTime(t time.Time) string {
+	if t.Equal(t.Truncate(24*time.Hour)) && t.Location() == time.UTC {
+		return t.Format(todoTxtDateLayout)
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+const testingTodoTxt = `# my tasks
+(A) 2020-01-02 Call mom +family @phone due:2020-01-03 id:3 parent:1 rank:k rev:4
+x 2020-01-05 2020-01-01 Pay bills @home pri:B id:4 order:1 rev:1
+
+Water plants color:green
+Water plants color:green
+2020-02-03 Meeting at 10:30 id:oops
+`
+
+func writeTestingTodoTxt(t *testing.T, content string) (*TodoTxt, string) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "todo.txt")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return NewTodoTxt(path), dir
+}
+
+func TestTodoTxtReadsFile(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingTodoTxt(t, testingTodoTxt)
+	defer os.RemoveAll(dir)
+
+	call, err := s.GetItemByID(3)
+	assert.NoError(t, err)
+	assert.Equal(t, &entity.Item{
+		ID:           3,
+		Title:        "Call mom +family @phone",
+		ParentItemID: 1,
+		Rank:         "k",
+		Revision:     4,
+		Priority:     entity.ItemPriorityHigh,
+		CreatedAt:    time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		Due:          time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+	}, call)
+
+	bills, err := s.GetItemByID(4)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ItemStateCompleted, bills.State)
+	assert.Equal(t, time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC), bills.CompletedAt)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), bills.CreatedAt)
+	assert.Equal(t, "Pay bills @home", bills.Title)
+	assert.Equal(t, entity.ItemPriorityMedium, bills.Priority)
+	// order of files written before ranks
+	assert.Equal(t, orderRank(1), bills.Rank)
+
+	// lines without id, duplicated lines included, are tasks under the root
+	items, err := s.GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	var titles []string
+	for _, it := range items {
+		titles = append(titles, it.Title)
+	}
+	assert.ElementsMatch(t, []string{
+		"Pay bills @home", "Water plants color:green", "Water plants color:green", "Meeting at 10:30 id:oops",
+	}, titles)
+}
+
+func TestTodoTxtKeepsWhatItDoesNotUnderstand(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingTodoTxt(t, testingTodoTxt)
+	defer os.RemoveAll(dir)
+
+	call, err := s.GetItemByID(3)
+	assert.NoError(t, err)
+	call.Title = "Call dad +family @phone"
+	_, err = s.SaveItem(call)
+	assert.NoError(t, err)
+	_, err = s.SaveItem(&entity.Item{Title: "New one", Description: "multiple\nlines: yes"})
+	assert.NoError(t, err)
+
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(buf), "\n"), "\n")
+	assert.Len(t, lines, 8)
+	assert.Equal(t, "# my tasks", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "(A) 2020-01-02 Call dad +family @phone due:2020-01-03 id:3 parent:1 rank:k rev:5 "))
+	assert.True(t, strings.HasPrefix(lines[2], "x 2020-01-05 2020-01-01 Pay bills @home pri:B id:4 "))
+	assert.Equal(t, "", lines[3])
+	assert.True(t, strings.HasPrefix(lines[4], "Water plants color:green id:5 "))
+	assert.True(t, strings.HasPrefix(lines[5], "Water plants color:green id:6 "))
+	assert.Contains(t, lines[6], "Meeting at 10:30 id:oops id:7 ")
+	assert.Contains(t, lines[7], "New one desc:multiple%0Alines:%20yes id:8 ")
+
+	// everything reads back the same
+	reread := NewTodoTxt(s.file)
+	for id := int64(3); id <= 8; id++ {
+		before, err := s.GetItemByID(id)
+		assert.NoError(t, err)
+		after, err := reread.GetItemByID(id)
+		assert.NoError(t, err)
+		assert.Equal(t, before, after)
+	}
+}
+
+func TestTodoTxtEscapesTitles(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingTodoTxt(t, "")
+	defer os.RemoveAll(dir)
+
+	titles := []string{
+		"clean up items deleted:2024-01-01 last year",
+		"ask about id:7 ticket",
+		"set type:task in form",
+		"est:2h estimate talk",
+		"move parent:3 rrule:FREQ=DAILY remind:15m",
+		`x marks C:\ and \escaped`,
+		"(B) is a priority",
+		"2020-01-02 is a date",
+	}
+	for _, title := range titles {
+		_, err := s.SaveItem(&entity.Item{Title: title, ParentItemID: entity.RootID})
+		assert.NoError(t, err)
+	}
+
+	items, err := NewTodoTxt(s.file).GetItemsByParentID(entity.RootID)
+	assert.NoError(t, err)
+	var got []string
+	for _, it := range items {
+		assert.False(t, isDeleted(it), it.Title)
+		assert.Zero(t, it.Estimate, it.Title)
+		got = append(got, it.Title)
+	}
+	assert.ElementsMatch(t, titles, got)
+}
+
+func TestTodoTxtCompletion(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingTodoTxt(t, "(C) 2020-01-02 Task id:1\n")
+	defer os.RemoveAll(dir)
+
+	item, err := s.GetItemByID(1)
+	assert.NoError(t, err)
+	item.State = entity.ItemStateCompleted
+	item.CompletedAt = time.Date(2020, 1, 4, 12, 0, 0, 0, time.UTC)
+	_, err = s.SaveItem(item)
+	assert.NoError(t, err)
+
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buf), "x 2020-01-04 2020-01-02 Task pri:C id:1 "))
+	assert.Contains(t, string(buf), "completed:2020-01-04T12:00:00Z")
+
+	// the priority comes back when the task is reopened
+	item.State = entity.ItemStateNormal
+	_, err = s.SaveItem(item)
+	assert.NoError(t, err)
+	buf, err = ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buf), "(C) 2020-01-02 Task id:1 "))
+}
+
+func TestTodoTxtPriority(t *testing.T) {
+	t.Parallel()
+	s, dir := writeTestingTodoTxt(t, "(D) 2020-01-02 Task id:1\n")
+	defer os.RemoveAll(dir)
+
+	item, err := s.GetItemByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ItemPriorityLow, item.Priority)
+
+	// letters of the same level are kept
+	item.Title = "Renamed"
+	_, err = s.SaveItem(item)
+	assert.NoError(t, err)
+	buf, err := ioutil.ReadFile(s.file)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buf), "(D) 2020-01-02 Renamed id:1 "))
+
+	for _, c := range []struct {
+		priority entity.ItemPriority
+		prefix   string
+	}{
+		{entity.ItemPriorityHigh, "(A) 2020-01-02 Renamed id:1 "},
+		{entity.ItemPriorityMedium, "(B) 2020-01-02 Renamed id:1 "},
+		{entity.ItemPriorityLow, "(C) 2020-01-02 Renamed id:1 "},
+		{entity.ItemPriorityNone, "2020-01-02 Renamed id:1 "},
+	} {
+		p, prefix := c.priority, c.prefix
+		item.Priority = p
+		_, err = s.SaveItem(item)
+		assert.NoError(t, err)
+		buf, err = ioutil.ReadFile(s.file)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(buf), prefix), string(buf))
+		reread, err := NewTodoTxt(s.file).GetItemByID(1)
+		assert.NoError(t, err)
+		assert.Equal(t, p, reread.Priority)
+	}
+}
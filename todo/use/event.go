@@ -0,0 +1,103 @@
+package use
+
+import (
+	"sync"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+// ItemEventType indicates how an item is changed.
+type ItemEventType int
+
+// All ItemEventType(s).
+const (
+	ItemCreated ItemEventType = iota
+	ItemUpdated
+	// ItemReordered indicates only the rank of the item is changed.
+	ItemReordered
+	// ItemDeleted indicates the item is moved to the trash.
+	ItemDeleted
+	// ItemRestored indicates the item is moved back from the trash.
+	ItemRestored
+	// ItemRemoved indicates the item is removed permanently, Item is the last state of it.
+	ItemRemoved
+)
+
+// ItemEvent is a change of an item emitted by Storage.
+type ItemEvent struct {
+	Type ItemEventType
+	// Item is the item after the change.
+	Item *entity.Item
+	// PreviousParentItemID is the parent of the item before the change, which differs from that of Item only if the
+	// item is moved.
+	PreviousParentItemID int64
+}
+
+var itemEventTypeToTaskEventTypeMap = map[ItemEventType]model.TaskEventType{
+	ItemCreated:   model.TaskEventCreated,
+	ItemUpdated:   model.TaskEventUpdated,
+	ItemReordered: model.TaskEventReordered,
+	ItemDeleted:   model.TaskEventDeleted,
+	ItemRestored:  model.TaskEventRestored,
+	ItemRemoved:   model.TaskEventRemoved,
+}
+
+// SubscribeTaskEvents returns a channel of changes made to tasks from now on, the channel is closed once cancel is
+// called. A change is followed by updates of the projects it is under, see projectEvents.
+func (t *TaskInteractor) SubscribeTaskEvents() (events <-chan model.TaskEvent, cancel func()) {
+	items, cancelItems := t.Storage.Subscribe()
+	tasks := make(chan model.TaskEvent)
+	done := make(chan struct{})
+	go func() {
+		defer close(tasks)
+		for e := range items {
+			for _, te := range append([]model.TaskEvent{itemEventToTaskEvent(e)}, t.projectEvents(e)...) {
+				select {
+				case tasks <- te:
+				case <-done:
+					// items is closed soon by cancelItems, which has been called
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return tasks, func() {
+		once.Do(func() {
+			close(done)
+			cancelItems()
+		})
+	}
+}
+
+func itemEventToTaskEvent(e ItemEvent) model.TaskEvent {
+	return model.TaskEvent{
+		Type:             itemEventTypeToTaskEventTypeMap[e.Type],
+		Task:             itemToTask(e.Item),
+		PreviousParentID: e.PreviousParentItemID,
+	}
+}
+
+// projectEvents returns updates of the projects among the ancestors of the item of e, including those it is moved
+// out of, as their progress changes along with it. Projects which could not be read are skipped.
+func (t *TaskInteractor) projectEvents(e ItemEvent) []model.TaskEvent {
+	var events []model.TaskEvent
+	visited := make(map[int64]bool)
+	for _, parentID := range []int64{e.Item.ParentItemID, e.PreviousParentItemID} {
+		for id := parentID; id != entity.RootID && !visited[id]; {
+			visited[id] = true
+			it, err := t.Storage.GetItemByID(id)
+			if err != nil {
+				break
+			}
+			if it.Type == entity.ItemTypeProject {
+				if tasks, err := itemsToTasks(t.Storage, []*entity.Item{it}); err == nil {
+					events = append(events, model.TaskEvent{Type: model.TaskEventUpdated, Task: tasks[0], PreviousParentID: it.ParentItemID})
+				}
+			}
+			id = it.ParentItemID
+		}
+	}
+	return events
+}
@@ -0,0 +1,60 @@
+package use
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+func TestSubscribeTaskEvents(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	s := tt.Storage.(*MockStorage)
+
+	items := make(chan ItemEvent, 1)
+	s.EXPECT().Subscribe().Return(items, func() { close(items) })
+	events, cancel := tt.SubscribeTaskEvents()
+
+	// moved from a project under a category to another category
+	task := &entity.Item{ID: 42, ParentItemID: 1, Title: "task", State: entity.ItemStateCompleted}
+	release := &entity.Item{ID: 2, Title: "Release", Type: entity.ItemTypeProject, ParentItemID: 3}
+	s.EXPECT().GetItemByID(int64(1)).Return(&entity.Item{ID: 1, Type: entity.ItemTypeCategory}, nil)
+	s.EXPECT().GetItemByID(release.ID).Return(release, nil)
+	s.EXPECT().GetItemsByParentID(release.ID).Return([]*entity.Item{{ID: 4, ParentItemID: 2}}, nil)
+	s.EXPECT().GetItemsByParentID(int64(4)).Return(nil, nil)
+	s.EXPECT().GetItemByID(int64(3)).Return(&entity.Item{ID: 3, Type: entity.ItemTypeCategory}, nil)
+	items <- ItemEvent{Type: ItemUpdated, Item: task, PreviousParentItemID: release.ID}
+	e := <-events
+	assert.Equal(t, model.TaskEventUpdated, e.Type)
+	assert.Equal(t, int64(42), e.Task.ID)
+	assert.Equal(t, int64(1), e.Task.ParentID)
+	assert.Equal(t, release.ID, e.PreviousParentID)
+	assert.Equal(t, "task", e.Task.Title)
+	// followed by the project whose progress is changed
+	e = <-events
+	assert.Equal(t, model.TaskEventUpdated, e.Type)
+	assert.Equal(t, release.ID, e.Task.ID)
+	assert.Equal(t, &model.Progress{Total: 1}, e.Task.Progress)
+
+	// events not received are dropped once canceled
+	items <- ItemEvent{Type: ItemCreated, Item: &entity.Item{}}
+	cancel()
+	cancel()
+	for range events {
+	}
+}
+
+func TestItemEventTypeToTaskEventTypeMap(t *testing.T) {
+	t.Parallel()
+	for _, typ := range []ItemEventType{ItemCreated, ItemUpdated, ItemReordered} {
+		_, ok := itemEventTypeToTaskEventTypeMap[typ]
+		assert.True(t, ok)
+	}
+}
@@ -0,0 +1,107 @@
+package use
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// ErrMoveUnderItself is returned when a task is moved under itself or one of its sub tasks.
+var ErrMoveUnderItself = errors.New("Task could not be moved under itself or its sub tasks")
+
+// MoveTask moves a task under given parent at given position among the other tasks of the parent, a position out
+// of range puts it at the end. Only the task is changed, unless the tasks of the parent have to be given new ranks.
+func (t *TaskInteractor) MoveTask(taskID, parentID int64, position int) error {
+	return t.Storage.RunInTx(func(tx Storage) error {
+		if err := validateMoveTask(tx, taskID, parentID); err != nil {
+			return err
+		}
+		item, err := tx.GetItemByID(taskID)
+		if err != nil {
+			return fmt.Errorf("getting item: %w", err)
+		}
+		siblings, err := tx.GetItemsByParentID(parentID)
+		if err != nil {
+			return fmt.Errorf("getting items of parent[%d]: %w", parentID, err)
+		}
+		rank, err := rankAt(tx, withoutItem(tasksOnly(siblings), taskID), position)
+		if err != nil {
+			return err
+		}
+		item.ParentItemID, item.Rank = parentID, rank
+		if _, err := tx.SaveItem(item); err != nil {
+			return fmt.Errorf("saving item: %w", err)
+		}
+		return nil
+	})
+}
+
+// validateMoveTask returns ErrMoveUnderItself if parentID is taskID or one of its descendants, or ErrParentIsView
+// if parentID is a view.
+func validateMoveTask(s Storage, taskID, parentID int64) error {
+	if taskID == entity.RootID {
+		return ErrMoveUnderItself
+	}
+	visited := make(map[int64]bool)
+	for id := parentID; id != entity.RootID && !visited[id]; {
+		if id == taskID {
+			return fmt.Errorf("%w: parent[%d] is under task[%d]", ErrMoveUnderItself, parentID, taskID)
+		}
+		visited[id] = true
+		parent, err := s.GetItemByID(id)
+		if err != nil {
+			return fmt.Errorf("getting parent item: %w", err)
+		}
+		if parent.Type == entity.ItemTypeView {
+			return fmt.Errorf("%w: parent[%d]", ErrParentIsView, parentID)
+		}
+		id = parent.ParentItemID
+	}
+	return nil
+}
+
+// rankAt returns the rank of an item put at given position among siblings, which are sorted and do not include the
+// item, a position out of range is the end. If the ranks around position are too dense, or not in order, e.g. of
+// the same rank, siblings are saved with new ranks spread evenly to make room.
+func rankAt(s Storage, siblings []*entity.Item, position int) (string, error) {
+	if position < 0 || position > len(siblings) {
+		position = len(siblings)
+	}
+	var prev, next string
+	if position > 0 {
+		prev = siblings[position-1].Rank
+	}
+	if position < len(siblings) {
+		next = siblings[position].Rank
+	}
+	if rank, ok := entity.RankBetween(prev, next); ok && len(rank) <= entity.MaxRankLength {
+		return rank, nil
+	}
+
+	ranks := entity.SpreadRanks(len(siblings) + 1)
+	for i, it := range siblings {
+		rank := ranks[i]
+		if i >= position {
+			rank = ranks[i+1]
+		}
+		if it.Rank == rank {
+			continue
+		}
+		it.Rank = rank
+		if _, err := s.SaveItem(it); err != nil {
+			return "", fmt.Errorf("spreading ranks: %w", err)
+		}
+	}
+	return ranks[position], nil
+}
+
+func withoutItem(items []*entity.Item, id int64) []*entity.Item {
+	without := items[:0:0]
+	for _, it := range items {
+		if it.ID != id {
+			without = append(without, it)
+		}
+	}
+	return without
+}
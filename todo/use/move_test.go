@@ -0,0 +1,81 @@
+package use
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+func TestMoveTaskToAnotherParent(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	var oldParent, newParent int64 = 20, 10
+	moved := &entity.Item{ID: 5, ParentItemID: oldParent, Rank: "i"}
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(newParent).Return(&entity.Item{ID: newParent}, nil),
+		s.EXPECT().GetItemByID(moved.ID).Return(moved, nil),
+		s.EXPECT().GetItemsByParentID(newParent).Return([]*entity.Item{
+			{ID: 3, ParentItemID: newParent, Rank: "c"},
+			{ID: 4, ParentItemID: newParent, Rank: "o"},
+		}, nil),
+		// neither the old nor the new siblings are changed
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 5, ParentItemID: newParent, Rank: "b"}}),
+	)
+	assert.NoError(t, tt.MoveTask(moved.ID, newParent, 0))
+}
+
+func TestMoveTaskWithinParent(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	moved := &entity.Item{ID: 1, Rank: "c"}
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(moved.ID).Return(moved, nil),
+		s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return([]*entity.Item{
+			{ID: 1, Rank: "c"},
+			{ID: 2, Rank: "i"},
+			{ID: 3, Rank: "o"},
+		}, nil),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 1, Rank: "l"}}),
+	)
+	// moved down by one, the position is among the other tasks
+	assert.NoError(t, tt.MoveTask(moved.ID, entity.RootID, 1))
+}
+
+func TestMoveTaskUnderItself(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	// 3 is a child of 2, which is a child of 1
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(int64(3)).Return(&entity.Item{ID: 3, ParentItemID: 2}, nil),
+		s.EXPECT().GetItemByID(int64(2)).Return(&entity.Item{ID: 2, ParentItemID: 1}, nil),
+	)
+	err := tt.MoveTask(1, 3, 0)
+	assert.True(t, errors.Is(err, ErrMoveUnderItself))
+
+	expectTx(tt)
+	err = tt.MoveTask(1, 1, 0)
+	assert.True(t, errors.Is(err, ErrMoveUnderItself))
+
+	expectTx(tt)
+	err = tt.MoveTask(entity.RootID, 1, 0)
+	assert.True(t, errors.Is(err, ErrMoveUnderItself))
+}
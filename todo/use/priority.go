@@ -0,0 +1,29 @@
+package use
+
+import (
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+var taskPriorityToItemPriorityMap = map[model.TaskPriority]entity.ItemPriority{
+	model.TaskPriorityNone:   entity.ItemPriorityNone,
+	model.TaskPriorityLow:    entity.ItemPriorityLow,
+	model.TaskPriorityMedium: entity.ItemPriorityMedium,
+	model.TaskPriorityHigh:   entity.ItemPriorityHigh,
+}
+
+var itemPriorityToTaskPriorityMap = map[entity.ItemPriority]model.TaskPriority{}
+
+func init() {
+	for k, v := range taskPriorityToItemPriorityMap {
+		itemPriorityToTaskPriorityMap[v] = k
+	}
+}
+
+func taskPriorityToItemPriority(p model.TaskPriority) entity.ItemPriority {
+	return taskPriorityToItemPriorityMap[p]
+}
+
+func itemPriorityToTaskPriority(p entity.ItemPriority) model.TaskPriority {
+	return itemPriorityToTaskPriorityMap[p]
+}
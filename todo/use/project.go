@@ -0,0 +1,62 @@
+package use
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+// projectProgress rolls up the progress of a project from its descendants in s.
+func projectProgress(s Storage, projectID int64) (entity.Progress, error) {
+	var p entity.Progress
+	err := walkItems(s, projectID, p.Add)
+	return p, err
+}
+
+// itemsToTasks converts items to tasks in the same order, with the progress of projects among them.
+func itemsToTasks(s Storage, items []*entity.Item) ([]*model.Task, error) {
+	tasks := make([]*model.Task, len(items))
+	for i, it := range items {
+		tasks[i] = itemToTask(it)
+		if it.Type != entity.ItemTypeProject {
+			continue
+		}
+		p, err := projectProgress(s, it.ID)
+		if err != nil {
+			return nil, fmt.Errorf("getting progress of project[%d]: %w", it.ID, err)
+		}
+		tasks[i].Progress = &model.Progress{Completed: p.Completed, Total: p.Total, Remaining: p.Remaining}
+	}
+	return tasks, nil
+}
+
+// completeProjects completes the project of given ID if all the tasks under it are completed, then its parent
+// project in turn. It stops at the first ancestor which is not a project, or not done.
+func completeProjects(s Storage, projectID int64) error {
+	for projectID != entity.RootID {
+		project, err := s.GetItemByID(projectID)
+		if err != nil {
+			return fmt.Errorf("getting item: %w", err)
+		}
+		if project.Type != entity.ItemTypeProject {
+			return nil
+		}
+		if project.State != entity.ItemStateCompleted {
+			p, err := projectProgress(s, project.ID)
+			if err != nil {
+				return err
+			}
+			if !p.Done() {
+				return nil
+			}
+			project.State, project.CompletedAt = entity.ItemStateCompleted, time.Now().UTC()
+			if _, err := s.SaveItem(project); err != nil {
+				return fmt.Errorf("saving project[%d]: %w", project.ID, err)
+			}
+		}
+		projectID = project.ParentItemID
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package use
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+	"github.com/tevino/the-clean-architecture-demo/todo/use/mock_use"
+)
+
+func TestListProjectsWithProgress(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	release := &entity.Item{ID: 1, Title: "Release", Type: entity.ItemTypeProject}
+	call := &entity.Item{ID: 2, Title: "Call mom"}
+	changelog := &entity.Item{ID: 3, Title: "Write changelog", State: entity.ItemStateCompleted, Estimate: time.Hour, ParentItemID: 1}
+	tag := &entity.Item{ID: 4, Title: "Tag the commit", Estimate: 2 * time.Hour, ParentItemID: 1}
+	s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return([]*entity.Item{release, call}, nil)
+	s.EXPECT().GetItemsByParentID(release.ID).Return([]*entity.Item{changelog, tag}, nil)
+	s.EXPECT().GetItemsByParentID(changelog.ID).Return(nil, nil)
+	s.EXPECT().GetItemsByParentID(tag.ID).Return(nil, nil)
+	project := itemToTask(release)
+	assert.Equal(t, model.TaskTypeProject, project.Type)
+	project.Progress = &model.Progress{Completed: 1, Total: 2, Remaining: 2 * time.Hour}
+	tt.Presenter.(*mock_use.MockPresenter).EXPECT().
+		ShowTasksOfParentID(int64(entity.RootID), []*model.Task{project, itemToTask(call)})
+
+	assert.NoError(t, tt.ListTasksByParentID(entity.RootID, nil))
+}
+
+func TestAddProject(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(int64(entity.RootID)).Return(entity.RootItem, nil),
+		s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return(nil, nil),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{
+			Title: "Release", Type: entity.ItemTypeProject, Estimate: 24 * time.Hour, Rank: "i",
+		}}).Return(int64(1), nil),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTaskAdded(gomock.Any()),
+	)
+	assert.NoError(t, tt.AddTask(&model.FormAddTask{Title: "Release", Type: model.TaskTypeProject, Estimate: "1d"}))
+
+	err := tt.AddTask(&model.FormAddTask{Title: "Release", Type: model.TaskTypeProject, Estimate: "soon"})
+	assert.True(t, errors.Is(err, entity.ErrInvalidEstimate))
+}
+
+func TestAutoCompleteProjects(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	tt.AutoCompleteProjects = true
+	s := tt.Storage.(*MockStorage)
+
+	work := &entity.Item{ID: 1, Title: "Work", Type: entity.ItemTypeCategory}
+	release := &entity.Item{ID: 2, Title: "Release", Type: entity.ItemTypeProject, ParentItemID: 1}
+	docs := &entity.Item{ID: 3, Title: "Docs", Type: entity.ItemTypeProject, ParentItemID: 2}
+	changelog := &entity.Item{ID: 4, Title: "Write changelog", ParentItemID: 3}
+	tag := &entity.Item{ID: 5, Title: "Tag the commit", ParentItemID: 2}
+	var saved []int64
+	save := func(it *entity.Item) (int64, error) {
+		assert.Equal(t, entity.ItemStateCompleted, it.State)
+		assert.False(t, it.CompletedAt.IsZero())
+		saved = append(saved, it.ID)
+		return it.ID, nil
+	}
+	s.EXPECT().SaveItem(gomock.Any()).DoAndReturn(save).AnyTimes()
+	s.EXPECT().GetItemsByParentID(changelog.ID).Return(nil, nil).AnyTimes()
+	s.EXPECT().GetItemsByParentID(tag.ID).Return(nil, nil).AnyTimes()
+	s.EXPECT().GetItemsByParentID(docs.ID).Return([]*entity.Item{changelog}, nil).AnyTimes()
+	s.EXPECT().GetItemsByParentID(release.ID).Return([]*entity.Item{docs, tag}, nil).AnyTimes()
+
+	// the tag is not done yet
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(changelog.ID).Return(changelog, nil),
+		s.EXPECT().GetItemByID(docs.ID).Return(docs, nil),
+		s.EXPECT().GetItemByID(release.ID).Return(release, nil),
+	)
+	assert.NoError(t, tt.ChangeTaskStateByID(changelog.ID, 0, model.TaskStateCompleted))
+	assert.Equal(t, []int64{changelog.ID, docs.ID}, saved)
+
+	// up to the category
+	saved = nil
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(tag.ID).Return(tag, nil),
+		s.EXPECT().GetItemByID(release.ID).Return(release, nil),
+		s.EXPECT().GetItemByID(work.ID).Return(work, nil),
+	)
+	assert.NoError(t, tt.ChangeTaskStateByID(tag.ID, 0, model.TaskStateCompleted))
+	assert.Equal(t, []int64{tag.ID, release.ID}, saved)
+}
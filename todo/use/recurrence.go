@@ -0,0 +1,67 @@
+package use
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// repeatItem adds the next occurrence of an item just completed right after it, unless the recurrence ends. The
+// recurrence moves to the next occurrence, so the completed item is not repeated again once completed again.
+//
+// The next occurrence follows the due of the item, or the time it is completed if the recurrence repeats from
+// completion or the item has no due. Repeating from completion keeps the time of day of the due.
+func repeatItem(s Storage, item *entity.Item, recurrence string) error {
+	r, err := entity.ParseRecurrence(recurrence)
+	if err != nil {
+		return err
+	}
+	at := item.Due
+	if r.FromCompletion || at.IsZero() {
+		at = item.CompletedAt
+		if !item.Due.IsZero() {
+			due, completed := item.Due, item.CompletedAt.In(item.Due.Location())
+			at = time.Date(completed.Year(), completed.Month(), completed.Day(),
+				due.Hour(), due.Minute(), due.Second(), due.Nanosecond(), due.Location())
+		}
+	}
+	due, rest, ok := r.Next(at)
+	if !ok {
+		return nil
+	}
+
+	siblings, err := s.GetItemsByParentID(item.ParentItemID)
+	if err != nil {
+		return fmt.Errorf("getting items of parent[%d]: %w", item.ParentItemID, err)
+	}
+	siblings = tasksOnly(siblings)
+	position := len(siblings)
+	for i, it := range siblings {
+		if it.ID == item.ID {
+			position = i + 1
+		}
+	}
+	next := &entity.Item{
+		Title:        item.Title,
+		Description:  item.Description,
+		Type:         item.Type,
+		State:        entity.ItemStateNormal,
+		Priority:     item.Priority,
+		Due:          due,
+		Recurrence:   rest.String(),
+		Reminders:    item.Reminders,
+		Tags:         item.Tags,
+		Estimate:     item.Estimate,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		ParentItemID: item.ParentItemID,
+	}
+	if next.Rank, err = rankAt(s, siblings, position); err != nil {
+		return err
+	}
+	if _, err := s.SaveItem(next); err != nil {
+		return fmt.Errorf("saving next occurrence: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package use
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+	"github.com/tevino/the-clean-architecture-demo/todo/use/mock_use"
+)
+
+// expectRepeat expects completing item to save it and then its next occurrence, which are returned once saved.
+func expectRepeat(tt *TaskInteractor, item *entity.Item, siblings []*entity.Item) (completed, next *entity.Item) {
+	s := tt.Storage.(*MockStorage)
+	completed, next = &entity.Item{}, &entity.Item{}
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(item.ID).Return(item, nil),
+		s.EXPECT().SaveItem(gomock.Any()).DoAndReturn(func(it *entity.Item) (int64, error) {
+			*completed = *it
+			return it.ID, nil
+		}),
+		s.EXPECT().GetItemsByParentID(item.ParentItemID).Return(siblings, nil),
+		s.EXPECT().SaveItem(gomock.Any()).DoAndReturn(func(it *entity.Item) (int64, error) {
+			*next = *it
+			return 42, nil
+		}),
+	)
+	return completed, next
+}
+
+func TestCompletingRecurringTaskRepeatsIt(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	before := &entity.Item{ID: 1, Title: "Plan", ParentItemID: 7, Rank: "c"}
+	report := &entity.Item{
+		ID:           2,
+		Title:        "Weekly report",
+		Description:  "Send it to the team",
+		Due:          time.Date(2020, 1, 2, 15, 4, 0, 0, time.UTC),
+		Recurrence:   "FREQ=WEEKLY;COUNT=3",
+		Reminders:    []time.Duration{time.Hour},
+		ParentItemID: 7,
+		Rank:         "h",
+		Revision:     5,
+	}
+	after := &entity.Item{ID: 3, Title: "Review", ParentItemID: 7, Rank: "q"}
+	view := &entity.Item{ID: 4, Title: "Urgent", Type: entity.ItemTypeView, ParentItemID: 7, Rank: "i"}
+	completed, next := expectRepeat(tt, report, []*entity.Item{before, report, view, after})
+	assert.NoError(t, tt.ChangeTaskStateByID(report.ID, 5, model.TaskStateCompleted))
+
+	// the recurrence moves to the next occurrence
+	assert.Equal(t, entity.ItemStateCompleted, completed.State)
+	assert.Empty(t, completed.Recurrence)
+	assert.Equal(t, "Weekly report", next.Title)
+	assert.Equal(t, "Send it to the team", next.Description)
+	assert.Equal(t, entity.ItemStateNormal, next.State)
+	assert.Equal(t, time.Date(2020, 1, 9, 15, 4, 0, 0, time.UTC), next.Due)
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=2", next.Recurrence)
+	assert.Equal(t, []time.Duration{time.Hour}, next.Reminders)
+	assert.Equal(t, int64(7), next.ParentItemID)
+	assert.True(t, next.Rank > report.Rank && next.Rank < after.Rank, next.Rank)
+	assert.Zero(t, next.Revision)
+}
+
+func TestCompletingRecurringTaskRepeatsFromCompletion(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	water := &entity.Item{
+		ID:         1,
+		Title:      "Water plants",
+		Due:        time.Date(2020, 1, 2, 15, 4, 0, 0, time.UTC),
+		Recurrence: "FREQ=DAILY;INTERVAL=2;X-FROM=COMPLETION",
+		Rank:       "h",
+	}
+	completed, next := expectRepeat(tt, water, []*entity.Item{water})
+	assert.NoError(t, tt.ChangeTaskStateByID(water.ID, 0, model.TaskStateCompleted))
+
+	// two days after the day it is completed, at the time of the due
+	y, m, d := completed.CompletedAt.In(time.UTC).Date()
+	assert.Equal(t, time.Date(y, m, d+2, 15, 4, 0, 0, time.UTC), next.Due)
+	assert.Equal(t, "FREQ=DAILY;INTERVAL=2;X-FROM=COMPLETION", next.Recurrence)
+	assert.True(t, next.Rank > water.Rank)
+}
+
+func TestCompletingLastOccurrence(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	last := &entity.Item{ID: 1, Title: "Pay rent", Due: time.Now(), Recurrence: "FREQ=MONTHLY;COUNT=1"}
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(last.ID).Return(last, nil),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 1, Title: "Pay rent", Due: last.Due, State: entity.ItemStateCompleted}}),
+	)
+	assert.NoError(t, tt.ChangeTaskStateByID(last.ID, 0, model.TaskStateCompleted))
+}
+
+func TestAddRecurringTask(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	err := tt.AddTask(&model.FormAddTask{Title: "Report", Recurrence: "FREQ=HOURLY"})
+	assert.True(t, errors.Is(err, entity.ErrInvalidRecurrence))
+
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(int64(entity.RootID)).Return(entity.RootItem, nil),
+		s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return(nil, nil),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{Title: "Report", Type: entity.ItemTypeTask, Recurrence: "FREQ=WEEKLY;BYDAY=MO", Rank: "i"}}),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTaskAdded(gomock.Any()),
+	)
+	assert.NoError(t, tt.AddTask(&model.FormAddTask{Title: "Report", Type: model.TaskTypeTask, Recurrence: "rrule:freq=weekly;byday=mo"}))
+}
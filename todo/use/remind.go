@@ -0,0 +1,155 @@
+package use
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+// Clock tells the time and waits for it, it is replaced in tests.
+type Clock interface {
+	Now() time.Time
+	// After returns a channel which receives the time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the Clock of the system.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time                         { return time.Now() }
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Notifier delivers reminders to the user.
+type Notifier interface {
+	Notify(*model.Reminder) error
+}
+
+// ReminderState keeps the time up to which reminders have been delivered, so that none is delivered twice across
+// restarts.
+type ReminderState interface {
+	// RemindedUntil returns the time last set, it is zero if none has been set.
+	RemindedUntil() (time.Time, error)
+	SetRemindedUntil(time.Time) error
+}
+
+// ReminderScheduler delivers reminders of tasks at their Reminders before their dues, see entity.Item.RemindAt.
+//
+// Reminders are delivered once their time comes, or late if it came while the scheduler was not running, except on
+// the first run, which starts from now. A reminder is delivered at most once: the time up to which reminders are
+// delivered is saved in State before they are delivered.
+type ReminderScheduler struct {
+	Storage
+	Notifier
+	State ReminderState
+	// Clock is the SystemClock if nil.
+	Clock Clock
+	// OnError is called with errors of checking and delivering reminders, which do not stop the scheduler, a failed
+	// check is retried on the next change of items or after ReminderRetryDelay. Errors are ignored if it is nil.
+	OnError func(error)
+}
+
+// ReminderRetryDelay is the time after which a ReminderScheduler checks reminders again if it failed to.
+const ReminderRetryDelay = time.Minute
+
+// Run delivers reminders until ctx is done, tasks are checked again on every change of items.
+func (s *ReminderScheduler) Run(ctx context.Context) error {
+	clock := s.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	// subscribe before loading so no change is missed
+	events, cancel := s.Storage.Subscribe()
+	defer cancel()
+
+	until, err := s.State.RemindedUntil()
+	if err != nil {
+		return fmt.Errorf("getting reminder state: %w", err)
+	}
+	if until.IsZero() {
+		until = clock.Now()
+		if err := s.State.SetRemindedUntil(until); err != nil {
+			return fmt.Errorf("saving reminder state: %w", err)
+		}
+	}
+	for {
+		now := clock.Now()
+		next, err := s.remind(until, now)
+		if err != nil {
+			s.onError(err)
+			next = now.Add(ReminderRetryDelay)
+		} else {
+			until = now
+		}
+
+		var timer <-chan time.Time
+		if !next.IsZero() {
+			timer = clock.After(next.Sub(now))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+			}
+		case <-timer:
+		}
+	}
+}
+
+// remind delivers reminders in (from, to], returns the time to check again.
+func (s *ReminderScheduler) remind(from, to time.Time) (time.Time, error) {
+	reminders, next, err := s.pending(from, to)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(reminders) == 0 {
+		return next, nil
+	}
+	// saved first, so the reminders are not delivered again even if the process stops half way
+	if err := s.State.SetRemindedUntil(to); err != nil {
+		return time.Time{}, fmt.Errorf("saving reminder state: %w", err)
+	}
+	for _, r := range reminders {
+		if err := s.Notify(r); err != nil {
+			s.onError(fmt.Errorf("delivering reminder of task[%d]: %w", r.Task.ID, err))
+		}
+	}
+	return next, nil
+}
+
+func (s *ReminderScheduler) onError(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// pending returns reminders in (from, to] sorted by time, along with the time to check again, which is the time of
+// the first reminder after to, or entity.MaxReminder after to if there is none before that.
+func (s *ReminderScheduler) pending(from, to time.Time) ([]*model.Reminder, time.Time, error) {
+	// a reminder is at most MaxReminder before the due, so those up to next are of items due before next+MaxReminder
+	next := to.Add(entity.MaxReminder)
+	items, err := s.Storage.GetItemsDueBetween(from, next.Add(entity.MaxReminder))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("getting reminders: %w", err)
+	}
+	var reminders []*model.Reminder
+	for _, it := range items {
+		for _, at := range it.RemindAt() {
+			switch {
+			case at.After(to):
+				if at.Before(next) {
+					next = at
+				}
+			case at.After(from):
+				reminders = append(reminders, &model.Reminder{Task: itemToTask(it), At: at})
+			}
+		}
+	}
+	sort.SliceStable(reminders, func(i, j int) bool { return reminders[i].At.Before(reminders[j].At) })
+	return reminders, next, nil
+}
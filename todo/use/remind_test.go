@@ -0,0 +1,238 @@
+package use
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+	"github.com/tevino/the-clean-architecture-demo/todo/use/mock_use"
+)
+
+// fakeClock is a Clock whose time only changes by set, waits are sent to timers.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers chan fakeTimer
+}
+
+type fakeTimer struct {
+	d    time.Duration
+	fire chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	fire := make(chan time.Time, 1)
+	c.timers <- fakeTimer{d, fire}
+	return fire
+}
+
+func (c *fakeClock) set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+type fakeReminderState struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (s *fakeReminderState) RemindedUntil() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.until, nil
+}
+
+func (s *fakeReminderState) SetRemindedUntil(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.until = t
+	return nil
+}
+
+type notifierFunc func(*model.Reminder) error
+
+func (f notifierFunc) Notify(r *model.Reminder) error { return f(r) }
+
+func TestReminderScheduler(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	s := NewMockStorage(ctl)
+
+	start := time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC)
+	report := &entity.Item{ID: 1, Title: "Report", Due: start.Add(time.Hour), Reminders: []time.Duration{15 * time.Minute, 0}}
+	// its reminder has passed before the first run
+	call := &entity.Item{ID: 2, Title: "Call mom", Due: start.Add(30 * time.Minute), Reminders: []time.Duration{time.Hour}}
+	done := &entity.Item{ID: 3, Title: "Done", Due: start.Add(time.Hour), Reminders: []time.Duration{0}, State: entity.ItemStateCompleted}
+	// out of the window of the reminders to check
+	later := &entity.Item{ID: 5, Title: "Later", Due: start.Add(3 * entity.MaxReminder), Reminders: []time.Duration{0}}
+	clock := &fakeClock{now: start, timers: make(chan fakeTimer)}
+	var mu sync.Mutex
+	items := []*entity.Item{report, call, done, later}
+	s.EXPECT().GetItemsDueBetween(gomock.Any(), gomock.Any()).DoAndReturn(func(from, to time.Time) ([]*entity.Item, error) {
+		assert.Equal(t, clock.Now().Add(2*entity.MaxReminder), to)
+		mu.Lock()
+		defer mu.Unlock()
+		var due []*entity.Item
+		for _, it := range items {
+			if !it.Due.Before(from) && it.Due.Before(to) {
+				due = append(due, it)
+			}
+		}
+		return due, nil
+	}).AnyTimes()
+	events := make(chan ItemEvent)
+	s.EXPECT().Subscribe().Return(events, func() {}).Times(2)
+
+	state := &fakeReminderState{}
+	reminders := make(chan *model.Reminder, 10)
+	errs := make(chan error, 10)
+	sched := &ReminderScheduler{
+		Storage: s,
+		Notifier: notifierFunc(func(r *model.Reminder) error {
+			reminders <- r
+			return errors.New("no display")
+		}),
+		State:   state,
+		Clock:   clock,
+		OnError: func(err error) { errs <- err },
+	}
+	run := func() (stop func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ended := make(chan bool)
+		go func() {
+			assert.NoError(t, sched.Run(ctx))
+			ended <- true
+		}()
+		return func() {
+			cancel()
+			<-ended
+		}
+	}
+	stop := run()
+
+	// waits until the first reminder
+	timer := <-clock.timers
+	assert.Equal(t, 45*time.Minute, timer.d)
+	clock.set(start.Add(45 * time.Minute))
+	timer.fire <- clock.Now()
+	r := <-reminders
+	assert.Equal(t, report.ID, r.Task.ID)
+	assert.Equal(t, start.Add(45*time.Minute), r.At)
+	assert.Error(t, <-errs)
+	assert.Equal(t, start.Add(45*time.Minute), state.until)
+
+	// a change of items is checked at once
+	timer = <-clock.timers
+	assert.Equal(t, 15*time.Minute, timer.d)
+	mu.Lock()
+	soon := &entity.Item{ID: 4, Title: "Soon", Due: start.Add(50 * time.Minute), Reminders: []time.Duration{0}}
+	items = append(items, soon)
+	mu.Unlock()
+	events <- ItemEvent{Type: ItemCreated, Item: soon}
+	timer = <-clock.timers
+	assert.Equal(t, 5*time.Minute, timer.d)
+	stop()
+
+	// reminders passed while not running are delivered once after a restart
+	clock.set(start.Add(2 * time.Hour))
+	stop = run()
+	assert.Equal(t, soon.ID, (<-reminders).Task.ID)
+	assert.Equal(t, report.ID, (<-reminders).Task.ID)
+	assert.Equal(t, start.Add(2*time.Hour), state.until)
+	// nothing left but to check the next window
+	timer = <-clock.timers
+	assert.Equal(t, entity.MaxReminder, timer.d)
+	select {
+	case r := <-reminders:
+		t.Errorf("unexpected reminder: %+v", r)
+	case <-time.After(10 * time.Millisecond):
+	}
+	stop()
+}
+
+func TestReminderSchedulerRetries(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	s := NewMockStorage(ctl)
+
+	start := time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC)
+	report := &entity.Item{ID: 1, Title: "Report", Due: start.Add(time.Minute), Reminders: []time.Duration{0}}
+	clock := &fakeClock{now: start, timers: make(chan fakeTimer)}
+	gomock.InOrder(
+		s.EXPECT().GetItemsDueBetween(gomock.Any(), gomock.Any()).Return(nil, errors.New("file is being edited")),
+		s.EXPECT().GetItemsDueBetween(gomock.Any(), gomock.Any()).Return([]*entity.Item{report}, nil),
+	)
+	s.EXPECT().Subscribe().Return(make(chan ItemEvent), func() {})
+
+	reminders := make(chan *model.Reminder, 10)
+	errs := make(chan error, 10)
+	sched := &ReminderScheduler{
+		Storage: s,
+		Notifier: notifierFunc(func(r *model.Reminder) error {
+			reminders <- r
+			return nil
+		}),
+		State:   &fakeReminderState{until: start},
+		Clock:   clock,
+		OnError: func(err error) { errs <- err },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ended := make(chan bool)
+	go func() {
+		assert.NoError(t, sched.Run(ctx))
+		ended <- true
+	}()
+
+	// a failed check is reported and retried
+	timer := <-clock.timers
+	assert.Error(t, <-errs)
+	assert.Equal(t, ReminderRetryDelay, timer.d)
+	clock.set(start.Add(2 * time.Minute))
+	timer.fire <- clock.Now()
+	assert.Equal(t, report.ID, (<-reminders).Task.ID)
+
+	<-clock.timers
+	cancel()
+	<-ended
+}
+
+func TestAddTaskWithReminders(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	err := tt.AddTask(&model.FormAddTask{Title: "Report", Reminders: []string{"soon"}})
+	assert.True(t, errors.Is(err, entity.ErrInvalidReminder))
+
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(int64(entity.RootID)).Return(entity.RootItem, nil),
+		s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return(nil, nil),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{
+			Title:     "Report",
+			Type:      entity.ItemTypeTask,
+			Reminders: []time.Duration{15 * time.Minute, 24 * time.Hour},
+			Rank:      "i",
+		}}),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTaskAdded(gomock.Any()),
+	)
+	assert.NoError(t, tt.AddTask(&model.FormAddTask{Title: "Report", Type: model.TaskTypeTask, Reminders: []string{"15m", "1d before"}}))
+}
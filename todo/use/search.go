@@ -0,0 +1,293 @@
+package use
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+// ErrEmptyKeyword is returned when searching for a keyword without any word.
+var ErrEmptyKeyword = errors.New("Search keyword could not be empty")
+
+// SearchInTasks presents tasks whose title or description has all words of keyword, a word matches words starting
+// with it regardless of case and diacritics. Tasks matching in the title and matching whole words go first.
+//
+// The index searched is built on first use and then kept up to date by changes from Storage.Subscribe, so a change
+// is found once its event is delivered, until Close is called.
+func (t *TaskInteractor) SearchInTasks(keyword string) error {
+	words := searchWords(keyword)
+	if len(words) == 0 {
+		return ErrEmptyKeyword
+	}
+	ix, err := t.searchIndex()
+	if err != nil {
+		return fmt.Errorf("building search index: %w", err)
+	}
+	return t.Presenter.ShowSearchResults(keyword, ix.search(words))
+}
+
+// searchIndex returns the index of the interactor, building it on first use.
+func (t *TaskInteractor) searchIndex() (*searchIndex, error) {
+	t.searchMu.Lock()
+	defer t.searchMu.Unlock()
+	if t.search != nil {
+		return t.search, nil
+	}
+	// subscribe before loading so no change is missed, changes already loaded are applied again harmlessly
+	events, cancel := t.Storage.Subscribe()
+	ix := newSearchIndex()
+	if err := ix.load(t.Storage); err != nil {
+		cancel()
+		return nil, err
+	}
+	go ix.follow(events)
+	t.search, t.stopSearch = ix, cancel
+	return ix, nil
+}
+
+// Close stops the search index following changes and releases it, a later search builds it again.
+func (t *TaskInteractor) Close() error {
+	t.searchMu.Lock()
+	defer t.searchMu.Unlock()
+	if t.stopSearch != nil {
+		t.stopSearch()
+	}
+	t.search, t.stopSearch = nil, nil
+	return nil
+}
+
+// searchField is a set of fields of an item where a word is found.
+type searchField uint8
+
+// All searchField(s).
+const (
+	searchFieldTitle searchField = 1 << iota
+	searchFieldDescription
+)
+
+// searchIndex is an inverted index of words in titles and descriptions of tasks not in the trash, it is safe for
+// concurrent use.
+type searchIndex struct {
+	mu    sync.RWMutex
+	items map[int64]*entity.Item
+	// postings are items having a word by the word, along with fields the word is found in.
+	postings map[string]map[int64]searchField
+	// words are keys of postings in ascending order, for finding words by prefix.
+	words []string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{items: make(map[int64]*entity.Item), postings: make(map[string]map[int64]searchField)}
+}
+
+// load adds items reachable from the root in s.
+func (ix *searchIndex) load(s Storage) error {
+	return walkItems(s, entity.RootID, func(it *entity.Item) {
+		ix.apply(ItemEvent{Type: ItemCreated, Item: it})
+	})
+}
+
+// follow applies events until the channel is closed.
+func (ix *searchIndex) follow(events <-chan ItemEvent) {
+	for e := range events {
+		ix.apply(e)
+	}
+}
+
+// apply updates the index by an event.
+func (ix *searchIndex) apply(e ItemEvent) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if old, ok := ix.items[e.Item.ID]; ok {
+		ix.remove(old)
+	}
+	switch {
+	case e.Type == ItemDeleted, e.Type == ItemRemoved, e.Item.Type == entity.ItemTypeView:
+		return
+	}
+	ix.items[e.Item.ID] = e.Item
+	for word, field := range itemWords(e.Item) {
+		docs, ok := ix.postings[word]
+		if !ok {
+			docs = make(map[int64]searchField)
+			ix.postings[word] = docs
+			i := sort.SearchStrings(ix.words, word)
+			ix.words = append(ix.words, "")
+			copy(ix.words[i+1:], ix.words[i:])
+			ix.words[i] = word
+		}
+		docs[e.Item.ID] = field
+	}
+}
+
+// remove removes an indexed item.
+func (ix *searchIndex) remove(it *entity.Item) {
+	delete(ix.items, it.ID)
+	for word := range itemWords(it) {
+		docs := ix.postings[word]
+		delete(docs, it.ID)
+		if len(docs) == 0 {
+			delete(ix.postings, word)
+			i := sort.SearchStrings(ix.words, word)
+			ix.words = append(ix.words[:i], ix.words[i+1:]...)
+		}
+	}
+}
+
+// Scores of a word matching a field, the best one counts for each word searched.
+const (
+	scoreTitleWord         = 4
+	scoreTitlePrefix       = 3
+	scoreDescriptionWord   = 2
+	scoreDescriptionPrefix = 1
+)
+
+// search returns items having all words sorted by score, items of the same score are sorted by ID.
+func (ix *searchIndex) search(words []string) []*model.SearchResult {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var scores map[int64]int
+	for _, w := range words {
+		best := make(map[int64]int)
+		for i := sort.SearchStrings(ix.words, w); i < len(ix.words) && strings.HasPrefix(ix.words[i], w); i++ {
+			exact := ix.words[i] == w
+			for id, field := range ix.postings[ix.words[i]] {
+				if scores != nil {
+					if _, ok := scores[id]; !ok {
+						continue
+					}
+				}
+				if s := fieldScore(field, exact); s > best[id] {
+					best[id] = s
+				}
+			}
+		}
+		for id, s := range best {
+			best[id] = s + scores[id]
+		}
+		scores = best
+	}
+
+	ids := make([]int64, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	results := make([]*model.SearchResult, len(ids))
+	for i, id := range ids {
+		results[i] = &model.SearchResult{Task: itemToTask(ix.items[id]), Path: ix.path(id)}
+	}
+	return results
+}
+
+func fieldScore(field searchField, exact bool) int {
+	switch {
+	case field&searchFieldTitle != 0 && exact:
+		return scoreTitleWord
+	case field&searchFieldTitle != 0:
+		return scoreTitlePrefix
+	case exact:
+		return scoreDescriptionWord
+	default:
+		return scoreDescriptionPrefix
+	}
+}
+
+// path returns ancestors of the item of given ID from the top level down, up to the first one not indexed.
+func (ix *searchIndex) path(id int64) []*model.Task {
+	var path []*model.Task
+	seen := map[int64]bool{id: true}
+	for it := ix.items[ix.items[id].ParentItemID]; it != nil && !seen[it.ID]; it = ix.items[it.ParentItemID] {
+		seen[it.ID] = true
+		path = append([]*model.Task{itemToTask(it)}, path...)
+	}
+	return path
+}
+
+// itemWords returns words in the title and description of an item along with fields they are found in.
+func itemWords(it *entity.Item) map[string]searchField {
+	words := make(map[string]searchField)
+	for _, w := range searchWords(it.Title) {
+		words[w] |= searchFieldTitle
+	}
+	for _, w := range searchWords(it.Description) {
+		words[w] |= searchFieldDescription
+	}
+	return words
+}
+
+// searchWords splits s into words of letters and digits, which are folded to lower case without diacritics.
+func searchWords(s string) []string {
+	var words []string
+	var b strings.Builder
+	flush := func() {
+		if b.Len() > 0 {
+			words = append(words, b.String())
+			b.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// combining marks are diacritics of the previous letter
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			r = unicode.ToLower(r)
+			if folded, ok := foldedRunes[r]; ok {
+				b.WriteString(folded)
+			} else {
+				b.WriteRune(r)
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// foldedRunes maps lower case Latin letters with diacritics to the letters without them.
+var foldedRunes = func() map[rune]string {
+	m := make(map[rune]string)
+	for folded, runes := range map[string]string{
+		"a":  "àáâãäåāăą",
+		"ae": "æ",
+		"c":  "çćĉċč",
+		"d":  "ðďđ",
+		"e":  "èéêëēĕėęě",
+		"g":  "ĝğġģ",
+		"h":  "ĥħ",
+		"i":  "ìíîïĩīĭįı",
+		"j":  "ĵ",
+		"k":  "ķ",
+		"l":  "ĺļľŀł",
+		"n":  "ñńņňŉ",
+		"o":  "òóôõöøōŏő",
+		"oe": "œ",
+		"r":  "ŕŗř",
+		"s":  "śŝşšſ",
+		"ss": "ß",
+		"t":  "ţťŧ",
+		"th": "þ",
+		"u":  "ùúûüũūŭůűų",
+		"w":  "ŵ",
+		"y":  "ýÿŷ",
+		"z":  "źżž",
+	} {
+		for _, r := range runes {
+			m[r] = folded
+		}
+	}
+	return m
+}()
@@ -0,0 +1,103 @@
+package use
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+	"github.com/tevino/the-clean-architecture-demo/todo/use/mock_use"
+)
+
+func TestSearchWords(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{"cafe", "deja", "vu", "aeon", "strasse", "naive", "42"},
+		searchWords("Café déjà-vu, ÆON Straße\tnaïve #42"))
+	// combining marks
+	assert.Equal(t, []string{"cafe"}, searchWords("Café"))
+	assert.Empty(t, searchWords(" -- "))
+}
+
+func resultTitles(results []*model.SearchResult) []string {
+	titles := make([]string, len(results))
+	for i, r := range results {
+		titles[i] = r.Task.Title
+	}
+	return titles
+}
+
+func TestSearchIndexRanksResults(t *testing.T) {
+	t.Parallel()
+	ix := newSearchIndex()
+	for _, it := range []*entity.Item{
+		{ID: 1, Title: "Notes", Description: "release"},
+		{ID: 2, Title: "Releases"},
+		{ID: 3, Title: "Release", Description: "write notes"},
+		{ID: 4, Title: "Unrelated", Description: "releases soon"},
+	} {
+		ix.apply(ItemEvent{Type: ItemCreated, Item: it})
+	}
+
+	assert.Equal(t, []string{"Release", "Releases", "Notes", "Unrelated"}, resultTitles(ix.search([]string{"release"})))
+	// all words must match
+	// of the same score
+	assert.Equal(t, []string{"Notes", "Release"}, resultTitles(ix.search([]string{"not", "rel"})))
+	assert.Empty(t, ix.search([]string{"release", "missing"}))
+
+	// changes
+	ix.apply(ItemEvent{Type: ItemUpdated, Item: &entity.Item{ID: 2, Title: "Changes"}})
+	ix.apply(ItemEvent{Type: ItemDeleted, Item: &entity.Item{ID: 3, Title: "Release", Description: "write notes"}})
+	assert.Equal(t, []string{"Notes", "Unrelated"}, resultTitles(ix.search([]string{"release"})))
+	assert.Equal(t, []string{"Changes"}, resultTitles(ix.search([]string{"chang"})))
+	assert.Equal(t, []string{"changes", "notes", "release", "releases", "soon", "unrelated"}, ix.words)
+}
+
+func TestSearchInTasks(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+	p := tt.Presenter.(*mock_use.MockPresenter)
+
+	work := &entity.Item{ID: 1, Title: "Work"}
+	release := &entity.Item{ID: 2, Title: "Release", ParentItemID: 1}
+	changelog := &entity.Item{ID: 3, Title: "Write changelog", Description: "for the release", ParentItemID: 2}
+	events := make(chan ItemEvent)
+	s.EXPECT().Subscribe().Return(events, func() { close(events) })
+	s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return([]*entity.Item{work}, nil)
+	s.EXPECT().GetItemsByParentID(work.ID).Return([]*entity.Item{release}, nil)
+	s.EXPECT().GetItemsByParentID(release.ID).Return([]*entity.Item{changelog}, nil)
+	s.EXPECT().GetItemsByParentID(changelog.ID).Return([]*entity.Item{}, nil)
+
+	var results []*model.SearchResult
+	p.EXPECT().ShowSearchResults(gomock.Any(), gomock.Any()).DoAndReturn(func(_ string, r []*model.SearchResult) error {
+		results = r
+		return nil
+	}).AnyTimes()
+
+	assert.NoError(t, tt.SearchInTasks("RELEASE"))
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, itemToTask(release), results[0].Task)
+		assert.Equal(t, []*model.Task{itemToTask(work)}, results[0].Path)
+		assert.Equal(t, itemToTask(changelog), results[1].Task)
+		assert.Equal(t, []*model.Task{itemToTask(work), itemToTask(release)}, results[1].Path)
+	}
+
+	// the index is built once and follows changes
+	events <- ItemEvent{Type: ItemCreated, Item: &entity.Item{ID: 4, Title: "Release party", ParentItemID: 1}}
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, tt.SearchInTasks("party"))
+		return len(results) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, ErrEmptyKeyword, tt.SearchInTasks(" ! "))
+
+	// the subscription is cancelled once
+	assert.NoError(t, tt.Close())
+	assert.Nil(t, tt.search)
+	assert.NoError(t, tt.Close())
+}
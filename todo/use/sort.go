@@ -0,0 +1,29 @@
+package use
+
+import (
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+var sortKeyMap = map[model.SortKey]entity.SortKey{
+	model.SortByPriority: entity.SortByPriority,
+	model.SortByDue:      entity.SortByDue,
+	model.SortByCreated:  entity.SortByCreated,
+	model.SortByTitle:    entity.SortByTitle,
+}
+
+// sortItems sorts items given in the manual order by s, keys after SortByOrder are ignored since they could only
+// matter for items equal by the manual order.
+func sortItems(items []*entity.Item, s model.TaskSort) {
+	var keys []entity.SortKey
+	for _, k := range s {
+		key, ok := sortKeyMap[k]
+		if !ok {
+			break
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 {
+		entity.SortItems(items, keys)
+	}
+}
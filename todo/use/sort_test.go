@@ -0,0 +1,39 @@
+package use
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+	"github.com/tevino/the-clean-architecture-demo/todo/use/mock_use"
+)
+
+func TestListSortedTasks(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	call := &entity.Item{ID: 1, Title: "Call mom", Rank: "h"}
+	report := &entity.Item{ID: 2, Title: "Report", Priority: entity.ItemPriorityHigh, Rank: "i"}
+	bills := &entity.Item{ID: 3, Title: "Pay bills", Priority: entity.ItemPriorityHigh, Rank: "j"}
+	items := func() []*entity.Item {
+		return []*entity.Item{call, report, bills}
+	}
+	s, p := tt.Storage.(*MockStorage), tt.Presenter.(*mock_use.MockPresenter)
+	gomock.InOrder(
+		s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return(items(), nil),
+		p.EXPECT().ShowTasksOfParentID(int64(entity.RootID), []*model.Task{itemToTask(call), itemToTask(report), itemToTask(bills)}),
+		s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return(items(), nil),
+		p.EXPECT().ShowTasksOfParentID(int64(entity.RootID), []*model.Task{itemToTask(bills), itemToTask(report), itemToTask(call)}),
+		// keys after the manual order are ignored
+		s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return(items(), nil),
+		p.EXPECT().ShowTasksOfParentID(int64(entity.RootID), []*model.Task{itemToTask(report), itemToTask(bills), itemToTask(call)}),
+	)
+	assert.NoError(t, tt.ListTasksByParentID(entity.RootID, model.TaskSort{model.SortByOrder}))
+	assert.NoError(t, tt.ListTasksByParentID(entity.RootID, model.TaskSort{model.SortByPriority, model.SortByTitle}))
+	assert.NoError(t, tt.ListTasksByParentID(entity.RootID, model.TaskSort{model.SortByPriority, model.SortByOrder, model.SortByTitle}))
+}
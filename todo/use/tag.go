@@ -0,0 +1,196 @@
+package use
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+// errors
+var (
+	ErrTagNotFound = errors.New("Tag not found")
+	ErrTagExists   = errors.New("Tag already exists")
+)
+
+// ListTags lists tags of tasks sorted by name, each with the number of tasks labeled. Tags are not stored on their
+// own: a tag comes into being by tagging a task with it, and is gone once no task has it.
+func (t *TaskInteractor) ListTags() error {
+	counts := make(map[string]int)
+	err := walkItems(t.Storage, entity.RootID, func(it *entity.Item) {
+		for _, tag := range it.Tags {
+			counts[tag]++
+		}
+	})
+	if err != nil {
+		return err
+	}
+	tags := make([]*model.Tag, 0, len(counts))
+	for name, n := range counts {
+		tags = append(tags, &model.Tag{Name: name, Count: n})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return t.Presenter.ShowTags(tags)
+}
+
+// ListTasksByTag lists tasks labeled with the tag under any parent, as a view named by the tag with a leading "#".
+func (t *TaskInteractor) ListTasksByTag(tag string) error {
+	tag, err := entity.ParseTag(tag)
+	if err != nil {
+		return err
+	}
+	return t.listTasksInView(&entity.TaskView{
+		Name:   "#" + tag,
+		Filter: &entity.Composition{Conditions: []*entity.Condition{{Type: entity.Equal, Target: entity.Tag, Value: tag}}},
+	}, nil)
+}
+
+// TagTask replaces tags of a task, the change is rejected with ErrConflict if the task is not at given revision.
+func (t *TaskInteractor) TagTask(taskID int64, revision uint64, tags []string) error {
+	tags, err := parseTags(tags)
+	if err != nil {
+		return err
+	}
+	return t.Storage.RunInTx(func(s Storage) error {
+		item, err := s.GetItemByID(taskID)
+		if err != nil {
+			return fmt.Errorf("getting item: %w", err)
+		}
+		item.Revision = revision
+		item.Tags = tags
+		if _, err := s.SaveItem(item); err != nil {
+			return fmt.Errorf("saving item: %w", err)
+		}
+		return nil
+	})
+}
+
+// RenameTag renames a tag on every task and in the queries of saved views, it is rejected with ErrTagExists if any
+// task has the new name, which is what MergeTags is for. Renaming a tag to its own name changes nothing.
+//
+// Tasks in the trash are out of reach of Storage, so they keep the old tag and bring it back once restored, when
+// MergeTags folds it into the new one.
+func (t *TaskInteractor) RenameTag(from, to string) error {
+	to, err := entity.ParseTag(to)
+	if err != nil {
+		return err
+	}
+	return t.replaceTags([]string{from}, to, true)
+}
+
+// MergeTags replaces tags in from with into on every task and in the queries of saved views, tasks in the trash keep
+// their tags as they do for RenameTag.
+func (t *TaskInteractor) MergeTags(from []string, into string) error {
+	into, err := entity.ParseTag(into)
+	if err != nil {
+		return err
+	}
+	return t.replaceTags(from, into, false)
+}
+
+// DeleteTag removes a tag from every task, saved views with the tag are kept as they are since removing the
+// condition would widen what they match.
+func (t *TaskInteractor) DeleteTag(tag string) error {
+	return t.replaceTags([]string{tag}, "", false)
+}
+
+// replaceTags replaces tags in from with to on every task and in the queries of saved views, tags are removed from
+// tasks if to is empty, views are kept as they are then. It fails with ErrTagNotFound if no task has any of from, and
+// with ErrTagExists if to must be new but is not, which it may be if it is one of from. Only tasks and views which
+// are changed are saved.
+func (t *TaskInteractor) replaceTags(from []string, to string, newTo bool) error {
+	from, err := parseTags(from)
+	if err != nil {
+		return err
+	}
+	var changed int
+	err = t.Storage.RunInTx(func(s Storage) error {
+		var items []*entity.Item
+		found, exists := false, false
+		err := walkItems(s, entity.RootID, func(it *entity.Item) {
+			exists = exists || it.HasTag(to)
+			tags := it.Tags
+			if it.ReplaceTags(from, to) {
+				found = true
+				if !reflect.DeepEqual(tags, it.Tags) {
+					items = append(items, it)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		switch {
+		case !found:
+			return fmt.Errorf("%w: %v", ErrTagNotFound, from)
+		case newTo && exists && !containsString(from, to):
+			return fmt.Errorf("%w: %s", ErrTagExists, to)
+		}
+		changed = len(items)
+		if to != "" {
+			views, err := replaceViewTags(s, from, to)
+			if err != nil {
+				return err
+			}
+			items = append(items, views...)
+		}
+		for _, it := range items {
+			if _, err := s.SaveItem(it); err != nil {
+				return fmt.Errorf("saving item[%d]: %w", it.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.Presenter.ShowTagsReplaced(from, to, changed)
+}
+
+// replaceViewTags replaces tags in from with to in the queries of saved views, returns the changed views. A query
+// which could not be parsed is left as it is.
+func replaceViewTags(s Storage, from []string, to string) ([]*entity.Item, error) {
+	items, err := s.GetItemsByParentID(entity.RootID)
+	if err != nil {
+		return nil, fmt.Errorf("getting items of parent[%d]: %w", entity.RootID, err)
+	}
+	var views []*entity.Item
+	for _, it := range items {
+		if it.Type != entity.ItemTypeView {
+			continue
+		}
+		filter, err := entity.ParseQuery(it.Description)
+		if err == nil && filter.ReplaceTags(from, to) {
+			it.Description = filter.String()
+			views = append(views, it)
+		}
+	}
+	return views, nil
+}
+
+// parseTags parses tags by entity.ParseTag, duplicates are dropped.
+func parseTags(tags []string) ([]string, error) {
+	var parsed []string
+	for _, s := range tags {
+		tag, err := entity.ParseTag(s)
+		if err != nil {
+			return nil, err
+		}
+		if !containsString(parsed, tag) {
+			parsed = append(parsed, tag)
+		}
+	}
+	return parsed, nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package use
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+	"github.com/tevino/the-clean-architecture-demo/todo/use/mock_use"
+)
+
+// expectTaggedItems expects items to be listed by parent from a tree of a category with two tasks and a view.
+func expectTaggedItems(s *MockStorage) {
+	children := map[int64][]*entity.Item{
+		entity.RootID: {
+			{ID: 1, Title: "Work", Type: entity.ItemTypeCategory, Tags: []string{"office"}},
+			{ID: 4, Title: "Urgent", Type: entity.ItemTypeView, Description: "tag:urgent"},
+		},
+		1: {
+			{ID: 2, Title: "Report", Type: entity.ItemTypeTask, ParentItemID: 1, Tags: []string{"office", "urgent"}, Revision: 3},
+			{ID: 3, Title: "Call", Type: entity.ItemTypeTask, ParentItemID: 1, Tags: []string{"phone"}},
+		},
+	}
+	s.EXPECT().GetItemsByParentID(gomock.Any()).DoAndReturn(func(id int64) ([]*entity.Item, error) {
+		// copies as storages return
+		items := make([]*entity.Item, len(children[id]))
+		for i, it := range children[id] {
+			copied := *it
+			copied.Tags = append([]string(nil), it.Tags...)
+			items[i] = &copied
+		}
+		return items, nil
+	}).AnyTimes()
+}
+
+func TestListTags(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	expectTaggedItems(tt.Storage.(*MockStorage))
+
+	tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTags([]*model.Tag{
+		{Name: "office", Count: 2},
+		{Name: "phone", Count: 1},
+		{Name: "urgent", Count: 1},
+	})
+	assert.NoError(t, tt.ListTags())
+}
+
+func TestListTasksByTag(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	expectTaggedItems(tt.Storage.(*MockStorage))
+
+	assert.True(t, errors.Is(tt.ListTasksByTag("#1"), entity.ErrInvalidTag))
+	tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTasksInView("#office", gomock.Any()).DoAndReturn(
+		func(_ string, tasks []*model.Task) error {
+			if assert.Len(t, tasks, 2) {
+				assert.Equal(t, "Work", tasks[0].Title)
+				assert.Equal(t, "Report", tasks[1].Title)
+				assert.Equal(t, []string{"office", "urgent"}, tasks[1].Tags)
+			}
+			return nil
+		})
+	assert.NoError(t, tt.ListTasksByTag("#office"))
+}
+
+func TestTagTask(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	assert.True(t, errors.Is(tt.TagTask(2, 3, []string{"a b"}), entity.ErrInvalidTag))
+
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(int64(2)).Return(&entity.Item{ID: 2, Title: "Report", Tags: []string{"office"}, Revision: 4}, nil),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 2, Title: "Report", Tags: []string{"urgent", "Q1"}, Revision: 3}}).
+			Return(int64(2), ErrConflict),
+	)
+	err := tt.TagTask(2, 3, []string{"#urgent", "Q1", "urgent"})
+	assert.True(t, errors.Is(err, ErrConflict))
+}
+
+func TestRenameTag(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+	expectTaggedItems(s)
+	s.EXPECT().RunInTx(gomock.Any()).DoAndReturn(func(fn func(Storage) error) error { return fn(s) }).AnyTimes()
+
+	assert.True(t, errors.Is(tt.RenameTag("phone", "#1"), entity.ErrInvalidTag))
+	assert.True(t, errors.Is(tt.RenameTag("garden", "yard"), ErrTagNotFound))
+	assert.True(t, errors.Is(tt.RenameTag("phone", "office"), ErrTagExists))
+
+	gomock.InOrder(
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 3, Title: "Call", Type: entity.ItemTypeTask, ParentItemID: 1, Tags: []string{"call"}}}),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTagsReplaced([]string{"phone"}, "call", 1),
+	)
+	assert.NoError(t, tt.RenameTag("#phone", "call"))
+
+	// renaming a tag to its own name saves nothing
+	tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTagsReplaced([]string{"urgent"}, "urgent", 0)
+	assert.NoError(t, tt.RenameTag("urgent", "#urgent"))
+	assert.True(t, errors.Is(tt.RenameTag("garden", "garden"), ErrTagNotFound))
+}
+
+func TestMergeAndDeleteTags(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+	p := tt.Presenter.(*mock_use.MockPresenter)
+	expectTaggedItems(s)
+	s.EXPECT().RunInTx(gomock.Any()).DoAndReturn(func(fn func(Storage) error) error { return fn(s) }).AnyTimes()
+
+	gomock.InOrder(
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 2, Title: "Report", Type: entity.ItemTypeTask, ParentItemID: 1, Tags: []string{"office"}, Revision: 3}}),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 3, Title: "Call", Type: entity.ItemTypeTask, ParentItemID: 1, Tags: []string{"office"}}}),
+		// so is the query of the view
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 4, Title: "Urgent", Type: entity.ItemTypeView, Description: "tag:office"}}),
+		p.EXPECT().ShowTagsReplaced([]string{"urgent", "phone"}, "office", 2),
+	)
+	assert.NoError(t, tt.MergeTags([]string{"urgent", "phone"}, "office"))
+
+	gomock.InOrder(
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 1, Title: "Work", Type: entity.ItemTypeCategory}}),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 2, Title: "Report", Type: entity.ItemTypeTask, ParentItemID: 1, Tags: []string{"urgent"}, Revision: 3}}),
+		p.EXPECT().ShowTagsReplaced([]string{"office"}, "", 2),
+	)
+	assert.NoError(t, tt.DeleteTag("office"))
+	assert.True(t, errors.Is(tt.DeleteTag("garden"), ErrTagNotFound))
+}
+
+func TestAddTaskWithTags(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	err := tt.AddTask(&model.FormAddTask{Title: "Report", Tags: []string{"1st"}})
+	assert.True(t, errors.Is(err, entity.ErrInvalidTag))
+
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(int64(entity.RootID)).Return(entity.RootItem, nil),
+		s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return(nil, nil),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{Title: "Report", Type: entity.ItemTypeTask, Tags: []string{"work", "Q1"}, Rank: "i"}}),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTaskAdded(gomock.Any()),
+	)
+	assert.NoError(t, tt.AddTask(&model.FormAddTask{Title: "Report", Type: model.TaskTypeTask, Tags: []string{"#work", "Q1", "work"}}))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/tevino/the-clean-architecture-demo/todo/entity"
@@ -14,75 +15,158 @@ import (
 type TaskInteractor struct {
 	Presenter
 	Storage
+
+	// AutoCompleteProjects completes a project once all the tasks under it are completed, see completeProjects.
+	AutoCompleteProjects bool
+
+	searchMu sync.Mutex
+	// search is built on the first search, stopSearch stops it following changes.
+	search     *searchIndex
+	stopSearch func()
 }
 
 // errors
 var (
 	ErrEmptyTitle = errors.New("Task title could not be empty")
+	// ErrConflict is wrapped by errors returned from Storage when an item is changed based on a stale revision.
+	ErrConflict = errors.New("Task has been changed by others")
+	// ErrNilItem and the errors below are wrapped by errors returned from Storage as well, ErrNilItem is returned when
+	// a nil item is saved.
+	ErrNilItem = errors.New("Item could not be nil")
+	// ErrItemNotFound is returned when an item does not exist or is in the trash.
+	ErrItemNotFound = errors.New("Item not found")
+	// ErrItemNotDeleted is returned when an item which is not in the trash is restored.
+	ErrItemNotDeleted = errors.New("Item is not in the trash")
+	// ErrParentDeleted is returned when an item is saved under or restored to a parent in the trash.
+	ErrParentDeleted = errors.New("Parent of the item is in the trash")
 )
 
-func (t *TaskInteractor) validateAddTask(f *model.FormAddTask) error {
+func (t *TaskInteractor) validateAddTask(s Storage, f *model.FormAddTask) error {
 	if f.Title == "" || strings.TrimSpace(f.Title) == "" {
 		return ErrEmptyTitle
 	}
-	if _, err := t.Storage.GetItemByID(f.ParentID); err != nil {
+	parent, err := s.GetItemByID(f.ParentID)
+	if err != nil {
 		return fmt.Errorf("getting parent item: %w", err)
 	}
+	if parent.Type == entity.ItemTypeView {
+		return fmt.Errorf("%w: parent[%d]", ErrParentIsView, f.ParentID)
+	}
 	return nil
 }
 
 func (t *TaskInteractor) AddTask(f *model.FormAddTask) error {
-	if err := t.validateAddTask(f); err != nil {
-		return fmt.Errorf("validating task: %w", err)
-	}
 	newTask := &entity.Item{
 		Title:        f.Title,
 		Due:          f.Due,
 		Description:  f.Description,
-		Order:        f.Order,
 		Type:         taskTypeToItemType(f.Type),
 		State:        entity.ItemStateNormal,
+		Priority:     taskPriorityToItemPriority(f.Priority),
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 		ParentItemID: f.ParentID,
 	}
-	taskID, err := t.Storage.SaveItem(newTask)
+	if f.Recurrence != "" {
+		r, err := entity.ParseRecurrence(f.Recurrence)
+		if err != nil {
+			return fmt.Errorf("validating task: %w", err)
+		}
+		newTask.Recurrence = r.String()
+	}
+	for _, s := range f.Reminders {
+		d, err := entity.ParseReminder(s)
+		if err != nil {
+			return fmt.Errorf("validating task: %w", err)
+		}
+		newTask.Reminders = append(newTask.Reminders, d)
+	}
+	tags, err := parseTags(f.Tags)
 	if err != nil {
-		return fmt.Errorf("saving task: %w", err)
+		return fmt.Errorf("validating task: %w", err)
 	}
-	newTask.ID = taskID
-
-	err = t.Storage.IncreaseOrderAfter(newTask)
+	newTask.Tags = tags
+	if f.Estimate != "" {
+		if newTask.Estimate, err = entity.ParseEstimate(f.Estimate); err != nil {
+			return fmt.Errorf("validating task: %w", err)
+		}
+	}
+	err = t.Storage.RunInTx(func(s Storage) error {
+		if err := t.validateAddTask(s, f); err != nil {
+			return fmt.Errorf("validating task: %w", err)
+		}
+		siblings, err := s.GetItemsByParentID(f.ParentID)
+		if err != nil {
+			return fmt.Errorf("getting items of parent[%d]: %w", f.ParentID, err)
+		}
+		newTask.Rank, err = rankAt(s, tasksOnly(siblings), f.Position)
+		if err != nil {
+			return err
+		}
+		taskID, err := s.SaveItem(newTask)
+		if err != nil {
+			return fmt.Errorf("saving task: %w", err)
+		}
+		newTask.ID = taskID
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("changing order: %w", err)
+		return err
 	}
 
 	return t.Presenter.ShowTaskAdded(itemToTask(newTask))
 }
 
-func (t *TaskInteractor) ChangeTaskStateByID(taskID int64, s model.TaskState) error {
-	item, err := t.Storage.GetItemByID(taskID)
-	if err != nil {
-		return fmt.Errorf("getting item: %w", err)
-	}
-	item.State = taskStateToItemState(s)
-	_, err = t.Storage.SaveItem(item)
-	if err != nil {
-		return fmt.Errorf("saving item: %w", err)
-	}
-	// TODO: Presenter.ShowTaskUpdated()?
-	return nil
+// ChangeTaskStateByID changes the state of a task, the change is rejected with ErrConflict if the task is not at
+// given revision, which is the one the user saw. Completing a recurring task adds its next occurrence, see
+// repeatItem, and completing the last task of a project may complete the project, see AutoCompleteProjects.
+func (t *TaskInteractor) ChangeTaskStateByID(taskID int64, revision uint64, s model.TaskState) error {
+	return t.Storage.RunInTx(func(tx Storage) error {
+		item, err := tx.GetItemByID(taskID)
+		if err != nil {
+			return fmt.Errorf("getting item: %w", err)
+		}
+		item.Revision = revision
+		state := taskStateToItemState(s)
+		var recurrence string
+		switch {
+		case state != entity.ItemStateCompleted:
+			item.CompletedAt = time.Time{}
+		case item.State != entity.ItemStateCompleted:
+			item.CompletedAt = time.Now().UTC()
+			recurrence, item.Recurrence = item.Recurrence, ""
+		}
+		item.State = state
+		_, err = tx.SaveItem(item)
+		if err != nil {
+			return fmt.Errorf("saving item: %w", err)
+		}
+		if recurrence != "" {
+			if err := repeatItem(tx, item, recurrence); err != nil {
+				return fmt.Errorf("repeating item: %w", err)
+			}
+		}
+		if t.AutoCompleteProjects && state == entity.ItemStateCompleted {
+			if err := completeProjects(tx, item.ParentItemID); err != nil {
+				return fmt.Errorf("completing projects: %w", err)
+			}
+		}
+		// TODO: Presenter.ShowTaskUpdated()?
+		return nil
+	})
 }
 
-// ListTasksByParentID lists sub tasks of a given parent.
-func (t *TaskInteractor) ListTasksByParentID(parentID int64) error {
+// ListTasksByParentID lists sub tasks of a given parent sorted by s, views are listed by ListViews instead.
+func (t *TaskInteractor) ListTasksByParentID(parentID int64, s model.TaskSort) error {
 	items, err := t.Storage.GetItemsByParentID(parentID)
 	if err != nil {
 		return fmt.Errorf("getting tasks from storage: %w", err)
 	}
-	tasks := make([]*model.Task, len(items))
-	for i, it := range items {
-		tasks[i] = itemToTask(it)
+	items = tasksOnly(items)
+	sortItems(items, s)
+	tasks, err := itemsToTasks(t.Storage, items)
+	if err != nil {
+		return err
 	}
 	err = t.ShowTasksOfParentID(parentID, tasks)
 	if err != nil {
@@ -91,11 +175,25 @@ func (t *TaskInteractor) ListTasksByParentID(parentID int64) error {
 	return nil
 }
 
-// TODO: implement task search
-// // SearchInTasks returns tasks with given keyword.
-// func (t *TaskInteractor) SearchInTasks(keyword string) ([]*model.Task, error) {
-// 	return nil, nil
-// }
+// ListPathToParentID shows the path to a parent, which is the parent and its ancestors from the top level down, the
+// path to the root is empty.
+func (t *TaskInteractor) ListPathToParentID(parentID int64) error {
+	var path []*model.Task
+	visited := make(map[int64]bool)
+	for id := parentID; id != entity.RootID && !visited[id]; {
+		visited[id] = true
+		it, err := t.Storage.GetItemByID(id)
+		if err != nil {
+			return fmt.Errorf("getting item[%d]: %w", id, err)
+		}
+		path = append([]*model.Task{itemToTask(it)}, path...)
+		id = it.ParentItemID
+	}
+	if err := t.Presenter.ShowPathToParentID(parentID, path); err != nil {
+		return fmt.Errorf("showing path to parent[%d]: %w", parentID, err)
+	}
+	return nil
+}
 
 func itemToTask(it *entity.Item) *model.Task {
 	return &model.Task{
@@ -104,30 +202,92 @@ func itemToTask(it *entity.Item) *model.Task {
 		Due:         it.Due,
 		Type:        itemTypeToTaskType(it.Type),
 		State:       itemStateToTaskState(it.State),
+		Priority:    itemPriorityToTaskPriority(it.Priority),
+		Estimate:    it.Estimate,
 		Description: it.Description,
-		Order:       it.Order,
+		Revision:    it.Revision,
+		ParentID:    it.ParentItemID,
+		Recurrence:  it.Recurrence,
+		Reminders:   it.Reminders,
+		Tags:        it.Tags,
 	}
 }
 
-//go:generate mockgen -destination mock_use/task_mock.go github.com/tevino/the-clean-architecture-demo/todo/use CasesTask,Presenter,Storage
+//go:generate mockgen -destination mock_use/task_mock.go github.com/tevino/the-clean-architecture-demo/todo/use CasesTask,Presenter
+// Storage refers to the package itself, its mock is generated into the package for tests to avoid an import cycle.
+//go:generate mockgen -destination storage_mock_test.go -package use -self_package github.com/tevino/the-clean-architecture-demo/todo/use github.com/tevino/the-clean-architecture-demo/todo/use Storage
 
 // CasesTask represents the Input Port of the task Interactor.
 type CasesTask interface {
 	AddTask(*model.FormAddTask) error
-	ListTasksByParentID(int64) error
-	ChangeTaskStateByID(int64, model.TaskState) error
+	ListTasksByParentID(int64, model.TaskSort) error
+	ListPathToParentID(parentID int64) error
+	ChangeTaskStateByID(taskID int64, revision uint64, s model.TaskState) error
+	MoveTask(taskID, parentID int64, position int) error
+	DeleteTask(taskID int64) error
+	RestoreTask(taskID int64) error
+	EmptyTrash() error
+	SearchInTasks(keyword string) error
+	ListViews() error
+	AddView(name, query string) error
+	UpdateView(viewID int64, revision uint64, name, query string) error
+	DeleteView(viewID int64) error
+	ListTasksInView(viewID int64, s model.TaskSort) error
+	ListTags() error
+	ListTasksByTag(tag string) error
+	TagTask(taskID int64, revision uint64, tags []string) error
+	RenameTag(from, to string) error
+	MergeTags(from []string, into string) error
+	DeleteTag(tag string) error
+	SubscribeTaskEvents() (events <-chan model.TaskEvent, cancel func())
 }
 
 // Presenter represents the Output Port of Interactor.
 type Presenter interface {
 	ShowTaskAdded(*model.Task) error
 	ShowTasksOfParentID(int64, []*model.Task) error
+	// ShowPathToParentID shows the parent of given ID and its ancestors, from the top level down.
+	ShowPathToParentID(parentID int64, path []*model.Task) error
+	ShowTaskDeleted(*model.Task) error
+	ShowTaskRestored(*model.Task) error
+	ShowTrashEmptied(removed int) error
+	// ShowSearchResults shows tasks found by keyword, the best match goes first.
+	ShowSearchResults(keyword string, results []*model.SearchResult) error
+	// ShowTasksInView shows tasks in the view of given name.
+	ShowTasksInView(name string, tasks []*model.Task) error
+	// ShowViews shows views, built-in views go first.
+	ShowViews([]*model.View) error
+	ShowViewSaved(*model.View) error
+	ShowViewDeleted(*model.View) error
+	// ShowTags shows tags sorted by name.
+	ShowTags([]*model.Tag) error
+	// ShowTagsReplaced shows that tags in from are replaced with to on given number of tasks, to is empty if they are
+	// removed.
+	ShowTagsReplaced(from []string, to string, tasks int) error
 }
 
 // Storage represents the entity gateway.
 type Storage interface {
 	SaveItem(*entity.Item) (int64, error)
+	// GetItemsByParentID returns items of given parent sorted by rank, items of the same rank are sorted by ID.
 	GetItemsByParentID(parentID int64) ([]*entity.Item, error)
 	GetItemByID(int64) (*entity.Item, error)
-	IncreaseOrderAfter(item *entity.Item) error
+	// GetItemsDueBetween returns items due in [from, to) sorted by due, items of the same due are sorted by ID.
+	GetItemsDueBetween(from, to time.Time) ([]*entity.Item, error)
+	// DeleteItem moves the item of given ID to the trash along with its descendants. Items in the trash are not
+	// found by the other methods.
+	DeleteItem(id int64) error
+	// RestoreItem moves the item of given ID back from the trash along with the descendants deleted with it, the
+	// item goes back to its rank among its siblings. An item deleted along with its parent could only be restored
+	// with the parent.
+	RestoreItem(id int64) error
+	// EmptyTrash removes items in the trash permanently, returns the number of removed items.
+	EmptyTrash() (int, error)
+	// RunInTx runs fn within a transaction, changes made through the Storage given to fn are either all kept if fn
+	// returns nil or all discarded otherwise. fn must only use the given Storage.
+	RunInTx(fn func(Storage) error) error
+	// Subscribe returns a channel of changes made to items from now on in the order they are made, including those
+	// made by others sharing the same data, e.g. other processes, if the storage could detect them. The channel is
+	// closed once cancel is called.
+	Subscribe() (events <-chan ItemEvent, cancel func())
 }
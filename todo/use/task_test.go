@@ -22,10 +22,18 @@ var i64 int64
 func newTask(ctl *gomock.Controller) *TaskInteractor {
 	return &TaskInteractor{
 		Presenter: mock_use.NewMockPresenter(ctl),
-		Storage:   mock_use.NewMockStorage(ctl),
+		Storage:   NewMockStorage(ctl),
 	}
 }
 
+// expectTx expects a transaction which runs the callback on the same mock storage.
+func expectTx(tt *TaskInteractor) *gomock.Call {
+	s := tt.Storage.(*MockStorage)
+	return s.EXPECT().RunInTx(gomock.Any()).DoAndReturn(func(fn func(Storage) error) error {
+		return fn(s)
+	})
+}
+
 type itemMatcher struct {
 	item entity.Item
 }
@@ -33,9 +41,10 @@ type itemMatcher struct {
 // Matches returns whether x is a match.
 func (m itemMatcher) Matches(x interface{}) bool {
 	target := x.(*entity.Item)
-	// skip the comparision of created and updated at
+	// skip the comparision of created, updated and completed at
 	m.item.CreatedAt = target.CreatedAt
 	m.item.UpdatedAt = target.UpdatedAt
+	m.item.CompletedAt = target.CompletedAt
 	return gomock.Eq(m.item).Matches(*target)
 }
 
@@ -54,28 +63,33 @@ func TestAddTaskSavesAllFields(t *testing.T) {
 		Description: "desc",
 		Type:        model.TaskTypeCategory,
 		ParentID:    entity.RootID + 1,
-		Order:       42,
+		Position:    1,
 	}
 	item := &entity.Item{
 		Title:        form.Title,
 		Due:          form.Due,
 		Description:  form.Description,
-		Order:        form.Order,
+		Rank:         "i",
 		State:        entity.ItemStateNormal,
 		Type:         taskTypeToItemType(form.Type),
 		ParentItemID: form.ParentID,
 	}
 
 	gomock.InOrder(
-		tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemByID(form.ParentID).Return(&entity.Item{}, nil),
-		tt.Storage.(*mock_use.MockStorage).EXPECT().SaveItem(itemMatcher{*item}).Return(i64, io.EOF),
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(form.ParentID).Return(&entity.Item{}, nil),
+		tt.Storage.(*MockStorage).EXPECT().GetItemsByParentID(form.ParentID).Return([]*entity.Item{
+			{ID: 1, Rank: "c"},
+			{ID: 2, Rank: "o"},
+		}, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(itemMatcher{*item}).Return(i64, io.EOF),
 	)
 
 	err := tt.AddTask(form)
 	assert.Error(t, err)
 }
 
-func TestAddTaskUpdatedsOrder(t *testing.T) {
+func TestAddTaskOnlySavesNewTask(t *testing.T) {
 	t.Parallel()
 	ctl := gomock.NewController(t)
 	defer ctl.Finish()
@@ -83,12 +97,39 @@ func TestAddTaskUpdatedsOrder(t *testing.T) {
 	tt := newTask(ctl)
 	var parentID int64 = entity.RootID
 	gomock.InOrder(
-		tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemByID(parentID).Return(&entity.Item{}, nil),
-		tt.Storage.(*mock_use.MockStorage).EXPECT().SaveItem(gomock.Any()),
-		tt.Storage.(*mock_use.MockStorage).EXPECT().IncreaseOrderAfter(gomock.Any()),
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(parentID).Return(&entity.Item{}, nil),
+		tt.Storage.(*MockStorage).EXPECT().GetItemsByParentID(parentID).Return([]*entity.Item{
+			{ID: 1, Rank: "i"},
+		}, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(itemMatcher{entity.Item{Title: "test", Type: entity.ItemTypeCategory, Rank: "h"}}),
 		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTaskAdded(gomock.Any()),
 	)
-	err := tt.AddTask(&model.FormAddTask{Title: "test", ParentID: entity.RootID})
+	err := tt.AddTask(&model.FormAddTask{Title: "test", ParentID: entity.RootID, Position: 0})
+	assert.NoError(t, err)
+}
+
+func TestAddTaskSpreadsRanksWithoutRoom(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	tt := newTask(ctl)
+	var parentID int64 = entity.RootID
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(parentID).Return(&entity.Item{}, nil),
+		// no rank between the same ranks
+		tt.Storage.(*MockStorage).EXPECT().GetItemsByParentID(parentID).Return([]*entity.Item{
+			{ID: 1, Rank: "i"},
+			{ID: 2, Rank: "i"},
+		}, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(itemMatcher{entity.Item{ID: 1, Rank: "9"}}),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(itemMatcher{entity.Item{ID: 2, Rank: "r"}}),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(itemMatcher{entity.Item{Title: "test", Type: entity.ItemTypeCategory, Rank: "i"}}),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTaskAdded(gomock.Any()),
+	)
+	err := tt.AddTask(&model.FormAddTask{Title: "test", ParentID: entity.RootID, Position: 1})
 	assert.NoError(t, err)
 }
 
@@ -105,6 +146,7 @@ func TestAddTaskValidationErrors(t *testing.T) {
 		{model.FormAddTask{Title: ""}, ErrEmptyTitle},
 		{model.FormAddTask{Title: " "}, ErrEmptyTitle},
 	} {
+		expectTx(tt)
 		err := tt.AddTask(&c.form)
 		if c.err != nil {
 			assert.True(t, errors.Is(err, c.err))
@@ -114,7 +156,10 @@ func TestAddTaskValidationErrors(t *testing.T) {
 	}
 
 	// case with storage mock
-	tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(nil, io.EOF)
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(nil, io.EOF),
+	)
 	err := tt.AddTask(&model.FormAddTask{Title: "x", Due: time.Now(), ParentID: 42})
 	assert.Error(t, err)
 }
@@ -127,21 +172,28 @@ func TestAddTaskNonValidationErrors(t *testing.T) {
 
 	// SaveItem error
 	gomock.InOrder(
-		tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(nil, nil),
-		tt.Storage.(*mock_use.MockStorage).EXPECT().SaveItem(gomock.Any()).Return(i64, io.EOF),
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(&entity.Item{ID: 42}, nil),
+		tt.Storage.(*MockStorage).EXPECT().GetItemsByParentID(gomock.Any()).Return(nil, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(gomock.Any()).Return(i64, io.EOF),
 	)
 
 	err := tt.AddTask(&model.FormAddTask{Title: "x", Due: time.Now(), ParentID: 42})
 	assert.Error(t, err)
 
-	// IncreaseOrderAfter error
+	// GetItemsByParentID error, the transaction is rolled back by the storage as the error is returned to it
 	gomock.InOrder(
-		tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(nil, nil),
-		tt.Storage.(*mock_use.MockStorage).EXPECT().SaveItem(gomock.Any()).Return(i64, nil),
-		tt.Storage.(*mock_use.MockStorage).EXPECT().IncreaseOrderAfter(gomock.Any()).Return(io.EOF),
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(&entity.Item{ID: 42}, nil),
+		tt.Storage.(*MockStorage).EXPECT().GetItemsByParentID(gomock.Any()).Return(nil, io.EOF),
 	)
 	err = tt.AddTask(&model.FormAddTask{Title: "x", Due: time.Now(), ParentID: 42})
-	assert.Error(t, err)
+	assert.True(t, errors.Is(err, io.EOF))
+
+	// transaction error
+	tt.Storage.(*MockStorage).EXPECT().RunInTx(gomock.Any()).Return(io.EOF)
+	err = tt.AddTask(&model.FormAddTask{Title: "x", Due: time.Now(), ParentID: 42})
+	assert.True(t, errors.Is(err, io.EOF))
 }
 
 func TestChangeTaskStateByID(t *testing.T) {
@@ -151,13 +203,74 @@ func TestChangeTaskStateByID(t *testing.T) {
 	tt := newTask(ctl)
 
 	gomock.InOrder(
-		tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(&entity.Item{}, nil),
-		tt.Storage.(*mock_use.MockStorage).EXPECT().SaveItem(gomock.Any()).Return(i64, nil),
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(&entity.Item{}, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(gomock.Any()).Return(i64, nil),
 	)
-	err := tt.ChangeTaskStateByID(1, model.TaskStateCompleted)
+	err := tt.ChangeTaskStateByID(1, 0, model.TaskStateCompleted)
 	assert.NoError(t, err)
 }
 
+func TestChangeTaskStateByIDSavesGivenRevision(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	// the storage is responsible for rejecting the revision if it is stale
+	stored := &entity.Item{ID: 1, Revision: 3}
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(stored.ID).Return(stored, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(itemMatcher{entity.Item{
+			ID: 1, Revision: 2, State: entity.ItemStateCompleted,
+		}}).Return(i64, ErrConflict),
+	)
+	err := tt.ChangeTaskStateByID(stored.ID, 2, model.TaskStateCompleted)
+	assert.True(t, errors.Is(err, ErrConflict))
+}
+
+func TestChangeTaskStateByIDSetsCompletedAt(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	var saved *entity.Item
+	save := func(it *entity.Item) (int64, error) {
+		saved = it
+		return it.ID, nil
+	}
+	stored := &entity.Item{ID: 1}
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(stored.ID).Return(stored, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(gomock.Any()).DoAndReturn(save),
+	)
+	before := time.Now()
+	assert.NoError(t, tt.ChangeTaskStateByID(stored.ID, 0, model.TaskStateCompleted))
+	assert.False(t, saved.CompletedAt.Before(before))
+	completedAt := saved.CompletedAt
+
+	// completing it again keeps the time
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(stored.ID).Return(saved, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(gomock.Any()).DoAndReturn(save),
+	)
+	assert.NoError(t, tt.ChangeTaskStateByID(stored.ID, 0, model.TaskStateCompleted))
+	assert.Equal(t, completedAt, saved.CompletedAt)
+
+	// reopening it clears the time
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(stored.ID).Return(saved, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(gomock.Any()).DoAndReturn(save),
+	)
+	assert.NoError(t, tt.ChangeTaskStateByID(stored.ID, 0, model.TaskStateNormal))
+	assert.True(t, saved.CompletedAt.IsZero())
+}
+
 func TestChangeTaskStateByIDErrors(t *testing.T) {
 	t.Parallel()
 	ctl := gomock.NewController(t)
@@ -165,17 +278,21 @@ func TestChangeTaskStateByIDErrors(t *testing.T) {
 	tt := newTask(ctl)
 
 	// GetItemByID error
-	tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(nil, io.EOF)
-	err := tt.ChangeTaskStateByID(1, model.TaskStateCompleted)
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(nil, io.EOF),
+	)
+	err := tt.ChangeTaskStateByID(1, 0, model.TaskStateCompleted)
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, io.EOF))
 
 	// SaveItem error
 	gomock.InOrder(
-		tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(&entity.Item{}, nil),
-		tt.Storage.(*mock_use.MockStorage).EXPECT().SaveItem(gomock.Any()).Return(i64, io.EOF),
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(gomock.Any()).Return(&entity.Item{}, nil),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(gomock.Any()).Return(i64, io.EOF),
 	)
-	err = tt.ChangeTaskStateByID(1, model.TaskStateCompleted)
+	err = tt.ChangeTaskStateByID(1, 0, model.TaskStateCompleted)
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, io.EOF))
 }
@@ -186,12 +303,12 @@ func TestListTasksByParentID(t *testing.T) {
 	defer ctl.Finish()
 	tt := newTask(ctl)
 
-	tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemsByParentID(gomock.Any()).Return([]*entity.Item{
+	tt.Storage.(*MockStorage).EXPECT().GetItemsByParentID(gomock.Any()).Return([]*entity.Item{
 		{Type: entity.ItemTypeCategory},
 		{Type: entity.ItemTypeTask},
 	}, nil)
 	tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTasksOfParentID(gomock.Any(), gomock.Any())
-	err := tt.ListTasksByParentID(0)
+	err := tt.ListTasksByParentID(0, nil)
 	assert.NoError(t, err)
 }
 
@@ -202,20 +319,43 @@ func TestListTasksByParentIDErrors(t *testing.T) {
 	tt := newTask(ctl)
 
 	// GetItemsByParentID error
-	tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemsByParentID(gomock.Any()).Return(nil, io.EOF)
-	err := tt.ListTasksByParentID(0)
+	tt.Storage.(*MockStorage).EXPECT().GetItemsByParentID(gomock.Any()).Return(nil, io.EOF)
+	err := tt.ListTasksByParentID(0, nil)
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, io.EOF))
 
 	gomock.InOrder(
-		tt.Storage.(*mock_use.MockStorage).EXPECT().GetItemsByParentID(gomock.Any()).Return(nil, nil),
+		tt.Storage.(*MockStorage).EXPECT().GetItemsByParentID(gomock.Any()).Return(nil, nil),
 		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTasksOfParentID(gomock.Any(), gomock.Any()).Return(io.EOF),
 	)
-	err = tt.ListTasksByParentID(0)
+	err = tt.ListTasksByParentID(0, nil)
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, io.EOF))
 }
 
+func TestListPathToParentID(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s, p := tt.Storage.(*MockStorage), tt.Presenter.(*mock_use.MockPresenter)
+
+	work := &entity.Item{ID: 1, Title: "Work", Type: entity.ItemTypeCategory}
+	release := &entity.Item{ID: 2, Title: "Release", Type: entity.ItemTypeProject, ParentItemID: 1}
+	docs := &entity.Item{ID: 3, Title: "Docs", ParentItemID: 2}
+	gomock.InOrder(
+		p.EXPECT().ShowPathToParentID(int64(entity.RootID), nil),
+		s.EXPECT().GetItemByID(docs.ID).Return(docs, nil),
+		s.EXPECT().GetItemByID(release.ID).Return(release, nil),
+		s.EXPECT().GetItemByID(work.ID).Return(work, nil),
+		p.EXPECT().ShowPathToParentID(docs.ID, []*model.Task{itemToTask(work), itemToTask(release), itemToTask(docs)}),
+		s.EXPECT().GetItemByID(docs.ID).Return(nil, io.EOF),
+	)
+	assert.NoError(t, tt.ListPathToParentID(entity.RootID))
+	assert.NoError(t, tt.ListPathToParentID(docs.ID))
+	assert.True(t, errors.Is(tt.ListPathToParentID(docs.ID), io.EOF))
+}
+
 func TestItemTypeToTaskTypeEqualLength(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, len(itemTypeToTaskTypeMap), len(taskTypeToItemTypeMap))
@@ -7,7 +7,9 @@ import (
 
 var itemTypeToTaskTypeMap = map[entity.ItemType]model.TaskType{
 	entity.ItemTypeCategory: model.TaskTypeCategory,
+	entity.ItemTypeProject:  model.TaskTypeProject,
 	entity.ItemTypeTask:     model.TaskTypeTask,
+	entity.ItemTypeView:     model.TaskTypeView,
 }
 
 var taskTypeToItemTypeMap = make(map[model.TaskType]entity.ItemType, len(itemTypeToTaskTypeMap))
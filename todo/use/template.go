@@ -26,7 +26,12 @@ Inbox is the place to dump your thoughts into.
 + Projects
 `
 
+// AddTemplate adds the built-in template, nothing is added if any part of it fails.
 func (t *TaskInteractor) AddTemplate() error {
+	return t.Storage.RunInTx(t.addTemplate)
+}
+
+func (t *TaskInteractor) addTemplate(s Storage) error {
 	levelInfoMap := make(map[int]*levelInfo)
 	previousLineIsItem := false
 	var item *entity.Item
@@ -46,16 +51,16 @@ func (t *TaskInteractor) AddTemplate() error {
 		case '+':
 			previousLineIsItem = false
 			// category
-			levelInfoMap[level].Order++
+			levelInfoMap[level].nextRank()
 
 			item = &entity.Item{Title: line[2:], Type: entity.ItemTypeCategory,
-				Order: levelInfoMap[level].Order, ParentItemID: levelInfoMap[level].ParentID}
+				Rank: levelInfoMap[level].Rank, ParentItemID: levelInfoMap[level].ParentID}
 		case '[':
 			// task
 			previousLineIsItem = true
-			levelInfoMap[level].Order++
+			levelInfoMap[level].nextRank()
 			item = &entity.Item{Title: line[4:], Type: entity.ItemTypeTask,
-				Order: levelInfoMap[level].Order, ParentItemID: levelInfoMap[level].ParentID}
+				Rank: levelInfoMap[level].Rank, ParentItemID: levelInfoMap[level].ParentID}
 		default:
 			// item detail
 			if previousLineIsItem {
@@ -73,7 +78,7 @@ func (t *TaskInteractor) AddTemplate() error {
 		}
 
 		if item != nil {
-			id, err := t.Storage.SaveItem(item)
+			id, err := s.SaveItem(item)
 			if err != nil {
 				return fmt.Errorf("save item: %w", err)
 			}
@@ -111,5 +116,11 @@ func _parseDue(s string) (time.Time, error) {
 
 type levelInfo struct {
 	ParentID int64
-	Order    uint64
+	Rank     string
+}
+
+// nextRank moves Rank to the one after it.
+func (l *levelInfo) nextRank() {
+	// a rank is always found after a valid or an empty one
+	l.Rank, _ = entity.RankBetween(l.Rank, "")
 }
@@ -5,8 +5,6 @@ import (
 	"io"
 	"testing"
 
-	"github.com/tevino/the-clean-architecture-demo/todo/use/mock_use"
-
 	"github.com/golang/mock/gomock"
 
 	"github.com/stretchr/testify/assert"
@@ -27,7 +25,8 @@ func TestTaskInteractor_AddTemplate(t *testing.T) {
 	defer ctl.Finish()
 	tt := newTask(ctl)
 
-	tt.Storage.(*mock_use.MockStorage).EXPECT().SaveItem(gomock.Any()).AnyTimes()
+	expectTx(tt)
+	tt.Storage.(*MockStorage).EXPECT().SaveItem(gomock.Any()).AnyTimes()
 	err := tt.AddTemplate()
 
 	assert.NoError(t, err)
@@ -40,7 +39,10 @@ func TestTaskInteractor_AddTemplate_Errors(t *testing.T) {
 	tt := newTask(ctl)
 
 	// SaveItem error
-	tt.Storage.(*mock_use.MockStorage).EXPECT().SaveItem(gomock.Any()).Return(i64, io.EOF)
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().SaveItem(gomock.Any()).Return(i64, io.EOF),
+	)
 	err := tt.AddTemplate()
 
 	assert.Error(t, err)
@@ -0,0 +1,56 @@
+package use
+
+import (
+	"fmt"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+)
+
+// DeleteTask moves a task to the trash along with its sub tasks.
+func (t *TaskInteractor) DeleteTask(taskID int64) error {
+	var item *entity.Item
+	err := t.Storage.RunInTx(func(tx Storage) error {
+		var err error
+		item, err = tx.GetItemByID(taskID)
+		if err != nil {
+			return fmt.Errorf("getting item: %w", err)
+		}
+		if err := tx.DeleteItem(taskID); err != nil {
+			return fmt.Errorf("deleting item: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.Presenter.ShowTaskDeleted(itemToTask(item))
+}
+
+// RestoreTask moves a task back from the trash along with the sub tasks deleted with it.
+func (t *TaskInteractor) RestoreTask(taskID int64) error {
+	var item *entity.Item
+	err := t.Storage.RunInTx(func(tx Storage) error {
+		if err := tx.RestoreItem(taskID); err != nil {
+			return fmt.Errorf("restoring item: %w", err)
+		}
+		var err error
+		item, err = tx.GetItemByID(taskID)
+		if err != nil {
+			return fmt.Errorf("getting item: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.Presenter.ShowTaskRestored(itemToTask(item))
+}
+
+// EmptyTrash removes tasks in the trash permanently.
+func (t *TaskInteractor) EmptyTrash() error {
+	removed, err := t.Storage.EmptyTrash()
+	if err != nil {
+		return fmt.Errorf("emptying trash: %w", err)
+	}
+	return t.Presenter.ShowTrashEmptied(removed)
+}
@@ -0,0 +1,75 @@
+package use
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/use/mock_use"
+)
+
+func TestDeleteTask(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	item := &entity.Item{ID: 42, Title: "mistyped"}
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(item.ID).Return(item, nil),
+		tt.Storage.(*MockStorage).EXPECT().DeleteItem(item.ID),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTaskDeleted(itemToTask(item)),
+	)
+	assert.NoError(t, tt.DeleteTask(item.ID))
+
+	// nothing is shown on errors
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(item.ID).Return(item, nil),
+		tt.Storage.(*MockStorage).EXPECT().DeleteItem(item.ID).Return(io.EOF),
+	)
+	assert.True(t, errors.Is(tt.DeleteTask(item.ID), io.EOF))
+}
+
+func TestRestoreTask(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	item := &entity.Item{ID: 42, Title: "restored"}
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().RestoreItem(item.ID),
+		tt.Storage.(*MockStorage).EXPECT().GetItemByID(item.ID).Return(item, nil),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTaskRestored(itemToTask(item)),
+	)
+	assert.NoError(t, tt.RestoreTask(item.ID))
+
+	gomock.InOrder(
+		expectTx(tt),
+		tt.Storage.(*MockStorage).EXPECT().RestoreItem(item.ID).Return(io.EOF),
+	)
+	assert.True(t, errors.Is(tt.RestoreTask(item.ID), io.EOF))
+}
+
+func TestEmptyTrash(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	gomock.InOrder(
+		tt.Storage.(*MockStorage).EXPECT().EmptyTrash().Return(3, nil),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTrashEmptied(3),
+	)
+	assert.NoError(t, tt.EmptyTrash())
+
+	tt.Storage.(*MockStorage).EXPECT().EmptyTrash().Return(0, io.EOF)
+	assert.True(t, errors.Is(tt.EmptyTrash(), io.EOF))
+}
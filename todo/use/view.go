@@ -0,0 +1,227 @@
+package use
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+)
+
+// errors
+var (
+	ErrEmptyViewName = errors.New("View name could not be empty")
+	ErrBuiltInView   = errors.New("Built-in view could not be changed")
+	ErrNotView       = errors.New("Item is not a view")
+	ErrParentIsView  = errors.New("Tasks could not be put under a view")
+)
+
+// builtInViews are views given by the application, their times are relative to the time they are listed.
+var builtInViews = []*model.View{
+	{ID: -1, Name: "Today", Query: "state:normal and not due < today and due < today+1d", BuiltIn: true},
+	{ID: -2, Name: "This Week", Query: "state:normal and not due < week and due < week+1w", BuiltIn: true},
+	{ID: -3, Name: "Overdue", Query: "state:normal and due < now", BuiltIn: true},
+}
+
+// ListViews lists built-in views followed by saved views.
+func (t *TaskInteractor) ListViews() error {
+	items, err := t.Storage.GetItemsByParentID(entity.RootID)
+	if err != nil {
+		return fmt.Errorf("getting items from storage: %w", err)
+	}
+	views := append([]*model.View{}, builtInViews...)
+	for _, it := range items {
+		if it.Type == entity.ItemTypeView {
+			views = append(views, itemToView(it))
+		}
+	}
+	return t.Presenter.ShowViews(views)
+}
+
+// AddView saves a view after the others, the query is saved in its canonical form.
+func (t *TaskInteractor) AddView(name, query string) error {
+	item := &entity.Item{
+		Type:         entity.ItemTypeView,
+		State:        entity.ItemStateNormal,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		ParentItemID: entity.RootID,
+	}
+	if err := setView(item, name, query); err != nil {
+		return err
+	}
+	err := t.Storage.RunInTx(func(s Storage) error {
+		items, err := s.GetItemsByParentID(entity.RootID)
+		if err != nil {
+			return fmt.Errorf("getting items of parent[%d]: %w", entity.RootID, err)
+		}
+		item.Rank, err = rankAt(s, items, -1)
+		if err != nil {
+			return err
+		}
+		item.ID, err = s.SaveItem(item)
+		if err != nil {
+			return fmt.Errorf("saving view: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.Presenter.ShowViewSaved(itemToView(item))
+}
+
+// UpdateView changes the name and query of a saved view, the change is rejected with ErrConflict if the view is
+// not at given revision.
+func (t *TaskInteractor) UpdateView(viewID int64, revision uint64, name, query string) error {
+	var item *entity.Item
+	err := t.Storage.RunInTx(func(s Storage) error {
+		var err error
+		item, err = getSavedView(s, viewID)
+		if err != nil {
+			return err
+		}
+		if err := setView(item, name, query); err != nil {
+			return err
+		}
+		item.Revision = revision
+		if _, err := s.SaveItem(item); err != nil {
+			return fmt.Errorf("saving view: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.Presenter.ShowViewSaved(itemToView(item))
+}
+
+// DeleteView moves a saved view to the trash.
+func (t *TaskInteractor) DeleteView(viewID int64) error {
+	var item *entity.Item
+	err := t.Storage.RunInTx(func(s Storage) error {
+		var err error
+		item, err = getSavedView(s, viewID)
+		if err != nil {
+			return err
+		}
+		if err := s.DeleteItem(viewID); err != nil {
+			return fmt.Errorf("deleting view: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.Presenter.ShowViewDeleted(itemToView(item))
+}
+
+// ListTasksInView lists tasks in the view of given ID under any parent sorted by s, the manual order of a view is
+// that parents go before their children and siblings are in their order.
+func (t *TaskInteractor) ListTasksInView(viewID int64, s model.TaskSort) error {
+	var view *model.View
+	for _, v := range builtInViews {
+		if v.ID == viewID {
+			view = v
+		}
+	}
+	if view == nil {
+		item, err := getSavedView(t.Storage, viewID)
+		if err != nil {
+			return err
+		}
+		view = itemToView(item)
+	}
+	filter, err := entity.ParseQuery(view.Query)
+	if err != nil {
+		return fmt.Errorf("parsing query of view %q: %w", view.Name, err)
+	}
+	return t.listTasksInView(&entity.TaskView{Name: view.Name, Filter: filter}, s)
+}
+
+func (t *TaskInteractor) listTasksInView(view *entity.TaskView, s model.TaskSort) error {
+	match, err := view.Match(time.Now())
+	if err != nil {
+		return fmt.Errorf("compiling view %q: %w", view.Name, err)
+	}
+	var items []*entity.Item
+	err = walkItems(t.Storage, entity.RootID, func(it *entity.Item) {
+		if match(it) {
+			items = append(items, it)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	sortItems(items, s)
+	tasks, err := itemsToTasks(t.Storage, items)
+	if err != nil {
+		return err
+	}
+	err = t.Presenter.ShowTasksInView(view.Name, tasks)
+	if err != nil {
+		return fmt.Errorf("showing tasks in view %q: %w", view.Name, err)
+	}
+	return nil
+}
+
+// getSavedView returns the item of a saved view.
+func getSavedView(s Storage, viewID int64) (*entity.Item, error) {
+	if viewID < 0 {
+		return nil, ErrBuiltInView
+	}
+	item, err := s.GetItemByID(viewID)
+	if err != nil {
+		return nil, fmt.Errorf("getting view: %w", err)
+	}
+	if item.Type != entity.ItemTypeView {
+		return nil, fmt.Errorf("%w: item[%d]", ErrNotView, viewID)
+	}
+	return item, nil
+}
+
+// setView sets the name and the canonical query of the item of a view.
+func setView(item *entity.Item, name, query string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrEmptyViewName
+	}
+	filter, err := entity.ParseQuery(query)
+	if err != nil {
+		return fmt.Errorf("parsing query: %w", err)
+	}
+	item.Title, item.Description = name, filter.String()
+	return nil
+}
+
+func itemToView(it *entity.Item) *model.View {
+	return &model.View{ID: it.ID, Name: it.Title, Query: it.Description, Revision: it.Revision}
+}
+
+// tasksOnly returns items other than views.
+func tasksOnly(items []*entity.Item) []*entity.Item {
+	tasks := items[:0:0]
+	for _, it := range items {
+		if it.Type != entity.ItemTypeView {
+			tasks = append(tasks, it)
+		}
+	}
+	return tasks
+}
+
+// walkItems calls fn with descendants of given parent in s other than views, an item goes before its children.
+func walkItems(s Storage, parentID int64, fn func(*entity.Item)) error {
+	items, err := s.GetItemsByParentID(parentID)
+	if err != nil {
+		return fmt.Errorf("getting items of parent[%d]: %w", parentID, err)
+	}
+	for _, it := range tasksOnly(items) {
+		fn(it)
+		if err := walkItems(s, it.ID, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
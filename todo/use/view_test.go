@@ -0,0 +1,173 @@
+package use
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tevino/the-clean-architecture-demo/todo/entity"
+	"github.com/tevino/the-clean-architecture-demo/todo/model"
+	"github.com/tevino/the-clean-architecture-demo/todo/use/mock_use"
+)
+
+func TestListTasksInView(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	work := &entity.Item{ID: 1, Title: "Work", Type: entity.ItemTypeCategory}
+	release := &entity.Item{ID: 2, Title: "Release", Type: entity.ItemTypeProject, ParentItemID: 1}
+	changelog := &entity.Item{ID: 3, Title: "Write changelog", ParentItemID: 2}
+	call := &entity.Item{ID: 4, Title: "Call mom"}
+	tasks := &entity.Item{ID: 5, Title: "Tasks", Description: "type:task", Type: entity.ItemTypeView}
+	s.EXPECT().GetItemByID(tasks.ID).Return(tasks, nil)
+	s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return([]*entity.Item{work, call, tasks}, nil)
+	s.EXPECT().GetItemsByParentID(work.ID).Return([]*entity.Item{release}, nil)
+	s.EXPECT().GetItemsByParentID(release.ID).Return([]*entity.Item{changelog}, nil)
+	s.EXPECT().GetItemsByParentID(changelog.ID).Return([]*entity.Item{}, nil)
+	s.EXPECT().GetItemsByParentID(call.ID).Return([]*entity.Item{}, nil)
+	tt.Presenter.(*mock_use.MockPresenter).EXPECT().
+		ShowTasksInView("Tasks", []*model.Task{itemToTask(changelog), itemToTask(call)})
+
+	assert.NoError(t, tt.ListTasksInView(tasks.ID, nil))
+}
+
+func TestListTasksInInvalidView(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	view := &entity.TaskView{
+		Filter: &entity.Composition{Conditions: []*entity.Condition{{Type: entity.Before, Target: entity.Title}}},
+	}
+	assert.True(t, errors.Is(tt.listTasksInView(view, nil), entity.ErrInvalidCondition))
+
+	task := &entity.Item{ID: 1, Title: "Work"}
+	tt.Storage.(*MockStorage).EXPECT().GetItemByID(task.ID).Return(task, nil)
+	assert.True(t, errors.Is(tt.ListTasksInView(task.ID, nil), ErrNotView))
+}
+
+func TestBuiltInViews(t *testing.T) {
+	t.Parallel()
+	for _, v := range builtInViews {
+		_, err := entity.ParseQuery(v.Query)
+		assert.NoError(t, err, v.Name)
+		assert.True(t, v.ID < 0, v.Name)
+	}
+}
+
+func TestListViews(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+
+	work := &entity.Item{ID: 1, Title: "Work", Type: entity.ItemTypeCategory}
+	urgent := &entity.Item{ID: 2, Title: "Urgent", Description: "due < today+1d", Type: entity.ItemTypeView, Revision: 3}
+	tt.Storage.(*MockStorage).EXPECT().GetItemsByParentID(int64(entity.RootID)).Return([]*entity.Item{work, urgent}, nil)
+	tt.Presenter.(*mock_use.MockPresenter).EXPECT().
+		ShowViews(append(builtInViews[:len(builtInViews):len(builtInViews)], &model.View{ID: 2, Name: "Urgent", Query: "due < today+1d", Revision: 3}))
+	assert.NoError(t, tt.ListViews())
+}
+
+func TestAddView(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	assert.Equal(t, ErrEmptyViewName, tt.AddView(" ", "title:a"))
+	var qe *entity.QueryError
+	assert.True(t, errors.As(tt.AddView("Urgent", "due <"), &qe))
+
+	work := &entity.Item{ID: 1, Title: "Work", Type: entity.ItemTypeCategory, Rank: "h"}
+	view := entity.Item{
+		ID:          2,
+		Title:       "Urgent",
+		Description: "due < today+1d and state:normal",
+		Type:        entity.ItemTypeView,
+		Rank:        "i",
+	}
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return([]*entity.Item{work}, nil),
+		s.EXPECT().SaveItem(itemMatcher{func() entity.Item { v := view; v.ID = 0; return v }()}).Return(view.ID, nil),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().
+			ShowViewSaved(&model.View{ID: 2, Name: "Urgent", Query: "due < today+1d and state:normal"}),
+	)
+	assert.NoError(t, tt.AddView(" Urgent ", "due<today+1d STATE = todo"))
+}
+
+func TestUpdateView(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	expectTx(tt)
+	assert.True(t, errors.Is(tt.UpdateView(-1, 0, "Today", "due < now"), ErrBuiltInView))
+
+	view := &entity.Item{ID: 2, Title: "Urgent", Description: "due < today+1d", Type: entity.ItemTypeView, Revision: 3}
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(view.ID).Return(view, nil),
+		s.EXPECT().SaveItem(itemMatcher{entity.Item{ID: 2, Title: "Soon", Description: "due < +7d", Type: entity.ItemTypeView, Revision: 2}}).
+			Return(view.ID, nil),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().
+			ShowViewSaved(&model.View{ID: 2, Name: "Soon", Query: "due < +7d", Revision: 2}),
+	)
+	assert.NoError(t, tt.UpdateView(view.ID, 2, "Soon", "due < +7d"))
+}
+
+func TestDeleteView(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	task := &entity.Item{ID: 1, Title: "Work"}
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(task.ID).Return(task, nil),
+	)
+	assert.True(t, errors.Is(tt.DeleteView(task.ID), ErrNotView))
+
+	view := &entity.Item{ID: 2, Title: "Urgent", Description: "due < today+1d", Type: entity.ItemTypeView}
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(view.ID).Return(view, nil),
+		s.EXPECT().DeleteItem(view.ID),
+		tt.Presenter.(*mock_use.MockPresenter).EXPECT().
+			ShowViewDeleted(&model.View{ID: 2, Name: "Urgent", Query: "due < today+1d"}),
+	)
+	assert.NoError(t, tt.DeleteView(view.ID))
+}
+
+func TestTasksOnly(t *testing.T) {
+	t.Parallel()
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	tt := newTask(ctl)
+	s := tt.Storage.(*MockStorage)
+
+	work := &entity.Item{ID: 1, Title: "Work", Type: entity.ItemTypeCategory, Rank: "h"}
+	view := &entity.Item{ID: 2, Title: "Urgent", Type: entity.ItemTypeView, Rank: "q"}
+	s.EXPECT().GetItemsByParentID(int64(entity.RootID)).Return([]*entity.Item{work, view}, nil)
+	tt.Presenter.(*mock_use.MockPresenter).EXPECT().ShowTasksOfParentID(int64(entity.RootID), []*model.Task{itemToTask(work)})
+	assert.NoError(t, tt.ListTasksByParentID(entity.RootID, nil))
+
+	gomock.InOrder(
+		expectTx(tt),
+		s.EXPECT().GetItemByID(view.ID).Return(view, nil),
+	)
+	err := tt.AddTask(&model.FormAddTask{Title: "Call mom", ParentID: view.ID})
+	assert.True(t, errors.Is(err, ErrParentIsView))
+}